@@ -1,34 +1,101 @@
 package cmd
 
 import (
-	"archive/tar"
-	"compress/gzip"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 
+	"github.com/fwartner/prjct/internal/archive"
+	"github.com/fwartner/prjct/internal/config"
 	"github.com/fwartner/prjct/internal/index"
+	"github.com/fwartner/prjct/internal/journal"
 	"github.com/spf13/cobra"
 )
 
 var (
-	archiveDelete bool
-	archiveOutput string
+	archiveDelete         bool
+	archiveDeleteFiltered bool
+	archiveOutput         string
+	archiveFormat         string
+	archiveLevel          int
+	archiveExclude        []string
+	archiveOnly           []string
+	archiveQuiet          bool
+	archiveSplit          string
 )
 
 var archiveCmd = &cobra.Command{
 	Use:   "archive <query>",
-	Short: "Archive a project as a .tar.gz file",
-	Long: `Searches the project index and creates a compressed archive of the
-first matching project. Use --delete to remove the original after archiving.`,
+	Short: "Archive a project as a .tar.gz, .tar or .zip file",
+	Long: `Searches the project index and creates an archive of the first
+matching project. Use --delete to remove the original after archiving.
+
+--format selects tar, tar.gz or zip and --level the compression level
+(1 fastest to 9 smallest). Without them, the template's archive settings
+apply, then the extension of --file, then tar.gz.
+
+--exclude leaves out paths matching gitignore-style patterns, in addition
+to the template's archive.exclude list, e.g. --exclude '04_Export/Proxies/**'
+--exclude '*.cache'. --only archives just the matching paths, e.g.
+--only '04_Export/Final/**'. Excluded paths are listed in the manifest.
+
+--split writes numbered volumes of at most the given size (e.g. 50G or
+500MiB) as <file>.001, <file>.002, ... with a manifest listing each
+volume's checksum in <file>.manifest.json.
+
+Progress (bytes and files done, throughput, ETA) is shown on stderr after a
+quick scan of the project. Use --quiet to turn it off, e.g. in cron jobs.
+
+The archive embeds a manifest with the SHA-256 checksum of every file.
+With --delete, the archive is re-read and compared against the manifest
+first, and the project's files are counted again; the original is kept if
+anything does not match. --delete is refused when --exclude, --only or the
+template's archive.exclude leave files out, since they would be lost;
+--delete-filtered deletes anyway after listing the files not archived.`,
 	Args: cobra.ExactArgs(1),
 	RunE: runArchive,
 }
 
+var archiveVerifyCmd = &cobra.Command{
+	Use:   "verify <file>",
+	Short: "Check an archive against its manifest",
+	Long: `Re-reads an archive and compares every file against the SHA-256
+checksums in its embedded manifest, or <file>.manifest.json next to it.
+Exits non-zero if a file is missing, changed or not listed.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runArchiveVerify,
+}
+
+// archiveVerifyView is the machine-readable result of archive verify.
+type archiveVerifyView struct {
+	Archive    string         `json:"archive" yaml:"archive"`
+	Files      int            `json:"files" yaml:"files"`
+	OK         bool           `json:"ok" yaml:"ok"`
+	Mismatches []mismatchView `json:"mismatches" yaml:"mismatches"`
+}
+
+// mismatchView is a file that failed verification.
+type mismatchView struct {
+	Path   string `json:"path" yaml:"path"`
+	Reason string `json:"reason" yaml:"reason"`
+}
+
 func init() {
+	archiveCmd.AddCommand(archiveVerifyCmd)
 	archiveCmd.Flags().BoolVar(&archiveDelete, "delete", false, "delete original after archiving")
-	archiveCmd.Flags().StringVarP(&archiveOutput, "output", "o", "", "output file path (default: <project>.tar.gz)")
+	archiveCmd.Flags().BoolVar(&archiveDeleteFiltered, "delete-filtered", false, "with --delete, also delete files left out by exclude or only patterns")
+	archiveCmd.Flags().StringVarP(&archiveOutput, "file", "f", "", "output file path (default: <project>.<format>)")
+	archiveCmd.Flags().StringVar(&archiveFormat, "format", "", "archive format: tar, tar.gz, or zip")
+	archiveCmd.Flags().IntVar(&archiveLevel, "level", 0, "compression level from 1 (fastest) to 9 (smallest)")
+	archiveCmd.Flags().StringArrayVar(&archiveExclude, "exclude", nil, "leave out paths matching a gitignore-style pattern (repeatable)")
+	archiveCmd.Flags().StringVar(&archiveSplit, "split", "", "split into volumes of at most this size, e.g. 50G")
+	archiveCmd.Flags().BoolVarP(&archiveQuiet, "quiet", "q", false, "do not show progress")
+	archiveCmd.Flags().StringArrayVar(&archiveOnly, "only", nil, "archive only paths matching a gitignore-style pattern (repeatable)")
+	_ = archiveCmd.RegisterFlagCompletionFunc("format", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return config.ArchiveFormats, cobra.ShellCompDirectiveNoFileComp
+	})
 }
 
 func runArchive(cmd *cobra.Command, args []string) error {
@@ -56,100 +123,394 @@ func runArchive(cmd *cobra.Command, args []string) error {
 		return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("project directory not found: %s", projectPath)}
 	}
 
-	outputPath := archiveOutput
-	if outputPath == "" {
-		outputPath = projectPath + ".tar.gz"
+	opts, err := archiveOptions(entry)
+	if err != nil {
+		return err
 	}
-
-	if err := createTarGz(outputPath, projectPath); err != nil {
-		return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("archive failed: %v", err)}
+	if err := checkDeleteFilters(opts, archiveDelete, archiveDeleteFiltered); err != nil {
+		return err
 	}
-
-	fmt.Printf("Archived: %s\n", outputPath)
-
-	// Update index status
-	_ = index.Update(idxPath, projectPath, func(e *index.Entry) {
-		e.Status = "archived"
+	if dryRun {
+		outputPath := archiveOutput
+		if outputPath == "" {
+			outputPath = projectPath + opts.Format.Ext()
+		}
+		fmt.Printf("  [DRY-RUN] archive %s → %s\n", projectPath, outputPath)
+		if archiveDelete {
+			fmt.Printf("  [DRY-RUN] delete %s after verification\n", projectPath)
+		}
+		return nil
+	}
+	_, err = archiveProject(idxPath, archiveJob{
+		entry:          entry,
+		output:         archiveOutput,
+		opts:           opts,
+		delete:         archiveDelete,
+		deleteFiltered: archiveDeleteFiltered,
+		progress:       !archiveQuiet && !isStructuredOutput(),
 	})
+	return err
+}
 
-	if archiveDelete {
-		if err := os.RemoveAll(projectPath); err != nil {
-			return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("archive created but failed to delete original: %v", err)}
+// archiveJob describes one project to archive.
+type archiveJob struct {
+	entry  index.Entry
+	output string // default: <project path>.<format>
+	opts   archive.Options
+	delete bool
+	// deleteFiltered allows delete although exclude or only patterns
+	// leave files out of the archive.
+	deleteFiltered bool
+	// progress shows the progress display on stderr.
+	progress bool
+	// silent suppresses the summary lines on stdout.
+	silent bool
+}
+
+// archiveProject archives a project, verifies the archive before deleting
+// the original, sets the status to archived and journals the operation. It
+// returns the path of the archive.
+func archiveProject(idxPath string, job archiveJob) (string, error) {
+	entry, opts := job.entry, job.opts
+	projectPath := entry.Path
+	printf := func(format string, a ...any) {
+		if !job.silent {
+			fmt.Printf(format, a...)
 		}
-		fmt.Printf("Deleted:  %s\n", projectPath)
 	}
 
-	return nil
-}
+	if err := checkDeleteFilters(opts, job.delete, job.deleteFiltered); err != nil {
+		return "", err
+	}
 
-func createTarGz(outputPath, sourcePath string) error {
-	outFile, err := os.Create(outputPath)
+	outputPath := job.output
+	if outputPath == "" {
+		outputPath = projectPath + opts.Format.Ext()
+	}
+
+	var progress *archiveProgress
+	if job.progress {
+		progress = newArchiveProgress()
+		opts.Progress = progress.update
+	}
+	manifest, err := archive.Create(outputPath, projectPath, opts)
+	if progress != nil {
+		progress.finish()
+	}
 	if err != nil {
-		return err
+		return "", &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("archive failed: %v", err)}
 	}
-	defer outFile.Close()
 
-	gw := gzip.NewWriter(outFile)
-	defer gw.Close()
+	printf("Archived: %s\n", outputPath)
+	if n := len(manifest.Volumes); n > 0 {
+		printf(" Volumes: %d (%s … %s)\n", n, manifest.Volumes[0].Name, manifest.Volumes[n-1].Name)
+	}
+	if len(manifest.Omitted) > 0 {
+		printf("Excluded: %d path(s)\n", len(manifest.Omitted))
+		if verbose {
+			for _, p := range manifest.Omitted {
+				printf("  - %s\n", p)
+			}
+		}
+	}
 
-	tw := tar.NewWriter(gw)
-	defer tw.Close()
+	// Never delete the original before the archive has been read back
+	if job.delete {
+		if err := checkArchivedFiles(projectPath, manifest); err != nil {
+			return outputPath, err
+		}
+		_, mismatches, err := archive.VerifyArchive(outputPath, manifest)
+		if err != nil {
+			return outputPath, &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("cannot verify archive, original kept: %v", err)}
+		}
+		if len(mismatches) > 0 {
+			return outputPath, &ExitError{Code: ExitGeneral, Message: mismatchMessage("archive does not match the project, original kept", mismatches)}
+		}
+		printf("Verified: %d file(s)\n", len(manifest.Files))
+	}
 
-	baseName := filepath.Base(sourcePath)
+	// Archiving ends a project in any status, so only the status itself
+	// has to exist in the workflow
+	setWorkflowStatus(idxPath, entry, "archived", false)
 
-	return filepath.WalkDir(sourcePath, func(path string, d os.DirEntry, err error) error {
+	rec := journal.Record{
+		Operation: journal.OpArchive,
+		Details: map[string]string{
+			"path":     projectPath,
+			"archive":  outputPath,
+			"template": entry.TemplateID,
+			"format":   string(opts.Format),
+			"level":    strconv.Itoa(opts.Level),
+		},
+		Created: archiveFiles(outputPath, manifest),
+	}
+	if opts.Split > 0 {
+		rec.Details["split"] = strconv.FormatInt(opts.Split, 10)
+	}
+	if len(opts.Exclude) > 0 {
+		rec.Details["exclude"] = strings.Join(opts.Exclude, ",")
+	}
+	if len(opts.Only) > 0 {
+		rec.Details["only"] = strings.Join(opts.Only, ",")
+	}
+	if job.delete {
+		notArchived, err := filesNotArchived(projectPath, manifest)
 		if err != nil {
-			return err
+			recordJournal(rec)
+			return outputPath, &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("cannot list files not archived, original kept: %v", err)}
+		}
+		if len(notArchived) > 0 {
+			printf("Not archived, deleted with the project: %d file(s)\n", len(notArchived))
+			for _, p := range notArchived {
+				printf("  - %s\n", p)
+			}
+			rec.Details["not_archived"] = strings.Join(notArchived, "\n")
+		}
+		if err := os.RemoveAll(projectPath); err != nil {
+			recordJournal(rec)
+			return outputPath, &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("archive created but failed to delete original: %v", err)}
 		}
+		rec.Removed = []string{projectPath}
+		printf("Deleted:  %s\n", projectPath)
+	}
+	recordJournal(rec)
 
-		info, err := d.Info()
-		if err != nil {
+	return outputPath, nil
+}
+
+// checkDeleteFilters refuses to delete a project whose archive leaves files
+// out, unless the caller explicitly allowed it.
+func checkDeleteFilters(opts archive.Options, del, deleteFiltered bool) error {
+	if !del || deleteFiltered || (len(opts.Exclude) == 0 && len(opts.Only) == 0) {
+		return nil
+	}
+	return &ExitError{
+		Code:    ExitGeneral,
+		Message: "refusing to delete: exclude or only patterns leave files out of the archive (use --delete-filtered to delete them anyway)",
+	}
+}
+
+// filesNotArchived lists the regular files of the project that the
+// manifest does not contain, in lexical order.
+func filesNotArchived(projectPath string, m *archive.Manifest) ([]string, error) {
+	stored := make(map[string]bool, len(m.Files))
+	for _, f := range m.Files {
+		stored[f.Path] = true
+	}
+	var missing []string
+	err := filepath.WalkDir(projectPath, func(p string, d os.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
 			return err
 		}
-
-		header, err := tar.FileInfoHeader(info, "")
+		rel, err := filepath.Rel(projectPath, p)
 		if err != nil {
 			return err
 		}
+		if rel = filepath.ToSlash(rel); !stored[rel] {
+			missing = append(missing, rel)
+		}
+		return nil
+	})
+	return missing, err
+}
+
+// checkArchivedFiles compares the project's files with the count recorded
+// in the manifest. Verifying the archive only proves it matches the
+// manifest; this catches files added while archiving and, without
+// filters, files the walk did not store.
+func checkArchivedFiles(projectPath string, m *archive.Manifest) error {
+	n, err := archive.CountFiles(projectPath)
+	if err != nil {
+		return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("cannot count project files, original kept: %v", err)}
+	}
+	if n != m.SourceFiles {
+		return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("project has %d file(s) but %d were counted when archiving, original kept", n, m.SourceFiles)}
+	}
+	if len(m.Exclude) == 0 && len(m.Only) == 0 && len(m.Files) != m.SourceFiles {
+		return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("archive stores %d of the project's %d file(s), original kept", len(m.Files), m.SourceFiles)}
+	}
+	return nil
+}
 
-		rel, err := filepath.Rel(filepath.Dir(sourcePath), path)
+// archiveOptions resolves format and compression level from the flags, the
+// project's template, the --file extension and the default, in that order.
+// Exclude patterns from the template and --exclude are combined.
+func archiveOptions(entry index.Entry) (archive.Options, error) {
+	opts := archive.Options{Name: entry.Name, Template: entry.TemplateID, Level: archiveLevel, Only: archiveOnly}
+	if archiveSplit != "" {
+		size, err := parseByteSize(archiveSplit)
 		if err != nil {
-			return err
+			return opts, &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("invalid --split: %v", err)}
 		}
-		header.Name = filepath.ToSlash(filepath.Join(baseName, rel[len(baseName):]))
-		if header.Name == baseName+"/" {
-			header.Name = baseName + "/"
+		opts.Split = size
+	}
+
+	settings := templateArchiveSettings(entry)
+	name := archiveFormat
+	if name == "" {
+		name = settings.Format
+	}
+	if name == "" {
+		name = string(formatFromName(archiveOutput))
+	}
+	if name == "" {
+		opts.Format = archive.DefaultFormat
+	} else {
+		f, err := archive.ParseFormat(name)
+		if err != nil {
+			return opts, &ExitError{Code: ExitGeneral, Message: err.Error()}
 		}
+		opts.Format = f
+	}
+
+	opts.Exclude = append(append([]string{}, settings.Exclude...), archiveExclude...)
+	if _, err := archive.CompilePatterns(opts.Exclude); err != nil {
+		return opts, &ExitError{Code: ExitGeneral, Message: err.Error()}
+	}
+	if _, err := archive.CompilePatterns(opts.Only); err != nil {
+		return opts, &ExitError{Code: ExitGeneral, Message: err.Error()}
+	}
 
-		// Use portable relative path
-		relFromSource, err := filepath.Rel(sourcePath, path)
+	if opts.Level == 0 && opts.Format.Compressed() {
+		opts.Level = settings.Level
+	}
+	if opts.Level < 0 || opts.Level > 9 {
+		return opts, &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("--level %d out of range 1-9", opts.Level)}
+	}
+	if opts.Level != 0 && !opts.Format.Compressed() {
+		return opts, &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("--level does not apply to %s archives", opts.Format)}
+	}
+	return opts, nil
+}
+
+// templateArchiveOptions returns the options for archiving entry from the
+// archive settings of its template alone. Archives written on behalf of a
+// rule, such as by retention, must not pick up the archive command's flags.
+func templateArchiveOptions(entry index.Entry) (archive.Options, error) {
+	settings := templateArchiveSettings(entry)
+	opts := archive.Options{
+		Name:     entry.Name,
+		Template: entry.TemplateID,
+		Format:   archive.DefaultFormat,
+		Exclude:  settings.Exclude,
+	}
+	if settings.Format != "" {
+		f, err := archive.ParseFormat(settings.Format)
 		if err != nil {
-			return err
+			return opts, &ExitError{Code: ExitGeneral, Message: err.Error()}
 		}
-		if relFromSource == "." {
-			header.Name = baseName + "/"
-		} else {
-			header.Name = filepath.ToSlash(filepath.Join(baseName, relFromSource))
-			if d.IsDir() {
-				header.Name += "/"
-			}
+		opts.Format = f
+	}
+	if _, err := archive.CompilePatterns(opts.Exclude); err != nil {
+		return opts, &ExitError{Code: ExitGeneral, Message: err.Error()}
+	}
+	if opts.Format.Compressed() {
+		opts.Level = settings.Level
+	}
+	return opts, nil
+}
+
+// templateArchiveSettings returns the archive settings of entry's template,
+// or none if the config or template cannot be loaded.
+func templateArchiveSettings(entry index.Entry) config.ArchiveSettings {
+	if cfg, err := loadConfig(); err == nil {
+		if tmpl, err := cfg.ResolveTemplate(entry.TemplateID); err == nil && tmpl.Archive != nil {
+			return *tmpl.Archive
 		}
+	}
+	return config.ArchiveSettings{}
+}
 
-		if err := tw.WriteHeader(header); err != nil {
-			return err
+// archiveFiles lists the files written for an archive: the archive itself,
+// or its volumes and the sidecar manifest.
+func archiveFiles(outputPath string, m *archive.Manifest) []string {
+	if len(m.Volumes) == 0 {
+		return []string{outputPath}
+	}
+	var files []string
+	for i := range m.Volumes {
+		files = append(files, archive.VolumePath(outputPath, i+1))
+	}
+	return append(files, archive.SidecarPath(outputPath))
+}
+
+// parseByteSize parses sizes like 500M, 50G, 50GB or 4GiB. K, M, G and T
+// (optionally followed by B) are powers of 1000; KiB, MiB, GiB and TiB are
+// powers of 1024. A plain number is bytes.
+func parseByteSize(s string) (int64, error) {
+	units := []struct {
+		suffix string
+		factor int64
+	}{
+		{"KIB", 1 << 10}, {"MIB", 1 << 20}, {"GIB", 1 << 30}, {"TIB", 1 << 40},
+		{"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9}, {"TB", 1e12},
+		{"K", 1e3}, {"M", 1e6}, {"G", 1e9}, {"T", 1e12},
+		{"B", 1},
+	}
+	upper := strings.ToUpper(strings.TrimSpace(s))
+	factor := int64(1)
+	for _, u := range units {
+		if strings.HasSuffix(upper, u.suffix) {
+			upper = strings.TrimSpace(strings.TrimSuffix(upper, u.suffix))
+			factor = u.factor
+			break
 		}
+	}
+	n, err := strconv.ParseFloat(upper, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("%q is not a positive size", s)
+	}
+	return int64(n * float64(factor)), nil
+}
 
-		if d.IsDir() {
-			return nil
+// formatFromName guesses the archive format from a file name's extension.
+func formatFromName(name string) archive.Format {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return archive.FormatTarGz
+	case strings.HasSuffix(lower, ".tar"):
+		return archive.FormatTar
+	case strings.HasSuffix(lower, ".zip"):
+		return archive.FormatZip
+	}
+	return ""
+}
+
+func runArchiveVerify(cmd *cobra.Command, args []string) error {
+	view := archiveVerifyView{Archive: args[0], Mismatches: []mismatchView{}}
+
+	manifest, mismatches, err := archive.VerifyArchive(args[0], nil)
+	if err != nil {
+		exitErr := &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("cannot verify %s: %v", args[0], err), Err: err}
+		if isStructuredOutput() {
+			return writeOutputError("archive-verify", view, exitErr)
 		}
+		return exitErr
+	}
+	view.Files = len(manifest.Files)
+	view.OK = len(mismatches) == 0
+	for _, m := range mismatches {
+		view.Mismatches = append(view.Mismatches, mismatchView{Path: m.Path, Reason: m.Reason})
+	}
 
-		f, err := os.Open(path)
-		if err != nil {
-			return err
+	var exitErr *ExitError
+	if !view.OK {
+		exitErr = &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("%s: %d file(s) failed verification", args[0], len(mismatches))}
+	}
+	if isStructuredOutput() {
+		if exitErr != nil {
+			return writeOutputError("archive-verify", view, exitErr)
 		}
-		defer f.Close()
+		return writeOutput("archive-verify", view)
+	}
 
-		_, err = io.Copy(tw, f)
-		return err
-	})
+	for _, m := range view.Mismatches {
+		fmt.Printf("  [FAIL] %s: %s\n", m.Path, m.Reason)
+	}
+	if exitErr != nil {
+		return exitErr
+	}
+	fmt.Printf("OK: %d file(s) verified in %s\n", view.Files, args[0])
+	return nil
 }
@@ -7,7 +7,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/fwartner/prjct/internal/archive"
 	"github.com/fwartner/prjct/internal/index"
+	"github.com/fwartner/prjct/internal/journal"
 	"github.com/spf13/cobra"
 )
 
@@ -82,6 +84,29 @@ func TestRunArchiveWithDelete(t *testing.T) {
 	}
 }
 
+func TestCheckArchivedFiles(t *testing.T) {
+	projectDir := t.TempDir()
+	for _, f := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(filepath.Join(projectDir, f), []byte(f), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stored := []archive.ManifestFile{{Path: "a.txt"}, {Path: "b.txt"}}
+
+	if err := checkArchivedFiles(projectDir, &archive.Manifest{SourceFiles: 2, Files: stored}); err != nil {
+		t.Errorf("complete archive: %v", err)
+	}
+	if err := checkArchivedFiles(projectDir, &archive.Manifest{SourceFiles: 1, Files: stored[:1]}); err == nil {
+		t.Error("expected error for a file added after archiving")
+	}
+	if err := checkArchivedFiles(projectDir, &archive.Manifest{SourceFiles: 2, Files: stored[:1]}); err == nil {
+		t.Error("expected error for an unfiltered archive missing a file")
+	}
+	if err := checkArchivedFiles(projectDir, &archive.Manifest{SourceFiles: 2, Exclude: []string{"b.txt"}, Files: stored[:1]}); err != nil {
+		t.Errorf("filtered archive: %v", err)
+	}
+}
+
 func TestRunArchiveCustomOutput(t *testing.T) {
 	dir := t.TempDir()
 	projectDir := filepath.Join(dir, "proj")
@@ -151,3 +176,290 @@ func TestRunArchiveMissingDir(t *testing.T) {
 		t.Fatal("expected ExitError")
 	}
 }
+
+func TestRunArchiveJournalAndUndo(t *testing.T) {
+	dir := t.TempDir()
+	projectDir := filepath.Join(dir, "myproject")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeTestIndex(t, dir, []index.Entry{
+		{Name: "myproject", TemplateID: "dev", Path: projectDir, CreatedAt: time.Now(), Status: "active"},
+	})
+	setConfigPath(t, filepath.Join(dir, "config.yaml"))
+	setArchiveFlags(t, false, "")
+
+	if err := runArchive(&cobra.Command{}, []string{"myproject"}); err != nil {
+		t.Fatalf("runArchive() error: %v", err)
+	}
+
+	archivePath := projectDir + ".tar.gz"
+	rec, _ := journal.Last(filepath.Join(dir, "journal.json"))
+	if rec == nil || rec.Operation != journal.OpArchive || rec.Details["archive"] != archivePath {
+		t.Fatalf("journal record = %+v, want archive", rec)
+	}
+	if len(rec.Created) != 1 || len(rec.Removed) != 0 {
+		t.Errorf("Created/Removed = %v/%v", rec.Created, rec.Removed)
+	}
+
+	if err := runUndo(&cobra.Command{}, nil); err != nil {
+		t.Fatalf("runUndo() error: %v", err)
+	}
+	if _, err := os.Stat(archivePath); !os.IsNotExist(err) {
+		t.Error("undo should remove the archive file")
+	}
+}
+
+func TestRunArchiveVerify(t *testing.T) {
+	dir := t.TempDir()
+	projectDir := filepath.Join(dir, "myproject")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(projectDir, "clip.mov"), []byte("footage"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeTestIndex(t, dir, []index.Entry{
+		{Name: "myproject", TemplateID: "dev", Path: projectDir, CreatedAt: time.Now()},
+	})
+	setConfigPath(t, filepath.Join(dir, "config.yaml"))
+	setArchiveFlags(t, false, "")
+
+	if err := runArchive(&cobra.Command{}, []string{"myproject"}); err != nil {
+		t.Fatalf("runArchive() error: %v", err)
+	}
+	archivePath := projectDir + ".tar.gz"
+	if err := runArchiveVerify(&cobra.Command{}, []string{archivePath}); err != nil {
+		t.Fatalf("runArchiveVerify() error: %v", err)
+	}
+
+	err := runArchiveVerify(&cobra.Command{}, []string{filepath.Join(dir, "missing.tar.gz")})
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatalf("runArchiveVerify(missing) error = %v, want ExitError", err)
+	}
+}
+
+func TestRunArchiveTemplateFormat(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "projects")
+	projectDir := filepath.Join(base, "myproject")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	cfgPath := filepath.Join(dir, "config.yaml")
+	cfg := "templates:\n  - id: dev\n    name: Dev\n    base_path: " + base +
+		"\n    archive:\n      format: zip\n      level: 9\n    directories:\n      - name: src\n"
+	if err := os.WriteFile(cfgPath, []byte(cfg), 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeTestIndex(t, dir, []index.Entry{
+		{Name: "myproject", TemplateID: "dev", Path: projectDir, CreatedAt: time.Now()},
+	})
+	setConfigPath(t, cfgPath)
+	setArchiveFlags(t, true, "")
+
+	if err := runArchive(&cobra.Command{}, []string{"myproject"}); err != nil {
+		t.Fatalf("runArchive() error: %v", err)
+	}
+	if _, err := os.Stat(projectDir + ".zip"); err != nil {
+		t.Fatalf("template default format not used: %v", err)
+	}
+
+	setRestoreTo(t, "")
+	if err := runRestore(&cobra.Command{}, []string{"myproject"}); err != nil {
+		t.Fatalf("runRestore() error: %v", err)
+	}
+	if _, err := os.Stat(projectDir); err != nil {
+		t.Errorf("zip archive not restored: %v", err)
+	}
+}
+
+func TestArchiveOptionsFlags(t *testing.T) {
+	dir := t.TempDir()
+	setConfigPath(t, filepath.Join(dir, "config.yaml"))
+	entry := index.Entry{Name: "p", TemplateID: "dev"}
+
+	tests := []struct {
+		format, output string
+		level          int
+		want           string
+		wantErr        bool
+	}{
+		{"", "", 0, "tar.gz", false},
+		{"tar", "", 0, "tar", false},
+		{"", "/tmp/out.zip", 0, "zip", false},
+		{"tgz", "", 6, "tar.gz", false},
+		{"tar", "", 6, "", true},
+		{"rar", "", 0, "", true},
+		{"zip", "", 10, "", true},
+	}
+	for _, tt := range tests {
+		oldFormat, oldLevel := archiveFormat, archiveLevel
+		archiveFormat, archiveLevel = tt.format, tt.level
+		setArchiveFlags(t, false, tt.output)
+		opts, err := archiveOptions(entry)
+		archiveFormat, archiveLevel = oldFormat, oldLevel
+
+		if (err != nil) != tt.wantErr {
+			t.Errorf("archiveOptions(%q, %q, %d) error = %v", tt.format, tt.output, tt.level, err)
+			continue
+		}
+		if !tt.wantErr && string(opts.Format) != tt.want {
+			t.Errorf("archiveOptions(%q, %q, %d) format = %q, want %q", tt.format, tt.output, tt.level, opts.Format, tt.want)
+		}
+	}
+}
+
+func TestRunArchiveExclude(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "projects")
+	projectDir := filepath.Join(base, "myproject")
+	for _, f := range []string{"edit/cut.prproj", "edit/preview.cache", "export/proxies/p.mov"} {
+		p := filepath.Join(projectDir, filepath.FromSlash(f))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(f), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfgPath := filepath.Join(dir, "config.yaml")
+	cfg := "templates:\n  - id: dev\n    name: Dev\n    base_path: " + base +
+		"\n    archive:\n      exclude: ['*.cache']\n    directories:\n      - name: src\n"
+	if err := os.WriteFile(cfgPath, []byte(cfg), 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeTestIndex(t, dir, []index.Entry{
+		{Name: "myproject", TemplateID: "dev", Path: projectDir, CreatedAt: time.Now()},
+	})
+	setConfigPath(t, cfgPath)
+	setArchiveFlags(t, false, "")
+	oldExclude := archiveExclude
+	archiveExclude = []string{"export/proxies/"}
+	t.Cleanup(func() { archiveExclude = oldExclude })
+
+	if err := runArchive(&cobra.Command{}, []string{"myproject"}); err != nil {
+		t.Fatalf("runArchive() error: %v", err)
+	}
+
+	rec, _ := journal.Last(filepath.Join(dir, "journal.json"))
+	if rec == nil || rec.Details["exclude"] != "*.cache,export/proxies/" {
+		t.Fatalf("journal record = %+v, want template and flag patterns", rec)
+	}
+	if err := runArchiveVerify(&cobra.Command{}, []string{projectDir + ".tar.gz"}); err != nil {
+		t.Errorf("runArchiveVerify() error: %v", err)
+	}
+}
+
+func TestRunArchiveDeleteWithFilters(t *testing.T) {
+	dir := t.TempDir()
+	projectDir := filepath.Join(dir, "myproject")
+	for _, f := range []string{"edit/cut.prproj", "edit/preview.cache"} {
+		p := filepath.Join(projectDir, filepath.FromSlash(f))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(f), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeTestIndex(t, dir, []index.Entry{
+		{Name: "myproject", TemplateID: "dev", Path: projectDir, CreatedAt: time.Now()},
+	})
+	setConfigPath(t, filepath.Join(dir, "config.yaml"))
+	setArchiveFlags(t, true, "")
+	oldExclude, oldFiltered := archiveExclude, archiveDeleteFiltered
+	t.Cleanup(func() { archiveExclude, archiveDeleteFiltered = oldExclude, oldFiltered })
+	archiveExclude = []string{"*.cache"}
+
+	archiveDeleteFiltered = false
+	var exitErr *ExitError
+	if err := runArchive(&cobra.Command{}, []string{"myproject"}); !errors.As(err, &exitErr) {
+		t.Fatalf("expected ExitError for --delete with --exclude, got %v", err)
+	}
+	if _, err := os.Stat(projectDir + ".tar.gz"); !os.IsNotExist(err) {
+		t.Error("no archive should be written when --delete is refused")
+	}
+
+	archiveDeleteFiltered = true
+	if err := runArchive(&cobra.Command{}, []string{"myproject"}); err != nil {
+		t.Fatalf("runArchive(--delete-filtered) error: %v", err)
+	}
+	if _, err := os.Stat(projectDir); !os.IsNotExist(err) {
+		t.Error("original should be deleted with --delete-filtered")
+	}
+	rec, _ := journal.Last(filepath.Join(dir, "journal.json"))
+	if rec == nil || rec.Details["not_archived"] != "edit/preview.cache" {
+		t.Errorf("journal record = %+v, want the excluded file listed", rec)
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{"50G", 50e9},
+		{"50GB", 50e9},
+		{"4GiB", 4 << 30},
+		{"500mib", 500 << 20},
+		{"1.5T", 1.5e12},
+		{"1024", 1024},
+	}
+	for _, tt := range tests {
+		got, err := parseByteSize(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("parseByteSize(%q) = %d, %v; want %d", tt.in, got, err, tt.want)
+		}
+	}
+	for _, in := range []string{"", "G", "-5M", "lots"} {
+		if _, err := parseByteSize(in); err == nil {
+			t.Errorf("parseByteSize(%q) should fail", in)
+		}
+	}
+}
+
+func TestRunArchiveSplitAndRestore(t *testing.T) {
+	dir := t.TempDir()
+	projectDir := filepath.Join(dir, "myproject")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 3000)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	if err := os.WriteFile(filepath.Join(projectDir, "clip.mov"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeTestIndex(t, dir, []index.Entry{
+		{Name: "myproject", TemplateID: "dev", Path: projectDir, CreatedAt: time.Now()},
+	})
+	setConfigPath(t, filepath.Join(dir, "config.yaml"))
+	setArchiveFlags(t, true, "")
+	oldFormat, oldSplit := archiveFormat, archiveSplit
+	archiveFormat, archiveSplit = "tar", "1K"
+	t.Cleanup(func() { archiveFormat, archiveSplit = oldFormat, oldSplit })
+
+	if err := runArchive(&cobra.Command{}, []string{"myproject"}); err != nil {
+		t.Fatalf("runArchive() error: %v", err)
+	}
+	archivePath := projectDir + ".tar"
+	rec, _ := journal.Last(filepath.Join(dir, "journal.json"))
+	if rec == nil || rec.Details["split"] != "1000" || len(rec.Created) < 3 {
+		t.Fatalf("journal record = %+v, want volumes and sidecar", rec)
+	}
+	if err := runArchiveVerify(&cobra.Command{}, []string{archivePath + ".001"}); err != nil {
+		t.Fatalf("runArchiveVerify() error: %v", err)
+	}
+
+	setRestoreTo(t, "")
+	if err := runRestore(&cobra.Command{}, []string{"myproject"}); err != nil {
+		t.Fatalf("runRestore() error: %v", err)
+	}
+	got, err := os.ReadFile(filepath.Join(projectDir, "clip.mov"))
+	if err != nil || string(got) != string(data) {
+		t.Errorf("restored file differs: %v", err)
+	}
+}
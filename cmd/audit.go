@@ -0,0 +1,381 @@
+package cmd
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fwartner/prjct/internal/config"
+	"github.com/fwartner/prjct/internal/index"
+	"github.com/spf13/cobra"
+)
+
+var (
+	auditTemplate string
+	auditStatus   string
+	auditHTML     string
+	auditMaxDrift int
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit [query]",
+	Short: "Check all indexed projects against their templates",
+	Long: `Compares every indexed project with the current layout of its template
+and reports missing, extra and renamed-looking directories. A missing
+directory next to an extra one with a similar name (e.g. Color and
+05_Color) is reported as a rename. Directories below a missing or extra
+directory are not counted separately.
+
+Use a query, --template or --status to audit a subset; archived projects
+are skipped unless --status archived is given. --html writes a report
+that can be published, "-" writes it to stdout. With --max-drift the
+command exits with code 10 if any project has more issues than that, or
+cannot be read.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runAudit,
+}
+
+func init() {
+	auditCmd.Flags().StringVarP(&auditTemplate, "template", "t", "", "only audit projects of this template")
+	auditCmd.Flags().StringVar(&auditStatus, "status", "", "only audit projects with this status")
+	auditCmd.Flags().StringVar(&auditHTML, "html", "", "write an HTML report to this file (- for stdout)")
+	auditCmd.Flags().IntVar(&auditMaxDrift, "max-drift", -1, "exit with code 10 if a project has more issues than this")
+}
+
+// auditRename pairs a missing template directory with the extra directory
+// that looks like it was renamed from it.
+type auditRename struct {
+	Expected string `json:"expected" yaml:"expected"`
+	Actual   string `json:"actual" yaml:"actual"`
+}
+
+// auditProjectView is the audit result for one project.
+type auditProjectView struct {
+	Name       string        `json:"name" yaml:"name"`
+	Path       string        `json:"path" yaml:"path"`
+	TemplateID string        `json:"template_id" yaml:"template_id"`
+	Status     string        `json:"status,omitempty" yaml:"status,omitempty"`
+	Missing    []string      `json:"missing" yaml:"missing"`
+	Extra      []string      `json:"extra" yaml:"extra"`
+	Renamed    []auditRename `json:"renamed" yaml:"renamed"`
+	Drift      int           `json:"drift" yaml:"drift"`
+	Error      string        `json:"error,omitempty" yaml:"error,omitempty"`
+	Failed     bool          `json:"failed" yaml:"failed"`
+}
+
+// auditView is the machine-readable result of the audit command.
+type auditView struct {
+	GeneratedAt time.Time          `json:"generated_at" yaml:"generated_at"`
+	MaxDrift    int                `json:"max_drift" yaml:"max_drift"`
+	Audited     int                `json:"audited" yaml:"audited"`
+	InSync      int                `json:"in_sync" yaml:"in_sync"`
+	Drifting    int                `json:"drifting" yaml:"drifting"`
+	Failed      int                `json:"failed" yaml:"failed"`
+	Projects    []auditProjectView `json:"projects" yaml:"projects"`
+}
+
+func runAudit(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	idxPath, err := resolveIndexPath()
+	if err != nil {
+		return err
+	}
+	idx, err := index.Load(idxPath)
+	if err != nil {
+		return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("cannot load project index: %v", err)}
+	}
+
+	query := ""
+	if len(args) == 1 {
+		query = args[0]
+	}
+	entries := index.Search(idx, query)
+	if auditTemplate != "" {
+		entries = index.FilterByTemplate(entries, auditTemplate)
+	}
+	if auditStatus != "" {
+		entries = index.FilterByStatus(entries, auditStatus)
+	}
+
+	report := auditView{GeneratedAt: time.Now(), MaxDrift: auditMaxDrift, Projects: []auditProjectView{}}
+	for _, e := range entries {
+		if e.Status == "archived" && auditStatus != "archived" {
+			continue
+		}
+		v := auditProject(cfg, e)
+		v.Failed = v.Error != "" || (auditMaxDrift >= 0 && v.Drift > auditMaxDrift)
+		report.Audited++
+		switch {
+		case v.Error == "" && v.Drift == 0:
+			report.InSync++
+		case v.Error == "":
+			report.Drifting++
+		}
+		if v.Failed {
+			report.Failed++
+		}
+		report.Projects = append(report.Projects, v)
+	}
+
+	var exitErr *ExitError
+	if auditMaxDrift >= 0 && report.Failed > 0 {
+		exitErr = &ExitError{Code: ExitAuditFailed, Message: fmt.Sprintf("%d project(s) drift past the threshold of %d", report.Failed, auditMaxDrift)}
+	}
+
+	if auditHTML != "" {
+		if err := writeAuditHTML(auditHTML, report); err != nil {
+			return err
+		}
+	}
+	switch {
+	case auditHTML == "-":
+	case isStructuredOutput():
+		if exitErr != nil {
+			return writeOutputError("audit", report, exitErr)
+		}
+		return writeOutput("audit", report)
+	default:
+		printAuditReport(report)
+		if auditHTML != "" {
+			fmt.Printf("Report written to %s\n", auditHTML)
+		}
+	}
+	if exitErr != nil {
+		return exitErr
+	}
+	return nil
+}
+
+// auditProject compares one project with its template.
+func auditProject(cfg *config.Config, e index.Entry) auditProjectView {
+	v := auditProjectView{
+		Name:       e.Name,
+		Path:       e.Path,
+		TemplateID: e.TemplateID,
+		Status:     e.Status,
+		Missing:    []string{},
+		Extra:      []string{},
+		Renamed:    []auditRename{},
+	}
+	tmpl, err := cfg.ResolveTemplate(e.TemplateID)
+	if err != nil {
+		v.Error = fmt.Sprintf("template %q not found in config", e.TemplateID)
+		return v
+	}
+	if info, err := os.Stat(e.Path); err != nil || !info.IsDir() {
+		v.Error = "project directory not accessible"
+		return v
+	}
+
+	d := compareDirs(tmpl, e)
+	missing, extra, renamed := pairRenames(d.missing, d.extra)
+	v.Missing = nonNil(topLevel(missing))
+	v.Extra = nonNil(topLevel(extra))
+	if renamed != nil {
+		v.Renamed = renamed
+	}
+	v.Drift = len(v.Missing) + len(v.Extra) + len(v.Renamed)
+	return v
+}
+
+// pairRenames matches missing directories with extra directories that look
+// like renamed or moved versions of them. Directories below a matched pair
+// that exist under the new name are dropped from both lists.
+func pairRenames(missing, extra []string) (restMissing, restExtra []string, renamed []auditRename) {
+	extraSet := make(map[string]bool, len(extra))
+	for _, p := range extra {
+		extraSet[p] = true
+	}
+	// renamedTo maps a matched missing directory to its actual path.
+	renamedTo := make(map[string]string)
+
+	// missing is sorted, so parents are paired before their children
+	for _, m := range missing {
+		if actual, ok := renamedBelow(m, renamedTo); ok && extraSet[actual] {
+			delete(extraSet, actual)
+			renamedTo[m] = actual
+			continue
+		}
+		if best := similarDir(m, extra, extraSet); best != "" {
+			delete(extraSet, best)
+			renamedTo[m] = best
+			renamed = append(renamed, auditRename{Expected: m, Actual: best})
+			continue
+		}
+		restMissing = append(restMissing, m)
+	}
+	for _, p := range extra {
+		if extraSet[p] {
+			restExtra = append(restExtra, p)
+		}
+	}
+	return restMissing, restExtra, renamed
+}
+
+// renamedBelow returns where p would be if one of its ancestors was renamed.
+func renamedBelow(p string, renamedTo map[string]string) (string, bool) {
+	for dir := path.Dir(p); dir != "."; dir = path.Dir(dir) {
+		if actual, ok := renamedTo[dir]; ok {
+			return actual + strings.TrimPrefix(p, dir), true
+		}
+	}
+	return "", false
+}
+
+// similarDir returns the available extra directory that most likely is
+// missing under another name: one with the same parent and a similar name,
+// or one with the same name elsewhere.
+func similarDir(missing string, extra []string, available map[string]bool) string {
+	name := normalizeDirName(path.Base(missing))
+	best, bestDist := "", -1
+	for _, e := range extra {
+		if !available[e] {
+			continue
+		}
+		other := normalizeDirName(path.Base(e))
+		dist := -1
+		switch {
+		case path.Dir(e) == path.Dir(missing) && other == name:
+			dist = 0
+		case path.Dir(e) == path.Dir(missing) && len(name) >= 4 && index.EditDistance(name, other) <= 2:
+			dist = index.EditDistance(name, other)
+		case path.Base(e) == path.Base(missing):
+			dist = 3
+		}
+		if dist >= 0 && (bestDist < 0 || dist < bestDist) {
+			best, bestDist = e, dist
+		}
+	}
+	return best
+}
+
+var dirNumberPrefix = regexp.MustCompile(`^[0-9]+[ ._-]*`)
+
+// normalizeDirName lowercases a directory name and strips a leading number
+// and separators, so "05_Color", "color" and "Co-lor" compare equal.
+func normalizeDirName(name string) string {
+	name = dirNumberPrefix.ReplaceAllString(strings.ToLower(name), "")
+	return strings.NewReplacer(" ", "", "_", "", "-", "", ".", "").Replace(name)
+}
+
+// topLevel drops paths that are below another path of the sorted list.
+func topLevel(paths []string) []string {
+	var out []string
+	for _, p := range paths {
+		if len(out) > 0 && strings.HasPrefix(p, out[len(out)-1]+"/") {
+			continue
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+func printAuditReport(report auditView) {
+	if report.Audited == 0 {
+		fmt.Println("No projects to audit.")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintf(w, "  NAME\tTEMPLATE\tSTATUS\tMISSING\tEXTRA\tRENAMED\tRESULT\n")
+	fmt.Fprintf(w, "  ----\t--------\t------\t-------\t-----\t-------\t------\n")
+	for _, p := range report.Projects {
+		result := "ok"
+		switch {
+		case p.Error != "":
+			result = p.Error
+		case p.Failed:
+			result = "FAIL"
+		case p.Drift > 0:
+			result = "drift"
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%d\t%d\t%d\t%s\n",
+			p.Name, p.TemplateID, statusLabel(p.Status), len(p.Missing), len(p.Extra), len(p.Renamed), result)
+	}
+	w.Flush()
+
+	if verbose {
+		for _, p := range report.Projects {
+			if p.Drift == 0 {
+				continue
+			}
+			fmt.Printf("\n%s (%s)\n", p.Name, p.Path)
+			for _, m := range p.Missing {
+				fmt.Printf("  [MISSING] %s\n", m)
+			}
+			for _, e := range p.Extra {
+				fmt.Printf("  [EXTRA]   %s\n", e)
+			}
+			for _, r := range p.Renamed {
+				fmt.Printf("  [RENAMED] %s → %s\n", r.Expected, r.Actual)
+			}
+		}
+	}
+
+	fmt.Printf("\nAudited %d project(s): %d in sync, %d drifting", report.Audited, report.InSync, report.Drifting)
+	if report.MaxDrift >= 0 {
+		fmt.Printf(", %d past the threshold of %d", report.Failed, report.MaxDrift)
+	}
+	fmt.Println()
+}
+
+var auditHTMLTemplate = template.Must(template.New("audit").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>prjct audit</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; width: 100%; }
+th, td { text-align: left; padding: .4em .8em; border-bottom: 1px solid #ddd; vertical-align: top; }
+th { background: #f4f4f4; }
+tr.fail td { background: #fdecea; }
+tr.drift td { background: #fff8e1; }
+ul { margin: 0; padding-left: 1.2em; }
+.muted { color: #888; }
+</style>
+</head>
+<body>
+<h1>Project audit</h1>
+<p>{{.GeneratedAt.Format "2006-01-02 15:04"}} &middot; {{.Audited}} project(s): {{.InSync}} in sync, {{.Drifting}} drifting{{if ge .MaxDrift 0}}, {{.Failed}} past the threshold of {{.MaxDrift}}{{end}}</p>
+<table>
+<tr><th>Project</th><th>Template</th><th>Status</th><th>Missing</th><th>Extra</th><th>Renamed</th></tr>
+{{range .Projects}}<tr class="{{if .Failed}}fail{{else if .Drift}}drift{{end}}">
+<td>{{.Name}}<br><span class="muted">{{.Path}}</span>{{if .Error}}<br>{{.Error}}{{end}}</td>
+<td>{{.TemplateID}}</td>
+<td>{{.Status}}</td>
+<td>{{if .Missing}}<ul>{{range .Missing}}<li>{{.}}</li>{{end}}</ul>{{end}}</td>
+<td>{{if .Extra}}<ul>{{range .Extra}}<li>{{.}}</li>{{end}}</ul>{{end}}</td>
+<td>{{if .Renamed}}<ul>{{range .Renamed}}<li>{{.Expected}} &rarr; {{.Actual}}</li>{{end}}</ul>{{end}}</td>
+</tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// writeAuditHTML renders the report as a standalone HTML page to dest, or
+// to stdout if dest is "-".
+func writeAuditHTML(dest string, report auditView) error {
+	var w io.Writer = outputStdout
+	if dest != "-" {
+		f, err := os.Create(dest)
+		if err != nil {
+			return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("cannot write report: %v", err)}
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := auditHTMLTemplate.Execute(w, report); err != nil {
+		return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("cannot write report: %v", err)}
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fwartner/prjct/internal/index"
+	"github.com/spf13/cobra"
+)
+
+func TestPairRenames(t *testing.T) {
+	missing := []string{"Audio", "Color", "Color/LUTs", "Graphics"}
+	extra := []string{"05_Color", "05_Color/LUTs", "Edits", "Edits/Graphics", "Misc"}
+
+	restMissing, restExtra, renamed := pairRenames(missing, extra)
+	if strings.Join(restMissing, ",") != "Audio" {
+		t.Errorf("missing = %v, want [Audio]", restMissing)
+	}
+	if strings.Join(restExtra, ",") != "Edits,Misc" {
+		t.Errorf("extra = %v, want [Edits Misc]", restExtra)
+	}
+	want := []auditRename{{"Color", "05_Color"}, {"Graphics", "Edits/Graphics"}}
+	if len(renamed) != len(want) {
+		t.Fatalf("renamed = %+v, want %+v", renamed, want)
+	}
+	for i := range want {
+		if renamed[i] != want[i] {
+			t.Errorf("renamed[%d] = %+v, want %+v", i, renamed[i], want[i])
+		}
+	}
+}
+
+// auditTestSetup indexes a project that matches the test template and one
+// with a missing, a renamed and an extra directory.
+func auditTestSetup(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	base := filepath.Join(dir, "projects")
+	for _, d := range []string{"clean/src", "clean/docs", "drift/01_Src", "drift/misc/sub"} {
+		if err := os.MkdirAll(filepath.Join(base, d), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfgPath := writeTestConfigAt(t, filepath.Join(dir, "config.yaml"), base)
+	writeTestIndex(t, dir, []index.Entry{
+		{Name: "clean", TemplateID: "test", Path: filepath.Join(base, "clean")},
+		{Name: "drift", TemplateID: "test", Path: filepath.Join(base, "drift")},
+		{Name: "gone", TemplateID: "test", Path: filepath.Join(base, "gone"), Status: "archived"},
+	})
+	setConfigPath(t, cfgPath)
+	setAuditFlags(t, "", -1)
+	return dir
+}
+
+func setAuditFlags(t *testing.T, html string, maxDrift int) {
+	t.Helper()
+	oldHTML, oldMax := auditHTML, auditMaxDrift
+	auditHTML, auditMaxDrift = html, maxDrift
+	t.Cleanup(func() { auditHTML, auditMaxDrift = oldHTML, oldMax })
+}
+
+func TestRunAudit(t *testing.T) {
+	auditTestSetup(t)
+	buf := setOutputFormat(t, OutputJSON)
+
+	if err := runAudit(&cobra.Command{}, nil); err != nil {
+		t.Fatalf("runAudit() error: %v", err)
+	}
+	var env struct {
+		Data auditView `json:"data"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &env); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, buf.String())
+	}
+	r := env.Data
+	if r.Audited != 2 || r.InSync != 1 || r.Drifting != 1 || r.Failed != 0 {
+		t.Fatalf("summary = %+v, want 2 audited, 1 in sync, 1 drifting", r)
+	}
+	var drift auditProjectView
+	for _, p := range r.Projects {
+		if p.Name == "drift" {
+			drift = p
+		}
+	}
+	if strings.Join(drift.Missing, ",") != "docs" || strings.Join(drift.Extra, ",") != "misc" {
+		t.Errorf("missing = %v, extra = %v, want [docs] and [misc]", drift.Missing, drift.Extra)
+	}
+	if len(drift.Renamed) != 1 || drift.Renamed[0] != (auditRename{"src", "01_Src"}) {
+		t.Errorf("renamed = %+v, want src → 01_Src", drift.Renamed)
+	}
+	if drift.Drift != 3 {
+		t.Errorf("drift = %d, want 3", drift.Drift)
+	}
+}
+
+func TestRunAuditThresholdAndHTML(t *testing.T) {
+	dir := auditTestSetup(t)
+	report := filepath.Join(dir, "audit.html")
+	setAuditFlags(t, report, 2)
+	setOutputFormat(t, OutputTable)
+
+	err := runAudit(&cobra.Command{}, nil)
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != ExitAuditFailed {
+		t.Fatalf("runAudit() error = %v, want exit code %d", err, ExitAuditFailed)
+	}
+	data, err := os.ReadFile(report)
+	if err != nil {
+		t.Fatalf("report not written: %v", err)
+	}
+	for _, want := range []string{"<html", "drift", "src &rarr; 01_Src", `class="fail"`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("report does not contain %q", want)
+		}
+	}
+
+	setAuditFlags(t, "", 3)
+	if err := runAudit(&cobra.Command{}, nil); err != nil {
+		t.Errorf("runAudit() within threshold error: %v", err)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/fwartner/prjct/internal/index"
+	"github.com/fwartner/prjct/internal/journal"
 	"github.com/fwartner/prjct/internal/project"
 	tmplpkg "github.com/fwartner/prjct/internal/template"
 	"github.com/spf13/cobra"
@@ -19,8 +20,10 @@ type BulkManifest struct {
 
 // BulkProject is a single entry in a bulk manifest.
 type BulkProject struct {
-	Template string `yaml:"template"`
-	Name     string `yaml:"name"`
+	Template string            `yaml:"template"`
+	Name     string            `yaml:"name"`
+	Meta     map[string]string `yaml:"meta,omitempty"`
+	Tags     []string          `yaml:"tags,omitempty"`
 }
 
 var bulkCmd = &cobra.Command{
@@ -32,12 +35,24 @@ manifest format is:
   projects:
     - template: video
       name: "Client A Campaign"
+      meta:
+        client: "Client A"
+      tags: [rush]
     - template: photo
-      name: "Client A Portraits"`,
+      name: "Client A Portraits"
+
+Tags given with --tag are added to every project in the manifest.`,
 	Args: cobra.ExactArgs(1),
 	RunE: runBulk,
 }
 
+var bulkTags []string
+
+func init() {
+	bulkCmd.Flags().StringArrayVar(&bulkTags, "tag", nil, "tag every created project (repeatable)")
+	_ = bulkCmd.RegisterFlagCompletionFunc("tag", completeTags)
+}
+
 func runBulk(cmd *cobra.Command, args []string) error {
 	data, err := os.ReadFile(args[0])
 	if err != nil {
@@ -53,6 +68,11 @@ func runBulk(cmd *cobra.Command, args []string) error {
 		return &ExitError{Code: ExitGeneral, Message: "manifest contains no projects"}
 	}
 
+	commonTags, err := parseTags(bulkTags)
+	if err != nil {
+		return err
+	}
+
 	cfg, err := loadConfig()
 	if err != nil {
 		return err
@@ -82,6 +102,21 @@ func runBulk(cmd *cobra.Command, args []string) error {
 			vars[v.Name] = v.Default
 		}
 
+		meta, metaErr := buildMetadata(tmpl, vars, bp.Meta, nil)
+		if metaErr != nil {
+			fmt.Fprintf(os.Stderr, "  SKIP %q: %v\n", bp.Name, metaErr)
+			failed++
+			continue
+		}
+
+		tags, tagErr := parseTags(bp.Tags)
+		if tagErr != nil {
+			fmt.Fprintf(os.Stderr, "  SKIP %q: %v\n", bp.Name, tagErr)
+			failed++
+			continue
+		}
+		tags = append(tags, commonTags...)
+
 		opts := project.CreateOptions{
 			Verbose:   verbose,
 			DryRun:    dryRun,
@@ -95,13 +130,19 @@ func runBulk(cmd *cobra.Command, args []string) error {
 			continue
 		}
 
-		if !dryRun && idxPath != "" {
-			_ = index.Add(idxPath, index.Entry{
-				Name:         sanitized,
-				TemplateID:   tmpl.ID,
-				TemplateName: tmpl.Name,
-				Path:         result.ProjectPath,
-				CreatedAt:    time.Now(),
+		if !dryRun {
+			if idxPath != "" {
+				_ = index.Add(idxPath, newIndexEntry(cfg, tmpl, sanitized, result.ProjectPath, meta, tags, opts))
+			}
+			recordJournal(journal.Record{
+				Operation: journal.OpCreate,
+				Details: map[string]string{
+					"path":     result.ProjectPath,
+					"template": tmpl.ID,
+					"name":     sanitized,
+					"manifest": args[0],
+				},
+				Created: []string{result.ProjectPath},
 			})
 		}
 
@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/fwartner/prjct/internal/journal"
 	"github.com/spf13/cobra"
 )
 
@@ -90,3 +91,42 @@ func TestRunBulkBadFile(t *testing.T) {
 		t.Fatal("expected error for missing manifest")
 	}
 }
+
+func TestRunBulkJournalsGroupedCreates(t *testing.T) {
+	base := t.TempDir()
+	cfgPath := writeTestConfig(t, base)
+	setConfigPath(t, cfgPath)
+
+	manifest := `projects:
+  - template: test
+    name: "One"
+  - template: test
+    name: "Two"
+`
+	manifestPath := filepath.Join(t.TempDir(), "manifest.yaml")
+	_ = os.WriteFile(manifestPath, []byte(manifest), 0644)
+
+	beginInvocation()
+	if err := runBulk(&cobra.Command{}, []string{manifestPath}); err != nil {
+		t.Fatalf("runBulk() error: %v", err)
+	}
+	beginInvocation()
+	if err := runNote(&cobra.Command{}, []string{"One", "later"}); err != nil {
+		t.Fatalf("runNote() error: %v", err)
+	}
+
+	j, _ := journal.Load(filepath.Join(filepath.Dir(cfgPath), "journal.json"))
+	if len(j.Records) != 3 {
+		t.Fatalf("got %d records, want 3", len(j.Records))
+	}
+	creates := j.Records[:2]
+	if creates[0].Operation != journal.OpCreate || creates[0].Group == "" || creates[0].Group != creates[1].Group {
+		t.Errorf("bulk creates should share a group: %+v", creates)
+	}
+	if len(creates[0].Created) != 1 {
+		t.Errorf("Created = %v, want project path", creates[0].Created)
+	}
+	if j.Records[2].Group == creates[0].Group {
+		t.Error("a new invocation should start a new group")
+	}
+}
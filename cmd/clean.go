@@ -5,21 +5,52 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 
+	"github.com/fwartner/prjct/internal/archive"
 	"github.com/fwartner/prjct/internal/index"
+	"github.com/fwartner/prjct/internal/journal"
 	"github.com/spf13/cobra"
 )
 
+var (
+	cleanIncludeTemplateDirs bool
+	cleanJunk                bool
+)
+
 var cleanCmd = &cobra.Command{
 	Use:   "clean <query>",
 	Short: "Remove empty directories from a project",
 	Long: `Searches the project index and removes all empty directories
 from the first matching project. Operates recursively — a directory
-that becomes empty after its children are removed is also deleted.`,
+that becomes empty after its children are removed is also deleted.
+
+Directories defined by the project's template are kept even when empty;
+use --include-template-dirs to remove them too. Directories matching a
+clean_ignore pattern of the template are never touched:
+
+  clean_ignore:
+    - "05_Assets/Fonts"
+    - ".git/"
+
+With --junk, directories holding nothing but .DS_Store or Thumbs.db files
+count as empty; those files are deleted with them.`,
 	Args: cobra.ExactArgs(1),
 	RunE: runClean,
 }
 
+// junkFiles are files the operating system leaves behind that --junk
+// does not count as content.
+var junkFiles = map[string]bool{
+	".DS_Store": true,
+	"Thumbs.db": true,
+}
+
+func init() {
+	cleanCmd.Flags().BoolVar(&cleanIncludeTemplateDirs, "include-template-dirs", false, "also remove empty directories defined by the template")
+	cleanCmd.Flags().BoolVar(&cleanJunk, "junk", false, "treat directories containing only .DS_Store or Thumbs.db as empty")
+}
+
 func runClean(cmd *cobra.Command, args []string) error {
 	idxPath, err := resolveIndexPath()
 	if err != nil {
@@ -43,6 +74,28 @@ func runClean(cmd *cobra.Command, args []string) error {
 		return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("project directory not found: %s", projectPath)}
 	}
 
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	keep := make(map[string]bool)
+	var ignorePatterns []string
+	if tmpl, err := cfg.ResolveTemplate(entry.TemplateID); err == nil {
+		if !cleanIncludeTemplateDirs {
+			layout, _ := templateLayout(tmpl, entry)
+			for _, d := range layout {
+				keep[d] = true
+			}
+		}
+		ignorePatterns = tmpl.CleanIgnore
+	} else if !cleanIncludeTemplateDirs {
+		return &ExitError{Code: ExitTemplateNotFound, Message: fmt.Sprintf("template %q not found in config; use --include-template-dirs to clean without it", entry.TemplateID)}
+	}
+	ignore, err := archive.CompilePatterns(ignorePatterns)
+	if err != nil {
+		return &ExitError{Code: ExitConfigInvalid, Message: fmt.Sprintf("template %q: clean_ignore: %v", entry.TemplateID, err)}
+	}
+
 	// Collect all directories, deepest first
 	var dirs []string
 	_ = filepath.WalkDir(projectPath, func(path string, d os.DirEntry, walkErr error) error {
@@ -52,6 +105,10 @@ func runClean(cmd *cobra.Command, args []string) error {
 		if path == projectPath {
 			return nil // don't remove the root
 		}
+		rel, _ := filepath.Rel(projectPath, path)
+		if ignore.Match(filepath.ToSlash(rel), true) {
+			return filepath.SkipDir
+		}
 		dirs = append(dirs, path)
 		return nil
 	})
@@ -61,21 +118,41 @@ func runClean(cmd *cobra.Command, args []string) error {
 		return len(dirs[i]) > len(dirs[j])
 	})
 
-	removed := 0
+	removed, kept, junkRemoved := 0, 0, 0
+	var removedPaths []string
+	// gone holds the directories removed so far, so a dry run also finds
+	// parents that only become empty once their children are removed
+	gone := make(map[string]bool)
 	for _, dir := range dirs {
-		entries, err := os.ReadDir(dir)
-		if err != nil {
+		junk, ok := emptyDir(dir, gone)
+		if !ok {
 			continue
 		}
-		if len(entries) > 0 {
+		rel, _ := filepath.Rel(projectPath, dir)
+		if keep[filepath.ToSlash(rel)] {
+			if verbose {
+				fmt.Printf("  keep %s (template)\n", dir)
+			}
+			kept++
 			continue
 		}
 
 		if dryRun {
+			for _, f := range junk {
+				fmt.Printf("  [DRY-RUN] rm %s\n", f)
+			}
 			fmt.Printf("  [DRY-RUN] rmdir %s\n", dir)
+			gone[dir] = true
 			removed++
 			continue
 		}
+		if err := removeJunk(junk); err != nil {
+			if verbose {
+				fmt.Fprintf(os.Stderr, "  Warning: %v\n", err)
+			}
+			continue
+		}
+		junkRemoved += len(junk)
 		if err := os.Remove(dir); err != nil {
 			if verbose {
 				fmt.Fprintf(os.Stderr, "  Warning: cannot remove %s: %v\n", dir, err)
@@ -85,13 +162,63 @@ func runClean(cmd *cobra.Command, args []string) error {
 		if verbose {
 			fmt.Printf("  rmdir %s\n", dir)
 		}
+		gone[dir] = true
+		removedPaths = append(removedPaths, dir)
 		removed++
 	}
 
+	if len(removedPaths) > 0 {
+		details := map[string]string{"path": projectPath}
+		if junkRemoved > 0 {
+			details["junk_files"] = strconv.Itoa(junkRemoved)
+		}
+		recordJournal(journal.Record{
+			Operation: journal.OpClean,
+			Details:   details,
+			Removed:   removedPaths,
+		})
+	}
+
 	if dryRun {
-		fmt.Printf("Dry run — would remove %d empty directory(ies)\n", removed)
+		fmt.Printf("Dry run — would remove %d empty directory(ies)", removed)
 	} else {
-		fmt.Printf("Removed %d empty directory(ies)\n", removed)
+		fmt.Printf("Removed %d empty directory(ies)", removed)
+	}
+	if kept > 0 {
+		fmt.Printf(", kept %d empty template directory(ies)", kept)
+	}
+	fmt.Println()
+	return nil
+}
+
+// emptyDir reports whether dir is empty once the directories in gone are
+// removed. With --junk, a directory holding only junk files counts as
+// empty; their paths are returned so they can be removed first.
+func emptyDir(dir string, gone map[string]bool) ([]string, bool) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, false
+	}
+	var junk []string
+	for _, e := range entries {
+		path := filepath.Join(dir, e.Name())
+		switch {
+		case gone[path]:
+		case cleanJunk && e.Type().IsRegular() && junkFiles[e.Name()]:
+			junk = append(junk, path)
+		default:
+			return nil, false
+		}
+	}
+	return junk, true
+}
+
+// removeJunk deletes the given junk files.
+func removeJunk(files []string) error {
+	for _, f := range files {
+		if err := os.Remove(f); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -77,3 +77,93 @@ func TestRunCleanNoEmpty(t *testing.T) {
 		t.Fatalf("runClean() error: %v", err)
 	}
 }
+
+// cleanTestSetup creates a project with empty template directories, an
+// ignored directory and directories holding only junk files.
+func cleanTestSetup(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	base := filepath.Join(dir, "projects")
+	project := filepath.Join(base, "spot")
+	for _, d := range []string{
+		"01_Footage", "Edits/Graphics", "Assets/Fonts", "Scratch/Old", "Junk/Deep",
+	} {
+		if err := os.MkdirAll(filepath.Join(project, d), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = os.WriteFile(filepath.Join(project, "Junk", ".DS_Store"), []byte("x"), 0644)
+	_ = os.WriteFile(filepath.Join(project, "Junk", "Deep", "Thumbs.db"), []byte("x"), 0644)
+	cfgPath := filepath.Join(dir, "config.yaml")
+	cfg := "templates:\n  - id: video\n    name: Video\n    base_path: " + base + `
+    clean_ignore:
+      - Assets/Fonts
+    directories:
+      - name: 01_Footage
+      - name: Edits
+        children:
+          - name: Graphics
+`
+	if err := os.WriteFile(cfgPath, []byte(cfg), 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeTestIndex(t, dir, []index.Entry{{Name: "spot", TemplateID: "video", Path: project}})
+	setConfigPath(t, cfgPath)
+	return project
+}
+
+func setCleanFlags(t *testing.T, includeTemplateDirs, junk bool) {
+	t.Helper()
+	oldInclude, oldJunk := cleanIncludeTemplateDirs, cleanJunk
+	cleanIncludeTemplateDirs, cleanJunk = includeTemplateDirs, junk
+	t.Cleanup(func() { cleanIncludeTemplateDirs, cleanJunk = oldInclude, oldJunk })
+}
+
+func TestRunCleanKeepsTemplateAndIgnoredDirs(t *testing.T) {
+	project := cleanTestSetup(t)
+	setCleanFlags(t, false, false)
+
+	if err := runClean(&cobra.Command{}, []string{"spot"}); err != nil {
+		t.Fatalf("runClean() error: %v", err)
+	}
+	for _, d := range []string{"01_Footage", "Edits/Graphics", "Assets/Fonts", "Junk/Deep"} {
+		if _, err := os.Stat(filepath.Join(project, d)); err != nil {
+			t.Errorf("%s should remain: %v", d, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(project, "Scratch")); !os.IsNotExist(err) {
+		t.Error("Scratch should be removed")
+	}
+}
+
+func TestRunCleanIncludeTemplateDirsAndJunk(t *testing.T) {
+	project := cleanTestSetup(t)
+	setCleanFlags(t, true, true)
+
+	if err := runClean(&cobra.Command{}, []string{"spot"}); err != nil {
+		t.Fatalf("runClean() error: %v", err)
+	}
+	for _, d := range []string{"01_Footage", "Edits", "Scratch", "Junk"} {
+		if _, err := os.Stat(filepath.Join(project, d)); !os.IsNotExist(err) {
+			t.Errorf("%s should be removed", d)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(project, "Assets/Fonts")); err != nil {
+		t.Errorf("ignored Assets/Fonts should remain: %v", err)
+	}
+}
+
+func TestRunCleanDryRunCountsNested(t *testing.T) {
+	project := cleanTestSetup(t)
+	setCleanFlags(t, false, true)
+	setDryRun(t, true)
+
+	if err := runClean(&cobra.Command{}, []string{"spot"}); err != nil {
+		t.Fatalf("runClean() error: %v", err)
+	}
+	for _, d := range []string{"Scratch/Old", "Junk/Deep/Thumbs.db"} {
+		if _, err := os.Stat(filepath.Join(project, d)); err != nil {
+			t.Errorf("dry run should not remove %s: %v", d, err)
+		}
+	}
+}
@@ -1,30 +1,110 @@
 package cmd
 
 import (
+	"bytes"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
+	"github.com/fwartner/prjct/internal/archive"
+	"github.com/fwartner/prjct/internal/config"
+	"github.com/fwartner/prjct/internal/fscopy"
 	"github.com/fwartner/prjct/internal/index"
+	"github.com/fwartner/prjct/internal/journal"
 	"github.com/fwartner/prjct/internal/project"
+	tmplpkg "github.com/fwartner/prjct/internal/template"
 	"github.com/spf13/cobra"
 )
 
-var cloneWithFiles bool
+var (
+	cloneWithFiles       bool
+	cloneReplaceContents bool
+	cloneVars            []string
+	cloneLink            string
+	cloneExclude         []string
+	cloneQuiet           bool
+)
 
 var cloneCmd = &cobra.Command{
 	Use:   "clone <query> <new-name>",
 	Short: "Clone a project's directory structure",
 	Long: `Creates a new project by duplicating the directory structure of an
-existing project. Use --with-files to also copy file contents.`,
+existing project. Use --with-files to also copy file contents.
+
+The old project name is replaced with the new one in the names of all
+copied directories and files, so "Client A_Edit_v1.prproj" becomes
+"Client B_Edit_v1.prproj". With --replace-contents it is also replaced
+inside text files.
+
+Files defined by the template are rendered again for the new project,
+with {name} and {date} of the clone and any --var values. A template file
+that was edited in the source project keeps the copied content instead.
+The clone starts with the workflow's initial status and no notes, keeps
+the source's metadata and tags, and records the source as cloned_from.
+
+--link chooses how file data is copied and implies --with-files:
+
+  copy     full copies (default)
+  reflink  copy-on-write clones on filesystems that support them, such as
+           Btrfs and XFS on Linux; other files are copied
+  hard     hard links, which share the files with the source; files that
+           cannot be linked are cloned or copied
+
+Copies keep the permissions and modification times of the source, and
+symlinks are recreated. --exclude skips files and directories matching
+gitignore-style patterns like "Cache/" or "*.tmp". Progress is shown on
+stderr while files are copied; use --quiet to hide it.`,
 	Args: cobra.ExactArgs(2),
 	RunE: runClone,
 }
 
+// cloneMaxTextSize is the largest file --replace-contents rewrites.
+const cloneMaxTextSize = 8 << 20
+
 func init() {
 	cloneCmd.Flags().BoolVar(&cloneWithFiles, "with-files", false, "also copy files")
+	cloneCmd.Flags().BoolVar(&cloneReplaceContents, "replace-contents", false, "also replace the old project name inside copied text files")
+	cloneCmd.Flags().StringArrayVar(&cloneVars, "var", nil, "set a template variable for the clone (key=value, repeatable)")
+	cloneCmd.Flags().StringVar(&cloneLink, "link", string(fscopy.Copy), "how to copy files: hard, reflink or copy")
+	cloneCmd.Flags().StringArrayVar(&cloneExclude, "exclude", nil, "skip paths matching a gitignore-style pattern (repeatable)")
+	cloneCmd.Flags().BoolVarP(&cloneQuiet, "quiet", "q", false, "do not show progress")
+}
+
+// cloneOptions control what cloneTree copies.
+type cloneOptions struct {
+	WithFiles bool
+	// OldName is replaced with NewName in the relative path of everything
+	// copied, and inside text files when ReplaceContents is set.
+	OldName         string
+	NewName         string
+	ReplaceContents bool
+	// Method is how file data is copied; Exclude lists the paths, relative
+	// to the source, that are left out.
+	Method  fscopy.Method
+	Exclude []string
+	// Progress, if not nil, is called as files are copied.
+	Progress func(archive.Progress)
+}
+
+// cloneStats counts what cloneTree copied.
+type cloneStats struct {
+	Dirs  int
+	Files int
+	// Methods counts the files by the method actually used.
+	Methods map[fscopy.Method]int
+}
+
+// rename returns rel with OldName replaced by NewName.
+func (o cloneOptions) rename(rel string) string {
+	if o.OldName == "" || o.OldName == o.NewName {
+		return rel
+	}
+	return strings.ReplaceAll(rel, o.OldName, o.NewName)
 }
 
 func runClone(cmd *cobra.Command, args []string) error {
@@ -60,84 +140,458 @@ func runClone(cmd *cobra.Command, args []string) error {
 		return &ExitError{Code: ExitProjectExists, Message: fmt.Sprintf("destination already exists: %s", destPath)}
 	}
 
+	vars, err := parseKeyValues(cloneVars)
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	tmpl, tmplErr := cfg.ResolveTemplate(entry.TemplateID)
+	if tmplErr != nil && len(vars) > 0 {
+		return &ExitError{Code: ExitTemplateNotFound, Message: fmt.Sprintf("template %q not found in config; --var needs the template", entry.TemplateID)}
+	}
+	clone := newCloneEntry(cfg, entry, newName, destPath)
+	if tmplErr == nil {
+		if err := setCloneVars(tmpl, &clone, entry, vars); err != nil {
+			return err
+		}
+	}
+
+	method, err := fscopy.ParseMethod(cloneLink)
+	if err != nil {
+		return &ExitError{Code: ExitGeneral, Message: err.Error()}
+	}
+	if _, err := archive.CompilePatterns(cloneExclude); err != nil {
+		return &ExitError{Code: ExitGeneral, Message: err.Error()}
+	}
+	opts := cloneOptions{
+		WithFiles:       cloneWithFiles || method != fscopy.Copy,
+		OldName:         entry.Name,
+		NewName:         newName,
+		ReplaceContents: cloneReplaceContents,
+		Method:          method,
+		Exclude:         cloneExclude,
+	}
+
 	if dryRun {
 		fmt.Printf("Dry run — would clone %s to %s\n", sourcePath, destPath)
 		return nil
 	}
 
-	dirCount := 0
-	fileCount := 0
+	var progress *archiveProgress
+	if opts.WithFiles && !cloneQuiet && !isStructuredOutput() {
+		progress = newArchiveProgress()
+		opts.Progress = progress.update
+	}
+	stats, err := cloneTree(sourcePath, destPath, opts)
+	if progress != nil {
+		progress.finish()
+	}
+	if err != nil {
+		return cloneFailed(destPath, err)
+	}
+	rendered := 0
+	if tmplErr == nil {
+		written, err := renderCloneFiles(tmpl, entry, &clone, opts)
+		if err != nil {
+			return cloneFailed(destPath, err)
+		}
+		rendered = len(written)
+	}
+
+	// Best-effort index update
+	_ = index.Add(idxPath, clone)
+
+	recordJournal(journal.Record{
+		Operation: journal.OpClone,
+		Details: map[string]string{
+			"path":             destPath,
+			"source":           sourcePath,
+			"source_name":      entry.Name,
+			"name":             newName,
+			"template":         entry.TemplateID,
+			"with_files":       strconv.FormatBool(opts.WithFiles),
+			"replace_contents": strconv.FormatBool(cloneReplaceContents),
+			"link":             string(method),
+			"exclude":          strings.Join(cloneExclude, "\n"),
+		},
+		Created: []string{destPath},
+	})
+
+	fmt.Printf("Cloned: %s\n", sourcePath)
+	fmt.Printf("    To: %s\n", destPath)
+	fmt.Printf("  Dirs: %d\n", stats.Dirs)
+	if stats.Files > 0 {
+		fmt.Printf(" Files: %d%s\n", stats.Files, methodSummary(stats.Methods))
+	}
+	if rendered > 0 {
+		fmt.Printf("Rendered: %d template file(s)\n", rendered)
+	}
+	return nil
+}
+
+// newCloneEntry returns the index entry of a clone of source: it keeps the
+// template, metadata and tags, and starts over with the workflow's initial
+// status and no notes.
+func newCloneEntry(cfg *config.Config, source index.Entry, name, path string) index.Entry {
+	now := time.Now()
+	e := index.Entry{
+		Name:            name,
+		TemplateID:      source.TemplateID,
+		TemplateName:    source.TemplateName,
+		Path:            path,
+		CreatedAt:       now,
+		SkippedOptional: source.SkippedOptional,
+		TemplateVersion: source.TemplateVersion,
+		ClonedFrom:      source.Path,
+	}
+	if len(source.Metadata) > 0 {
+		e.Metadata = make(map[string]string, len(source.Metadata))
+		for k, v := range source.Metadata {
+			e.Metadata[k] = v
+		}
+	}
+	e.AddTags(source.Tags...)
+	e.SetStatus(cfg.ResolveWorkflow().InitialStatus(), now)
+	return e
+}
+
+// setCloneVars sets the variables clone is rendered with: those of source,
+// the built-in variables of the clone, then values. Persisted variables are
+// updated in the clone's metadata as well.
+func setCloneVars(tmpl *config.Template, clone *index.Entry, source index.Entry, values map[string]string) error {
+	declared := make(map[string]config.Variable, len(tmpl.Variables))
+	for _, v := range tmpl.Variables {
+		declared[v.Name] = v
+	}
+	for k := range values {
+		if _, ok := declared[k]; !ok {
+			return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("%q is not a variable of template %q", k, tmpl.ID)}
+		}
+	}
+
+	vars := creationVars(tmpl, source)
+	for k, v := range tmplpkg.BuiltinVars(clone.Name, clone.CreatedAt) {
+		vars[k] = v
+	}
+	delete(vars, "path")
+	for k, v := range values {
+		vars[k] = v
+		if declared[k].Persist {
+			if clone.Metadata == nil {
+				clone.Metadata = make(map[string]string)
+			}
+			clone.Metadata[k] = v
+		}
+	}
+	clone.Variables = vars
+	return nil
+}
+
+// renderCloneFiles writes the template files of clone rendered with its own
+// variables and records their hashes. A file that source still has as it
+// was generated is replaced; a file edited in source keeps the copied
+// content. It returns the slash-separated paths written.
+func renderCloneFiles(tmpl *config.Template, source index.Entry, clone *index.Entry, opts cloneOptions) ([]string, error) {
+	generated := make(map[string]bool)
+	_, sourceFiles := templateLayout(tmpl, source)
+	for _, f := range sourceFiles {
+		current, err := os.ReadFile(filepath.Join(source.Path, filepath.FromSlash(f.Path)))
+		if err != nil {
+			continue
+		}
+		hash, known := source.FileHashes[f.Path]
+		if !known {
+			hash = project.HashContent([]byte(f.Content))
+		}
+		if project.HashContent(current) == hash {
+			generated[opts.rename(f.Path)] = true
+		}
+	}
+
+	var written []string
+	_, files := templateLayout(tmpl, *clone)
+	if len(files) > 0 {
+		clone.FileHashes = make(map[string]string, len(files))
+	}
+	for _, f := range files {
+		clone.FileHashes[f.Path] = project.HashContent([]byte(f.Content))
+		target := filepath.Join(clone.Path, filepath.FromSlash(f.Path))
+		if _, err := os.Lstat(target); err == nil {
+			if !generated[f.Path] {
+				continue
+			}
+			// The copy may be a hard link to the source's file
+			if err := os.Remove(target); err != nil {
+				return written, err
+			}
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return written, err
+		}
+		if err := os.WriteFile(target, []byte(f.Content), 0644); err != nil {
+			return written, err
+		}
+		if verbose {
+			fmt.Printf("  render %s\n", target)
+		}
+		written = append(written, f.Path)
+	}
+	return written, nil
+}
+
+// rerenderClone renders the template files of clone again after its tree
+// was copied from source once more, as redo does. Failures are ignored;
+// sync can create missing files later.
+func rerenderClone(idxPath string, clone *index.Entry, source string, opts cloneOptions) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return
+	}
+	tmpl, err := cfg.ResolveTemplate(clone.TemplateID)
+	if err != nil {
+		return
+	}
+	sourceEntry, ok := findEntry(idxPath, source)
+	if !ok {
+		sourceEntry = index.Entry{Name: opts.OldName, TemplateID: clone.TemplateID, Path: source}
+	}
+	_, _ = renderCloneFiles(tmpl, sourceEntry, clone, opts)
+}
+
+// cloneTree copies the directory structure of src to dst, including files
+// when opts.WithFiles is set, renaming paths as opts says. Copied
+// directories keep their permissions and, with files, their modification
+// times.
+func cloneTree(src, dst string, opts cloneOptions) (cloneStats, error) {
+	stats := cloneStats{Methods: make(map[fscopy.Method]int)}
+	exclude, err := archive.CompilePatterns(opts.Exclude)
+	if err != nil {
+		return stats, err
+	}
+
+	if collisions := cloneCollisions(src, exclude, opts); len(collisions) > 0 {
+		return stats, fmt.Errorf("replacing %q with %q in paths gives several paths the same name:\n  %s", opts.OldName, opts.NewName, strings.Join(collisions, "\n  "))
+	}
+
+	var state archive.Progress
+	if opts.Progress != nil {
+		state.Total = measureClone(src, exclude)
+		opts.Progress(state)
+	}
+	onBytes := func(n int64) {
+		if opts.Progress != nil {
+			state.Bytes += n
+			opts.Progress(state)
+		}
+	}
 
-	err = filepath.WalkDir(sourcePath, func(path string, d os.DirEntry, walkErr error) error {
+	type copiedDir struct {
+		target string
+		info   os.FileInfo
+	}
+	var dirs []copiedDir
+	err = filepath.WalkDir(src, func(path string, d os.DirEntry, walkErr error) error {
 		if walkErr != nil {
 			return walkErr
 		}
 
-		rel, relErr := filepath.Rel(sourcePath, path)
+		rel, relErr := filepath.Rel(src, path)
 		if relErr != nil {
 			return relErr
 		}
+		if rel != "." && exclude.Match(filepath.ToSlash(rel), d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
 
-		target := filepath.Join(destPath, rel)
+		target := filepath.Join(dst, opts.rename(rel))
 
 		if d.IsDir() {
 			if mkErr := os.MkdirAll(target, 0755); mkErr != nil {
 				return mkErr
 			}
-			dirCount++
+			if info, infoErr := d.Info(); infoErr == nil {
+				dirs = append(dirs, copiedDir{target, info})
+			}
+			stats.Dirs++
 			if verbose {
 				fmt.Printf("  mkdir %s\n", target)
 			}
 			return nil
 		}
 
-		if cloneWithFiles {
-			if cpErr := copyFile(path, target); cpErr != nil {
-				return cpErr
-			}
-			fileCount++
+		if !opts.WithFiles {
+			return nil
+		}
+		if !d.Type().IsRegular() && d.Type()&os.ModeSymlink == 0 {
 			if verbose {
-				fmt.Printf("  copy  %s\n", target)
+				fmt.Printf("  skip    %s (not a regular file)\n", path)
+			}
+			return nil
+		}
+		method := fscopy.Copy
+		replaced, rpErr := replaceInTextFile(path, target, opts)
+		if rpErr != nil {
+			return rpErr
+		}
+		if !replaced {
+			var cpErr error
+			if method, cpErr = fscopy.File(path, target, opts.Method, onBytes); cpErr != nil {
+				return cpErr
 			}
+		} else if info, infoErr := d.Info(); infoErr == nil {
+			onBytes(info.Size())
+		}
+		stats.Files++
+		stats.Methods[method]++
+		if opts.Progress != nil && d.Type().IsRegular() {
+			state.Files++
+			opts.Progress(state)
+		}
+		if verbose {
+			fmt.Printf("  %-7s %s\n", method, target)
 		}
 		return nil
 	})
-
 	if err != nil {
-		return &ExitError{Code: ExitCreateFailed, Message: fmt.Sprintf("clone failed: %v", err)}
+		return stats, err
 	}
 
-	// Best-effort index update
-	_ = index.Add(idxPath, index.Entry{
-		Name:         newName,
-		TemplateID:   entry.TemplateID,
-		TemplateName: entry.TemplateName,
-		Path:         destPath,
-		CreatedAt:    time.Now(),
+	// Deepest first, so setting a directory's time is not undone by
+	// changes inside it
+	for i := len(dirs) - 1; i >= 0; i-- {
+		d := dirs[i]
+		if err := os.Chmod(d.target, d.info.Mode().Perm()); err != nil {
+			return stats, err
+		}
+		if opts.WithFiles {
+			if err := fscopy.SetModTime(d.target, d.info); err != nil {
+				return stats, err
+			}
+		}
+	}
+	return stats, nil
+}
+
+// cloneFailed removes the partly written clone at destPath, which did not
+// exist before, and returns the error to report.
+func cloneFailed(destPath string, err error) error {
+	if rmErr := os.RemoveAll(destPath); rmErr != nil {
+		return &ExitError{Code: ExitCreateFailed, Message: fmt.Sprintf("clone failed: %v; cannot remove %s: %v", err, destPath, rmErr)}
+	}
+	return &ExitError{Code: ExitCreateFailed, Message: fmt.Sprintf("clone failed: %v", err)}
+}
+
+// cloneCollisions lists the paths below src that opts.rename maps to the
+// same relative path, e.g. "a, b → c". Nothing is copied while there are
+// any, as one would overwrite or merge into the other.
+func cloneCollisions(src string, exclude *archive.Patterns, opts cloneOptions) []string {
+	if opts.OldName == "" || opts.OldName == opts.NewName {
+		return nil
+	}
+	sources := make(map[string][]string)
+	_ = filepath.WalkDir(src, func(path string, d os.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return nil
+		}
+		rel, _ := filepath.Rel(src, path)
+		if rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if exclude.Match(rel, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || opts.WithFiles {
+			target := opts.rename(rel)
+			sources[target] = append(sources[target], rel)
+		}
+		return nil
 	})
 
-	fmt.Printf("Cloned: %s\n", sourcePath)
-	fmt.Printf("    To: %s\n", destPath)
-	fmt.Printf("  Dirs: %d\n", dirCount)
-	if fileCount > 0 {
-		fmt.Printf(" Files: %d\n", fileCount)
+	var collisions []string
+	for target, paths := range sources {
+		if len(paths) > 1 {
+			collisions = append(collisions, fmt.Sprintf("%s → %s", strings.Join(paths, ", "), target))
+		}
 	}
-	return nil
+	sort.Strings(collisions)
+	return collisions
 }
 
-func copyFile(src, dst string) error {
-	in, err := os.Open(src)
-	if err != nil {
-		return err
+// measureClone returns the number and size of the regular files below src
+// that are not excluded.
+func measureClone(src string, exclude *archive.Patterns) archive.Totals {
+	var t archive.Totals
+	_ = filepath.WalkDir(src, func(path string, d os.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return nil
+		}
+		rel, _ := filepath.Rel(src, path)
+		if rel != "." && exclude.Match(filepath.ToSlash(rel), d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				t.Files++
+				t.Bytes += info.Size()
+			}
+		}
+		return nil
+	})
+	return t
+}
+
+// methodSummary describes how the files of a clone were copied, e.g.
+// " (120 hard, 3 copy)", or returns "" if they were all copied.
+func methodSummary(methods map[fscopy.Method]int) string {
+	if len(methods) == 1 && methods[fscopy.Copy] > 0 {
+		return ""
+	}
+	var parts []string
+	for _, m := range []fscopy.Method{fscopy.Hard, fscopy.Reflink, fscopy.Copy, fscopy.Symlink} {
+		if n := methods[m]; n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, m))
+		}
 	}
-	defer in.Close()
+	return " (" + strings.Join(parts, ", ") + ")"
+}
 
-	out, err := os.Create(dst)
+// replaceInTextFile writes src to dst with the old project name replaced
+// when opts.ReplaceContents is set and src is a text file containing it.
+// It reports whether it wrote dst.
+func replaceInTextFile(src, dst string, opts cloneOptions) (bool, error) {
+	if !opts.ReplaceContents || opts.OldName == "" || opts.OldName == opts.NewName {
+		return false, nil
+	}
+	info, err := os.Lstat(src)
+	if err != nil || !info.Mode().IsRegular() || info.Size() > cloneMaxTextSize {
+		return false, err
+	}
+	data, err := os.ReadFile(src)
 	if err != nil {
-		return err
+		return false, err
 	}
-	defer out.Close()
+	old := []byte(opts.OldName)
+	if !isText(data) || !bytes.Contains(data, old) {
+		return false, nil
+	}
+	data = bytes.ReplaceAll(data, old, []byte(opts.NewName))
+	return true, os.WriteFile(dst, data, info.Mode().Perm())
+}
 
-	_, err = io.Copy(out, in)
-	return err
+// isText reports whether data looks like UTF-8 text.
+func isText(data []byte) bool {
+	return bytes.IndexByte(data, 0) < 0 && utf8.Valid(data)
 }
@@ -5,10 +5,13 @@ import (
 	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/fwartner/prjct/internal/index"
+	"github.com/fwartner/prjct/internal/journal"
+	"github.com/fwartner/prjct/internal/project"
 	"github.com/spf13/cobra"
 )
 
@@ -19,6 +22,13 @@ func setCloneWithFiles(t *testing.T, val bool) {
 	t.Cleanup(func() { cloneWithFiles = old })
 }
 
+func setCloneFlags(t *testing.T, replaceContents bool, vars []string) {
+	t.Helper()
+	oldReplace, oldVars := cloneReplaceContents, cloneVars
+	cloneReplaceContents, cloneVars = replaceContents, vars
+	t.Cleanup(func() { cloneReplaceContents, cloneVars = oldReplace, oldVars })
+}
+
 func TestRunClone(t *testing.T) {
 	base := t.TempDir()
 	cfgPath := writeTestConfig(t, base)
@@ -141,3 +151,259 @@ func TestRunCloneDestExists(t *testing.T) {
 		t.Errorf("Code = %d, want %d", exitErr.Code, ExitProjectExists)
 	}
 }
+
+func TestRunCloneRenameCollision(t *testing.T) {
+	base := t.TempDir()
+	cfgPath := writeTestConfig(t, base)
+	setConfigPath(t, cfgPath)
+	setCloneWithFiles(t, true)
+
+	sourceDir := filepath.Join(base, "Spot")
+	_ = os.MkdirAll(filepath.Join(sourceDir, "Spot_Edit"), 0755)
+	_ = os.MkdirAll(filepath.Join(sourceDir, "Promo_Edit"), 0755)
+	_ = os.WriteFile(filepath.Join(sourceDir, "Spot_Edit", "cut.prproj"), []byte("spot"), 0644)
+	_ = os.WriteFile(filepath.Join(sourceDir, "Promo_Edit", "cut.prproj"), []byte("promo"), 0644)
+	writeTestIndex(t, filepath.Dir(cfgPath), []index.Entry{
+		{Name: "Spot", TemplateID: "test", Path: sourceDir, CreatedAt: time.Now()},
+	})
+
+	err := runClone(&cobra.Command{}, []string{"Spot", "Promo"})
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != ExitCreateFailed {
+		t.Fatalf("runClone() = %v, want a collision error", err)
+	}
+	if !strings.Contains(exitErr.Message, "Promo_Edit, Spot_Edit → Promo_Edit") {
+		t.Errorf("message %q does not name the colliding paths", exitErr.Message)
+	}
+	if _, err := os.Stat(filepath.Join(base, "Promo")); !os.IsNotExist(err) {
+		t.Error("clone directory left behind after the failure")
+	}
+}
+
+func TestRunCloneJournalAndRedo(t *testing.T) {
+	base := t.TempDir()
+	cfgPath := writeTestConfig(t, base)
+	setConfigPath(t, cfgPath)
+	setCloneWithFiles(t, true)
+
+	sourceDir := filepath.Join(base, "Original")
+	_ = os.MkdirAll(filepath.Join(sourceDir, "src"), 0755)
+	_ = os.WriteFile(filepath.Join(sourceDir, "src", "a.txt"), []byte("a"), 0644)
+	writeTestIndex(t, filepath.Dir(cfgPath), []index.Entry{
+		{Name: "Original", TemplateID: "test", Path: sourceDir, CreatedAt: time.Now()},
+	})
+
+	if err := runClone(&cobra.Command{}, []string{"Original", "Copy"}); err != nil {
+		t.Fatalf("runClone() error: %v", err)
+	}
+	clonedDir := filepath.Join(base, "Copy")
+	rec, _ := journal.Last(filepath.Join(filepath.Dir(cfgPath), "journal.json"))
+	if rec == nil || rec.Operation != journal.OpClone || rec.Details["source"] != sourceDir || rec.Details["path"] != clonedDir {
+		t.Fatalf("journal record = %+v, want clone", rec)
+	}
+
+	if err := runUndo(&cobra.Command{}, nil); err != nil {
+		t.Fatalf("runUndo() error: %v", err)
+	}
+	if _, err := os.Stat(clonedDir); !os.IsNotExist(err) {
+		t.Fatal("undo should remove the clone")
+	}
+	if err := runRedo(&cobra.Command{}, nil); err != nil {
+		t.Fatalf("runRedo() error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(clonedDir, "src", "a.txt")); err != nil {
+		t.Errorf("redo should clone files again: %v", err)
+	}
+}
+
+func TestRunCloneTemplateAware(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "projects")
+	cfgPath := filepath.Join(dir, "config.yaml")
+	_ = os.WriteFile(cfgPath, []byte("templates:\n  - id: video\n    name: Video\n    base_path: "+base+`
+    variables:
+      - name: client
+        default: Nobody
+        persist: true
+    directories:
+      - name: Edit
+        files:
+          - name: "{name}_brief.txt"
+            content: "{name} for {client}"
+          - name: readme.txt
+            content: "Read me"
+`), 0644)
+	setConfigPath(t, cfgPath)
+	setCloneWithFiles(t, true)
+	setCloneFlags(t, true, []string{"client=Acme"})
+
+	source := filepath.Join(base, "Client A")
+	files := map[string]string{
+		"Edit/Client A_brief.txt":      "Client A for Initech",
+		"Edit/readme.txt":              "Edited by hand",
+		"Edit/Client A_Edit_v1.prproj": "<project>Client A</project>",
+		"Footage/clip.bin":             "Client A\x00",
+	}
+	for rel, content := range files {
+		_ = os.MkdirAll(filepath.Dir(filepath.Join(source, rel)), 0755)
+		_ = os.WriteFile(filepath.Join(source, rel), []byte(content), 0644)
+	}
+	writeTestIndex(t, dir, []index.Entry{{
+		Name: "Client A", TemplateID: "video", Path: source, CreatedAt: time.Now(),
+		Status:    "done",
+		Notes:     []string{"Delivered"},
+		Metadata:  map[string]string{"client": "Initech"},
+		Variables: map[string]string{"name": "Client A", "client": "Initech"},
+		FileHashes: map[string]string{
+			"Edit/Client A_brief.txt": project.HashContent([]byte("Client A for Initech")),
+			"Edit/readme.txt":         project.HashContent([]byte("Read me")),
+		},
+	}})
+
+	if err := runClone(&cobra.Command{}, []string{"Client A", "Client B"}); err != nil {
+		t.Fatalf("runClone() error: %v", err)
+	}
+
+	clone := filepath.Join(base, "Client B")
+	want := map[string]string{
+		"Edit/Client B_brief.txt":      "Client B for Acme",
+		"Edit/readme.txt":              "Edited by hand",
+		"Edit/Client B_Edit_v1.prproj": "<project>Client B</project>",
+		"Footage/clip.bin":             "Client A\x00",
+	}
+	for rel, content := range want {
+		data, err := os.ReadFile(filepath.Join(clone, rel))
+		if err != nil {
+			t.Errorf("%s: %v", rel, err)
+		} else if string(data) != content {
+			t.Errorf("%s = %q, want %q", rel, data, content)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(clone, "Edit", "Client A_brief.txt")); !os.IsNotExist(err) {
+		t.Error("the old name should not remain in file names")
+	}
+
+	e, ok := findEntry(filepath.Join(dir, "projects.json"), clone)
+	if !ok {
+		t.Fatal("clone not indexed")
+	}
+	if e.ClonedFrom != source || len(e.Notes) != 0 || e.Status == "done" {
+		t.Errorf("entry = %+v, want cloned_from set, no notes and a fresh status", e)
+	}
+	if e.Metadata["client"] != "Acme" || e.Variables["client"] != "Acme" || e.Variables["name"] != "Client B" {
+		t.Errorf("metadata = %v, variables = %v", e.Metadata, e.Variables)
+	}
+	if e.FileHashes["Edit/Client B_brief.txt"] != project.HashContent([]byte("Client B for Acme")) {
+		t.Errorf("file hashes = %v", e.FileHashes)
+	}
+}
+
+func TestRunCloneUnknownVar(t *testing.T) {
+	base := t.TempDir()
+	cfgPath := writeTestConfig(t, base)
+	setConfigPath(t, cfgPath)
+	setCloneFlags(t, false, []string{"nope=1"})
+
+	sourceDir := filepath.Join(base, "Original")
+	_ = os.MkdirAll(sourceDir, 0755)
+	writeTestIndex(t, filepath.Dir(cfgPath), []index.Entry{
+		{Name: "Original", TemplateID: "test", Path: sourceDir, CreatedAt: time.Now()},
+	})
+
+	if err := runClone(&cobra.Command{}, []string{"Original", "Copy"}); err == nil {
+		t.Fatal("expected error for an unknown variable")
+	}
+	if _, err := os.Stat(filepath.Join(base, "Copy")); !os.IsNotExist(err) {
+		t.Error("nothing should be cloned")
+	}
+}
+
+func setCloneLink(t *testing.T, link string, exclude []string) {
+	t.Helper()
+	oldLink, oldExclude, oldQuiet := cloneLink, cloneExclude, cloneQuiet
+	cloneLink, cloneExclude, cloneQuiet = link, exclude, true
+	t.Cleanup(func() { cloneLink, cloneExclude, cloneQuiet = oldLink, oldExclude, oldQuiet })
+}
+
+// cloneLinkSetup creates an indexed project with an executable, a symlink,
+// a cache directory and a temporary file.
+func cloneLinkSetup(t *testing.T) (base, source string, mtime time.Time) {
+	t.Helper()
+	base = t.TempDir()
+	cfgPath := writeTestConfig(t, base)
+	setConfigPath(t, cfgPath)
+
+	source = filepath.Join(base, "Original")
+	_ = os.MkdirAll(filepath.Join(source, "src"), 0755)
+	_ = os.MkdirAll(filepath.Join(source, "Cache"), 0755)
+	_ = os.WriteFile(filepath.Join(source, "src", "run.sh"), []byte("#!/bin/sh"), 0750)
+	_ = os.Chmod(filepath.Join(source, "src", "run.sh"), 0750)
+	_ = os.WriteFile(filepath.Join(source, "src", "scratch.tmp"), []byte("x"), 0644)
+	_ = os.WriteFile(filepath.Join(source, "Cache", "peaks.bin"), []byte("x"), 0644)
+	if err := os.Symlink("src/run.sh", filepath.Join(source, "run")); err != nil {
+		t.Skipf("symlinks not available: %v", err)
+	}
+	mtime = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	_ = os.Chtimes(filepath.Join(source, "src", "run.sh"), mtime, mtime)
+	_ = os.Chtimes(filepath.Join(source, "src"), mtime, mtime)
+	writeTestIndex(t, filepath.Dir(cfgPath), []index.Entry{
+		{Name: "Original", TemplateID: "test", Path: source, CreatedAt: time.Now()},
+	})
+	return base, source, mtime
+}
+
+func TestRunCloneCopyPreservesMetadata(t *testing.T) {
+	base, _, mtime := cloneLinkSetup(t)
+	setCloneWithFiles(t, true)
+	setCloneLink(t, "copy", []string{"Cache/", "*.tmp"})
+
+	if err := runClone(&cobra.Command{}, []string{"Original", "Copy"}); err != nil {
+		t.Fatalf("runClone() error: %v", err)
+	}
+	clone := filepath.Join(base, "Copy")
+	info, err := os.Stat(filepath.Join(clone, "src", "run.sh"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0750 || !info.ModTime().Equal(mtime) {
+		t.Errorf("run.sh mode = %v, mtime = %v; want 0750 and %v", info.Mode().Perm(), info.ModTime(), mtime)
+	}
+	if info, err := os.Stat(filepath.Join(clone, "src")); err != nil || !info.ModTime().Equal(mtime) {
+		t.Errorf("src mtime not preserved: %v", err)
+	}
+	if target, _ := os.Readlink(filepath.Join(clone, "run")); target != "src/run.sh" {
+		t.Errorf("symlink target = %q, want src/run.sh", target)
+	}
+	for _, rel := range []string{"Cache", "src/scratch.tmp"} {
+		if _, err := os.Lstat(filepath.Join(clone, rel)); !os.IsNotExist(err) {
+			t.Errorf("%s should be excluded", rel)
+		}
+	}
+}
+
+func TestRunCloneHardLinks(t *testing.T) {
+	base, source, _ := cloneLinkSetup(t)
+	setCloneLink(t, "hard", nil)
+
+	// --link implies --with-files
+	if err := runClone(&cobra.Command{}, []string{"Original", "Linked"}); err != nil {
+		t.Fatalf("runClone() error: %v", err)
+	}
+	a, _ := os.Stat(filepath.Join(source, "Cache", "peaks.bin"))
+	b, err := os.Stat(filepath.Join(base, "Linked", "Cache", "peaks.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !os.SameFile(a, b) {
+		t.Error("files should be hard links to the source")
+	}
+}
+
+func TestRunCloneInvalidLink(t *testing.T) {
+	_, _, _ = cloneLinkSetup(t)
+	setCloneLink(t, "symbolic", nil)
+
+	if err := runClone(&cobra.Command{}, []string{"Original", "Copy"}); err == nil {
+		t.Fatal("expected error for an unknown --link method")
+	}
+}
@@ -5,8 +5,13 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
+	"time"
 
+	"github.com/fwartner/prjct/internal/config"
+	"github.com/fwartner/prjct/internal/index"
 	"github.com/fwartner/prjct/internal/project"
+	"github.com/fwartner/prjct/internal/textdiff"
 	"github.com/spf13/cobra"
 )
 
@@ -14,11 +19,61 @@ var diffCmd = &cobra.Command{
 	Use:   "diff <template-id> <project-path>",
 	Short: "Compare a project against its template",
 	Long: `Shows the differences between a template's expected directory structure
-and the actual directories in an existing project.`,
+and the actual directories in an existing project, and the state of each
+template file:
+
+  missing    the file does not exist
+  unchanged  the file matches the template
+  modified   the file was edited since it was generated
+  outdated   the template changed since the file was generated and the
+             file was not edited; "prjct sync --update-unmodified" updates it
+  conflict   both the file and the template changed
+
+Outdated and conflicting files, and files of projects created before
+generated content was recorded, are followed by a unified diff from the
+file on disk to the current template content. Unchanged files are listed
+with --verbose.
+
+If the project is in the index, directory names are resolved with the
+variables it was created with, and conditional or optional directories it
+was created without are not reported as missing. Other directories are
+compared using the template defaults and the directory name.`,
 	Args: cobra.ExactArgs(2),
 	RunE: runDiff,
 }
 
+// diffView is the machine-readable result of the diff command.
+type diffView struct {
+	TemplateID    string         `json:"template_id" yaml:"template_id"`
+	Path          string         `json:"path" yaml:"path"`
+	TemplateDirs  int            `json:"template_dirs" yaml:"template_dirs"`
+	ProjectDirs   int            `json:"project_dirs" yaml:"project_dirs"`
+	MatchingCount int            `json:"matching_count" yaml:"matching_count"`
+	MissingCount  int            `json:"missing_count" yaml:"missing_count"`
+	ExtraCount    int            `json:"extra_count" yaml:"extra_count"`
+	Missing       []string       `json:"missing" yaml:"missing"`
+	Extra         []string       `json:"extra" yaml:"extra"`
+	Matching      []string       `json:"matching" yaml:"matching"`
+	Files         []fileDiffView `json:"files" yaml:"files"`
+}
+
+// Template file states reported by diff.
+const (
+	fileMissing   = "missing"
+	fileUnchanged = "unchanged"
+	fileModified  = "modified"
+	fileOutdated  = "outdated"
+	fileConflict  = "conflict"
+)
+
+// fileDiffView is the state of one template file in the project. Diff
+// turns the file on disk into the current template content.
+type fileDiffView struct {
+	Path  string `json:"path" yaml:"path"`
+	State string `json:"state" yaml:"state"`
+	Diff  string `json:"diff,omitempty" yaml:"diff,omitempty"`
+}
+
 func runDiff(cmd *cobra.Command, args []string) error {
 	cfg, err := loadConfig()
 	if err != nil {
@@ -35,62 +90,146 @@ func runDiff(cmd *cobra.Command, args []string) error {
 		return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("project path not found: %s", projectPath)}
 	}
 
-	// Get template dirs
-	templateDirs := make(map[string]bool)
-	for _, p := range project.Flatten(tmpl.Directories, "") {
-		templateDirs[filepath.ToSlash(p)] = true
+	entry := diffEntry(tmpl, projectPath)
+	d := compareDirs(tmpl, entry)
+	files := compareFiles(tmpl, entry)
+
+	if isStructuredOutput() {
+		return writeOutput("diff", diffView{
+			TemplateID:    tmpl.ID,
+			Path:          projectPath,
+			TemplateDirs:  d.templateDirs,
+			ProjectDirs:   d.projectDirs,
+			Missing:       nonNil(d.missing),
+			Extra:         nonNil(d.extra),
+			Matching:      nonNil(d.matching),
+			MissingCount:  len(d.missing),
+			ExtraCount:    len(d.extra),
+			MatchingCount: len(d.matching),
+			Files:         files,
+		})
+	}
+
+	for _, p := range d.missing {
+		fmt.Printf("  [MISSING] %s\n", p)
+	}
+	for _, p := range d.extra {
+		fmt.Printf("  [EXTRA]   %s\n", p)
 	}
 
-	// Get actual dirs
-	actualDirs := make(map[string]bool)
-	err = filepath.WalkDir(projectPath, func(path string, d os.DirEntry, err error) error {
-		if err != nil {
-			return nil // skip errors
+	counts := make(map[string]int)
+	heading := false
+	for _, f := range files {
+		counts[f.State]++
+		if f.State == fileUnchanged && !verbose {
+			continue
 		}
-		if !d.IsDir() {
-			return nil
+		if !heading {
+			fmt.Println("\nFiles:")
+			heading = true
 		}
-		rel, relErr := filepath.Rel(projectPath, path)
-		if relErr != nil || rel == "." {
-			return nil
+		fmt.Printf("  %-11s %s\n", "["+strings.ToUpper(f.State)+"]", f.Path)
+		if f.Diff != "" {
+			fmt.Print(f.Diff)
 		}
-		actualDirs[filepath.ToSlash(rel)] = true
-		return nil
-	})
-	if err != nil {
-		return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("walking project: %v", err)}
 	}
 
-	// Compare
-	var missing, extra, matching []string
+	fmt.Printf("\nTemplate: %d dirs | Project: %d dirs | Matching: %d | Missing: %d | Extra: %d\n",
+		d.templateDirs, d.projectDirs, len(d.matching), len(d.missing), len(d.extra))
+	if len(files) > 0 {
+		fmt.Printf("Files: %d | Unchanged: %d | Missing: %d | Modified: %d | Outdated: %d | Conflict: %d\n",
+			len(files), counts[fileUnchanged], counts[fileMissing], counts[fileModified], counts[fileOutdated], counts[fileConflict])
+	}
+
+	return nil
+}
+
+// dirDiff is the result of comparing a project's directories with the
+// layout of its template. Paths are slash-separated and sorted.
+type dirDiff struct {
+	templateDirs int
+	projectDirs  int
+	missing      []string
+	extra        []string
+	matching     []string
+}
+
+// compareDirs compares the directories of entry's project with the layout
+// tmpl produces for it.
+func compareDirs(tmpl *config.Template, entry index.Entry) dirDiff {
+	layout, _ := templateLayout(tmpl, entry)
+	templateDirs := make(map[string]bool, len(layout))
+	for _, p := range layout {
+		templateDirs[p] = true
+	}
+	actualDirs := projectDirs(entry.Path)
 
+	d := dirDiff{templateDirs: len(templateDirs), projectDirs: len(actualDirs)}
 	for p := range templateDirs {
 		if actualDirs[p] {
-			matching = append(matching, p)
+			d.matching = append(d.matching, p)
 		} else {
-			missing = append(missing, p)
+			d.missing = append(d.missing, p)
 		}
 	}
-
 	for p := range actualDirs {
 		if !templateDirs[p] {
-			extra = append(extra, p)
+			d.extra = append(d.extra, p)
 		}
 	}
+	sort.Strings(d.missing)
+	sort.Strings(d.extra)
+	sort.Strings(d.matching)
+	return d
+}
 
-	sort.Strings(missing)
-	sort.Strings(extra)
-	sort.Strings(matching)
+// compareFiles reports the state of each file tmpl generates for entry. The
+// content hashes recorded at generation tell local edits from template
+// changes; without one, a file that differs is reported as modified.
+func compareFiles(tmpl *config.Template, entry index.Entry) []fileDiffView {
+	_, layout := templateLayout(tmpl, entry)
+	files := []fileDiffView{}
+	for _, f := range layout {
+		v := fileDiffView{Path: f.Path}
+		current, err := os.ReadFile(filepath.Join(entry.Path, filepath.FromSlash(f.Path)))
+		generated, known := entry.FileHashes[f.Path]
+		switch {
+		case err != nil:
+			v.State = fileMissing
+		case string(current) == f.Content:
+			v.State = fileUnchanged
+		case !known:
+			v.State = fileModified
+		case project.HashContent(current) == generated:
+			v.State = fileOutdated
+		case project.HashContent([]byte(f.Content)) == generated:
+			v.State = fileModified
+		default:
+			v.State = fileConflict
+		}
+		if v.State == fileOutdated || v.State == fileConflict || (v.State == fileModified && !known) {
+			v.Diff = textdiff.Unified("project/"+f.Path, "template/"+f.Path, string(current), f.Content, 3)
+		}
+		files = append(files, v)
+	}
+	return files
+}
 
-	for _, p := range missing {
-		fmt.Printf("  [MISSING] %s\n", p)
+// diffEntry returns the index entry for projectPath, or a stand-in named after
+// the directory if it is not indexed.
+func diffEntry(tmpl *config.Template, projectPath string) index.Entry {
+	abs, err := filepath.Abs(projectPath)
+	if err != nil {
+		abs = projectPath
 	}
-	for _, p := range extra {
-		fmt.Printf("  [EXTRA]   %s\n", p)
+	if idxPath, err := resolveIndexPath(); err == nil {
+		if entry, ok := findEntry(idxPath, abs); ok {
+			return entry
+		}
 	}
-
-	fmt.Printf("\nTemplate: %d dirs | Project: %d dirs | Matching: %d | Missing: %d | Extra: %d\n",
-		len(templateDirs), len(actualDirs), len(matching), len(missing), len(extra))
-
-	return nil
+	entry := index.Entry{Name: filepath.Base(abs), TemplateID: tmpl.ID, Path: abs, CreatedAt: time.Now()}
+	if info, err := os.Stat(abs); err == nil {
+		entry.CreatedAt = info.ModTime()
+	}
+	return entry
 }
@@ -1,11 +1,15 @@
 package cmd
 
 import (
+	"encoding/json"
 	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/fwartner/prjct/internal/index"
+	"github.com/fwartner/prjct/internal/project"
 	"github.com/spf13/cobra"
 )
 
@@ -119,3 +123,90 @@ func TestRunDiffProjectNotFound(t *testing.T) {
 		t.Errorf("Code = %d, want %d", exitErr.Code, ExitGeneral)
 	}
 }
+
+func TestRunDiffFiles(t *testing.T) {
+	dir := t.TempDir()
+	content := `templates:
+  - id: docs
+    name: "Docs"
+    base_path: "/tmp"
+    directories:
+      - name: "docs"
+        files:
+          - name: "README.md"
+            content: "Hello again {name}\n"
+          - name: "notes.txt"
+            content: "notes\n"
+          - name: "brief.md"
+            content: "brief v2\n"
+          - name: "todo.md"
+            content: "todo\n"
+          - name: "keep.txt"
+            content: "same\n"
+`
+	cfgPath := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(cfgPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	setConfigPath(t, cfgPath)
+
+	projectDir := filepath.Join(dir, "proj")
+	files := map[string]string{
+		"README.md": "Hello proj\n",
+		"notes.txt": "my notes\n",
+		"brief.md":  "brief edited\n",
+		"keep.txt":  "same\n",
+	}
+	if err := os.MkdirAll(filepath.Join(projectDir, "docs"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, c := range files {
+		if err := os.WriteFile(filepath.Join(projectDir, "docs", name), []byte(c), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeTestIndex(t, dir, []index.Entry{{
+		Name: "proj", TemplateID: "docs", Path: projectDir,
+		FileHashes: map[string]string{
+			"docs/README.md": project.HashContent([]byte("Hello proj\n")),
+			"docs/notes.txt": project.HashContent([]byte("notes\n")),
+			"docs/brief.md":  project.HashContent([]byte("brief v1\n")),
+			"docs/keep.txt":  project.HashContent([]byte("same\n")),
+		},
+	}})
+	buf := setOutputFormat(t, OutputJSON)
+
+	if err := runDiff(&cobra.Command{}, []string{"docs", projectDir}); err != nil {
+		t.Fatalf("runDiff() error: %v", err)
+	}
+	var env struct {
+		Data diffView `json:"data"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &env); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, buf.String())
+	}
+	want := map[string]string{
+		"docs/README.md": fileOutdated,
+		"docs/notes.txt": fileModified,
+		"docs/brief.md":  fileConflict,
+		"docs/todo.md":   fileMissing,
+		"docs/keep.txt":  fileUnchanged,
+	}
+	if len(env.Data.Files) != len(want) {
+		t.Fatalf("files = %+v, want %d", env.Data.Files, len(want))
+	}
+	for _, f := range env.Data.Files {
+		if f.State != want[f.Path] {
+			t.Errorf("%s: state = %s, want %s", f.Path, f.State, want[f.Path])
+		}
+		hasDiff := f.State == fileOutdated || f.State == fileConflict
+		if (f.Diff != "") != hasDiff {
+			t.Errorf("%s: diff = %q", f.Path, f.Diff)
+		}
+	}
+	for _, f := range env.Data.Files {
+		if f.Path == "docs/README.md" && !strings.Contains(f.Diff, "-Hello proj\n+Hello again proj\n") {
+			t.Errorf("README diff = %q", f.Diff)
+		}
+	}
+}
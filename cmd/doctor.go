@@ -15,6 +15,52 @@ var doctorCmd = &cobra.Command{
 	RunE:  runDoctor,
 }
 
+// doctorView is the machine-readable result of the doctor command.
+type doctorView struct {
+	ConfigPath string            `json:"config_path" yaml:"config_path"`
+	Checks     []doctorCheckView `json:"checks" yaml:"checks"`
+	Passed     int               `json:"passed" yaml:"passed"`
+	Warnings   int               `json:"warnings" yaml:"warnings"`
+	Errors     int               `json:"errors" yaml:"errors"`
+}
+
+// doctorCheckView is a single check performed by doctor.
+type doctorCheckView struct {
+	Status  string `json:"status" yaml:"status"`
+	Message string `json:"message" yaml:"message"`
+}
+
+// check records a check result and prints it in table mode.
+func (r *doctorView) check(status, message string) {
+	r.Checks = append(r.Checks, doctorCheckView{Status: status, Message: message})
+	switch status {
+	case "OK":
+		r.Passed++
+	case "WARN":
+		r.Warnings++
+	case "FAIL":
+		r.Errors++
+	}
+	if !isStructuredOutput() {
+		printCheck(status, message)
+	}
+}
+
+// finish prints the summary line, or emits the report in structured mode.
+func (r *doctorView) finish(exitErr *ExitError) error {
+	if isStructuredOutput() {
+		if exitErr != nil {
+			return writeOutputError("doctor", r, exitErr)
+		}
+		return writeOutput("doctor", r)
+	}
+	fmt.Printf("\nResult: %d passed, %d warnings, %d errors\n", r.Passed, r.Warnings, r.Errors)
+	if exitErr != nil {
+		return exitErr
+	}
+	return nil
+}
+
 func runDoctor(cmd *cobra.Command, args []string) error {
 	path := configPath
 	if path == "" {
@@ -25,91 +71,74 @@ func runDoctor(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	fmt.Printf("Checking config: %s\n\n", path)
+	if !isStructuredOutput() {
+		fmt.Printf("Checking config: %s\n\n", path)
+	}
 
-	passed := 0
-	warnings := 0
-	failures := 0
+	report := &doctorView{ConfigPath: path, Checks: []doctorCheckView{}}
 
 	// Check 1: File exists
 	if _, err := os.Stat(path); os.IsNotExist(err) {
-		printCheck("FAIL", "Config file exists")
-		failures++
-		fmt.Printf("\nResult: %d passed, %d warnings, %d errors\n", passed, warnings, failures)
-		return &ExitError{
+		report.check("FAIL", "Config file exists")
+		return report.finish(&ExitError{
 			Code:    ExitConfigNotFound,
 			Message: fmt.Sprintf("config file not found: %s\nRun 'prjct install' to create a default config.", path),
-		}
+		})
 	}
-	printCheck("OK", "Config file exists")
-	passed++
+	report.check("OK", "Config file exists")
 
 	// Check 2: YAML parses
 	cfg, err := config.Load(path)
 	if err != nil {
-		printCheck("FAIL", fmt.Sprintf("YAML syntax valid (%v)", err))
-		failures++
-		fmt.Printf("\nResult: %d passed, %d warnings, %d errors\n", passed, warnings, failures)
-		return &ExitError{
+		report.check("FAIL", fmt.Sprintf("YAML syntax valid (%v)", err))
+		return report.finish(&ExitError{
 			Code:    ExitConfigInvalid,
 			Message: fmt.Sprintf("config parse error: %v", err),
-		}
+		})
 	}
-	printCheck("OK", "YAML syntax valid")
-	passed++
+	report.check("OK", "YAML syntax valid")
 
 	// Check 3: Templates found
 	if len(cfg.Templates) == 0 {
-		printCheck("FAIL", "Templates found")
-		failures++
+		report.check("FAIL", "Templates found")
 	} else {
-		printCheck("OK", fmt.Sprintf("%d templates found", len(cfg.Templates)))
-		passed++
+		report.check("OK", fmt.Sprintf("%d templates found", len(cfg.Templates)))
 	}
 
 	// Check 4: Validation
 	errs := cfg.Validate()
 	if len(errs) > 0 {
 		for _, e := range errs {
-			printCheck("FAIL", e.Error())
-			failures++
+			report.check("FAIL", e.Error())
 		}
 	} else {
-		printCheck("OK", "All template IDs unique")
-		passed++
-		printCheck("OK", "No reserved ID conflicts")
-		passed++
-		printCheck("OK", "Directory trees valid")
-		passed++
+		report.check("OK", "All template IDs unique")
+		report.check("OK", "No reserved ID conflicts")
+		report.check("OK", "Directory trees valid")
 	}
 
 	// Check 5: Base paths exist
 	for _, t := range cfg.Templates {
 		expanded, err := config.ExpandPath(t.BasePath)
 		if err != nil {
-			printCheck("WARN", fmt.Sprintf("Cannot expand base path for %q: %v", t.ID, err))
-			warnings++
+			report.check("WARN", fmt.Sprintf("Cannot expand base path for %q: %v", t.ID, err))
 			continue
 		}
 		if _, err := os.Stat(expanded); os.IsNotExist(err) {
-			printCheck("WARN", fmt.Sprintf("Base path does not exist: %s (template: %s)", expanded, t.ID))
-			warnings++
+			report.check("WARN", fmt.Sprintf("Base path does not exist: %s (template: %s)", expanded, t.ID))
 		} else {
-			printCheck("OK", fmt.Sprintf("Base path exists: %s (template: %s)", expanded, t.ID))
-			passed++
+			report.check("OK", fmt.Sprintf("Base path exists: %s (template: %s)", expanded, t.ID))
 		}
 	}
 
-	fmt.Printf("\nResult: %d passed, %d warnings, %d errors\n", passed, warnings, failures)
-
-	if failures > 0 {
-		return &ExitError{
+	if report.Errors > 0 {
+		return report.finish(&ExitError{
 			Code:    ExitConfigInvalid,
-			Message: fmt.Sprintf("config has %d errors", failures),
-		}
+			Message: fmt.Sprintf("config has %d errors", report.Errors),
+		})
 	}
 
-	return nil
+	return report.finish(nil)
 }
 
 func printCheck(status, message string) {
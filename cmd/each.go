@@ -0,0 +1,318 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/fwartner/prjct/internal/index"
+	tmplpkg "github.com/fwartner/prjct/internal/template"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var (
+	eachAll  bool
+	eachJobs int
+)
+
+var eachCmd = &cobra.Command{
+	Use:   "each [query] <command> [args...]",
+	Short: "Run a command on every matching project",
+	Long: `Runs sync, clean, tidy, archive, note or rename on every project
+matching the query, or on all indexed projects with --all:
+
+  prjct each client-a sync --update-unmodified
+  prjct each --all clean
+  prjct each --jobs 4 2025 archive --delete
+  prjct each client-a note "Sent final invoice"
+  prjct each client-a rename "2026_{name}"
+
+Flags of the command apply to every project. Placeholders like {name},
+{date} or a template variable in its arguments and flag values are
+resolved per project; flag values with placeholders make the command run
+one project at a time. archive --file needs a placeholder when more than
+one project matches. Flags of each must come before the query.
+
+With --dry-run the commands run one after the other and their plans are
+printed under a heading per project. Otherwise sync, archive and rename,
+the commands audited for it, process up to --jobs projects at the same
+time; the others always run one project at a time. A failing project does
+not stop the others; a summary lists the result for every project, and
+the command exits non-zero if any failed. All journal records of a run
+share one operation ID, see "prjct log --group".`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runEach,
+}
+
+// eachCommands are the commands each can run, by name.
+var eachCommands = map[string]*cobra.Command{
+	"sync":    syncCmd,
+	"clean":   cleanCmd,
+	"tidy":    tidyCmd,
+	"archive": archiveCmd,
+	"note":    noteCmd,
+	"rename":  renameCmd,
+}
+
+// eachConcurrent lists the commands audited for running on several
+// projects at the same time. They share the parsed flag variables, which
+// they only read, and update the index and journal under their locks.
+// --jobs does not apply to the others.
+var eachConcurrent = map[*cobra.Command]bool{
+	syncCmd:    true,
+	archiveCmd: true,
+	renameCmd:  true,
+}
+
+// eachResultView is the outcome of the command for one project.
+type eachResultView struct {
+	Name  string `json:"name" yaml:"name"`
+	Path  string `json:"path" yaml:"path"`
+	OK    bool   `json:"ok" yaml:"ok"`
+	Error string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// eachView is the machine-readable result of the each command.
+type eachView struct {
+	Command   string           `json:"command" yaml:"command"`
+	Group     string           `json:"group" yaml:"group"`
+	DryRun    bool             `json:"dry_run" yaml:"dry_run"`
+	Succeeded int              `json:"succeeded" yaml:"succeeded"`
+	Failed    int              `json:"failed" yaml:"failed"`
+	Projects  []eachResultView `json:"projects" yaml:"projects"`
+}
+
+func init() {
+	eachCmd.Flags().BoolVar(&eachAll, "all", false, "run on all indexed projects")
+	eachCmd.Flags().IntVarP(&eachJobs, "jobs", "j", 1, "number of projects to process at the same time")
+	// Everything after the query belongs to the command being run
+	eachCmd.Flags().SetInterspersed(false)
+}
+
+func runEach(cmd *cobra.Command, args []string) error {
+	query := ""
+	if !eachAll {
+		if len(args) < 2 {
+			return &ExitError{Code: ExitGeneral, Message: "give a query and a command, or --all and a command"}
+		}
+		query, args = args[0], args[1:]
+	}
+	name, cmdArgs := args[0], args[1:]
+	sub, ok := eachCommands[name]
+	if !ok {
+		return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("cannot run %q on each project; supported: %s", name, strings.Join(eachCommandNames(), ", "))}
+	}
+	if eachJobs < 1 {
+		return &ExitError{Code: ExitGeneral, Message: "--jobs must be at least 1"}
+	}
+	if err := sub.ParseFlags(cmdArgs); err != nil {
+		return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("%s: %v", name, err)}
+	}
+	positional := sub.Flags().Args()
+	// Placeholders in flag values are resolved by parsing the flags again
+	// for every project.
+	perProjectFlags := countPlaceholders(cmdArgs) > countPlaceholders(positional)
+	if err := sub.ValidateArgs(append([]string{"query"}, positional...)); err != nil {
+		return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("%s: %v", name, err)}
+	}
+
+	idxPath, err := resolveIndexPath()
+	if err != nil {
+		return err
+	}
+	idx, err := index.Load(idxPath)
+	if err != nil {
+		return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("cannot load index: %v", err)}
+	}
+	entries := index.Search(idx, query)
+	if len(entries) == 0 {
+		if eachAll {
+			return &ExitError{Code: ExitGeneral, Message: "no indexed projects"}
+		}
+		return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("no project matching %q", query)}
+	}
+
+	if sub == archiveCmd && archiveOutput != "" && len(entries) > 1 && !hasPlaceholder(archiveOutput) {
+		return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("archive --file %s would be used for all %d projects; add a placeholder like {name} or leave it out", archiveOutput, len(entries))}
+	}
+
+	jobs := eachJobs
+	if dryRun || !eachConcurrent[sub] || perProjectFlags {
+		jobs = 1
+	}
+	if !perProjectFlags {
+		cmdArgs = positional
+	}
+	results := runEachCommand(sub, entries, cmdArgs, perProjectFlags, jobs)
+
+	report := eachView{Command: name, Group: invocationID, DryRun: dryRun, Projects: results}
+	for _, r := range results {
+		if r.OK {
+			report.Succeeded++
+		} else {
+			report.Failed++
+		}
+	}
+	var exitErr *ExitError
+	if report.Failed > 0 {
+		exitErr = &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("%s failed for %d of %d project(s)", name, report.Failed, len(results))}
+	}
+	if isStructuredOutput() {
+		if exitErr != nil {
+			return writeOutputError("each", report, exitErr)
+		}
+		return writeOutput("each", report)
+	}
+
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintf(w, "  NAME\tRESULT\n")
+	fmt.Fprintf(w, "  ----\t------\n")
+	for _, r := range results {
+		result := "ok"
+		if !r.OK {
+			result = "FAILED: " + r.Error
+		}
+		fmt.Fprintf(w, "  %s\t%s\n", r.Name, result)
+	}
+	w.Flush()
+
+	if dryRun {
+		fmt.Printf("\nDry run — %s would run on %d project(s)\n", name, len(results))
+	} else {
+		fmt.Printf("\n%s: %d succeeded, %d failed (operation %s)\n", name, report.Succeeded, report.Failed, invocationID)
+	}
+	if exitErr != nil {
+		return exitErr
+	}
+	return nil
+}
+
+// runEachCommand runs sub on every entry, at most jobs at a time, and
+// returns the results in the order of entries. With parseFlags, args still
+// hold the command's flags, which are parsed again for every project; this
+// writes the shared flag variables and needs a single job. The command's
+// own output is discarded for structured output. With one job, each
+// project's output is preceded by a heading.
+func runEachCommand(sub *cobra.Command, entries []index.Entry, args []string, parseFlags bool, jobs int) []eachResultView {
+	if invocationID == "" {
+		beginInvocation()
+	}
+
+	// The commands print their own table output; keep it out of the
+	// machine-readable result.
+	if isStructuredOutput() {
+		oldFormat, oldStdout := outputFormat, os.Stdout
+		if devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0); err == nil {
+			os.Stdout = devNull
+			defer devNull.Close()
+		}
+		outputFormat = OutputTable
+		defer func() { outputFormat, os.Stdout = oldFormat, oldStdout }()
+	}
+
+	// Progress displays of concurrent archives would overwrite each other
+	if jobs > 1 && sub == archiveCmd {
+		old := archiveQuiet
+		archiveQuiet = true
+		defer func() { archiveQuiet = old }()
+	}
+
+	cfg, _ := loadConfig()
+	results := make([]eachResultView, len(entries))
+	sem := make(chan struct{}, jobs)
+	var wg sync.WaitGroup
+	for i, e := range entries {
+		projectArgs := []string{e.Path}
+		vars := tmplpkg.BuiltinVars(e.Name, e.CreatedAt)
+		if cfg != nil {
+			if tmpl, err := cfg.ResolveTemplate(e.TemplateID); err == nil {
+				vars = creationVars(tmpl, e)
+			}
+		}
+		resolved := make([]string, len(args))
+		for j, a := range args {
+			resolved[j] = tmplpkg.Resolve(a, vars)
+		}
+
+		if jobs == 1 {
+			if !isStructuredOutput() {
+				fmt.Printf("==> %s (%s)\n", e.Name, e.Path)
+			}
+			if parseFlags {
+				resetFlags(sub)
+				if err := sub.ParseFlags(resolved); err != nil {
+					results[i] = eachResultView{Name: e.Name, Path: e.Path, Error: err.Error()}
+					continue
+				}
+				resolved = sub.Flags().Args()
+			}
+			results[i] = runEachOne(sub, e, append(projectArgs, resolved...))
+			continue
+		}
+		projectArgs = append(projectArgs, resolved...)
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, e index.Entry) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = runEachOne(sub, e, projectArgs)
+		}(i, e)
+	}
+	wg.Wait()
+	return results
+}
+
+// runEachOne runs sub for one project. args[0] is the project's path,
+// which selects it exactly.
+func runEachOne(sub *cobra.Command, e index.Entry, args []string) eachResultView {
+	r := eachResultView{Name: e.Name, Path: e.Path, OK: true}
+	if err := sub.RunE(sub, args); err != nil {
+		r.OK = false
+		r.Error = err.Error()
+	}
+	return r
+}
+
+// resetFlags sets the flags of cmd given on the command line back to
+// their defaults, so that parsing them again does not append to list
+// flags.
+func resetFlags(cmd *cobra.Command) {
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			_ = sv.Replace(nil)
+		} else {
+			_ = f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	})
+}
+
+// hasPlaceholder reports whether s contains a {placeholder}.
+func hasPlaceholder(s string) bool {
+	open := strings.Index(s, "{")
+	return open >= 0 && strings.Contains(s[open:], "}")
+}
+
+func countPlaceholders(args []string) int {
+	n := 0
+	for _, a := range args {
+		if hasPlaceholder(a) {
+			n++
+		}
+	}
+	return n
+}
+
+func eachCommandNames() []string {
+	names := make([]string, 0, len(eachCommands))
+	for n := range eachCommands {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
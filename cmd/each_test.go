@@ -0,0 +1,214 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fwartner/prjct/internal/index"
+	"github.com/fwartner/prjct/internal/journal"
+	"github.com/spf13/cobra"
+)
+
+// eachTestSetup indexes three empty projects of the test template.
+func eachTestSetup(t *testing.T) (dir string, paths []string) {
+	t.Helper()
+	dir = t.TempDir()
+	base := filepath.Join(dir, "projects")
+	var entries []index.Entry
+	for _, name := range []string{"alpha", "beta", "gamma"} {
+		p := filepath.Join(base, name)
+		if err := os.MkdirAll(p, 0755); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, p)
+		entries = append(entries, index.Entry{Name: name, TemplateID: "test", Path: p})
+	}
+	writeTestIndex(t, dir, entries)
+	setConfigPath(t, writeTestConfigAt(t, filepath.Join(dir, "config.yaml"), base))
+	setEachFlags(t, false, 1)
+	return dir, paths
+}
+
+func setEachFlags(t *testing.T, all bool, jobs int) {
+	t.Helper()
+	oldAll, oldJobs := eachAll, eachJobs
+	eachAll, eachJobs = all, jobs
+	t.Cleanup(func() { eachAll, eachJobs = oldAll, oldJobs })
+}
+
+func TestRunEachSyncGroupsJournal(t *testing.T) {
+	dir, paths := eachTestSetup(t)
+	setEachFlags(t, true, 2)
+	setDryRun(t, false)
+	setOutputFormat(t, OutputJSON)
+	beginInvocation()
+
+	if err := runEach(&cobra.Command{}, []string{"sync"}); err != nil {
+		t.Fatalf("runEach() error: %v", err)
+	}
+	for _, p := range paths {
+		for _, d := range []string{"src", "docs"} {
+			if _, err := os.Stat(filepath.Join(p, d)); err != nil {
+				t.Errorf("%s not synced: %v", p, err)
+			}
+		}
+	}
+
+	j, err := journal.Load(filepath.Join(dir, "journal.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(j.Records) != 3 {
+		t.Fatalf("got %d journal records, want 3", len(j.Records))
+	}
+	for _, r := range j.Records {
+		if r.Group != invocationID {
+			t.Errorf("record %d group = %q, want %q", r.ID, r.Group, invocationID)
+		}
+	}
+}
+
+func TestRunEachDryRunPlan(t *testing.T) {
+	dir, _ := eachTestSetup(t)
+	setDryRun(t, true)
+	buf := setOutputFormat(t, OutputJSON)
+
+	if err := runEach(&cobra.Command{}, []string{"projects", "note", "Checked {name}"}); err != nil {
+		t.Fatalf("runEach() error: %v", err)
+	}
+	var env struct {
+		Data eachView `json:"data"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &env); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, buf.String())
+	}
+	if !env.Data.DryRun || env.Data.Succeeded != 3 || len(env.Data.Projects) != 3 {
+		t.Errorf("report = %+v, want a dry run over 3 projects", env.Data)
+	}
+
+	idx, err := index.Load(filepath.Join(dir, "projects.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range idx.Projects {
+		if len(e.Notes) != 0 {
+			t.Errorf("dry run added notes to %s: %v", e.Name, e.Notes)
+		}
+	}
+}
+
+func TestRunEachReportsFailures(t *testing.T) {
+	dir, paths := eachTestSetup(t)
+	setDryRun(t, false)
+	buf := setOutputFormat(t, OutputJSON)
+	if err := os.RemoveAll(paths[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	err := runEach(&cobra.Command{}, []string{"projects", "rename", "{name}-2025"})
+	if err == nil {
+		t.Fatal("expected an error when a project fails")
+	}
+	var env struct {
+		Data eachView `json:"data"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &env); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, buf.String())
+	}
+	if env.Data.Succeeded != 2 || env.Data.Failed != 1 || env.Data.Projects[1].OK {
+		t.Errorf("report = %+v, want beta to fail", env.Data)
+	}
+	for _, name := range []string{"alpha-2025", "gamma-2025"} {
+		if _, err := os.Stat(filepath.Join(dir, "projects", name)); err != nil {
+			t.Errorf("%s not renamed: %v", name, err)
+		}
+	}
+}
+
+func TestRunEachRejectsUnknownCommand(t *testing.T) {
+	eachTestSetup(t)
+	if err := runEach(&cobra.Command{}, []string{"alpha", "create"}); err == nil {
+		t.Error("expected an error for an unsupported command")
+	}
+}
+
+func TestRunEachConcurrentArchiveAndRename(t *testing.T) {
+	dir, paths := eachTestSetup(t)
+	setEachFlags(t, false, 3)
+	setDryRun(t, false)
+	setArchiveFlags(t, false, "")
+	// each parses the archive flags into the package variables
+	oldFormat := archiveFormat
+	t.Cleanup(func() {
+		archiveFormat = oldFormat
+		archiveCmd.Flags().Lookup("format").Changed = false
+	})
+	for _, p := range paths {
+		if err := os.WriteFile(filepath.Join(p, "notes.txt"), []byte(p), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	buf := setOutputFormat(t, OutputJSON)
+	if err := runEach(&cobra.Command{}, []string{"projects", "archive", "--format", "zip"}); err != nil {
+		t.Fatalf("runEach(archive) error: %v\n%s", err, buf.String())
+	}
+	for _, p := range paths {
+		if _, err := os.Stat(p + ".zip"); err != nil {
+			t.Errorf("%s not archived: %v", p, err)
+		}
+	}
+
+	if err := runEach(&cobra.Command{}, []string{"projects", "rename", "{name}-2025"}); err != nil {
+		t.Fatalf("runEach(rename) error: %v", err)
+	}
+	idx, err := index.Load(filepath.Join(dir, "projects.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range idx.Projects {
+		if _, err := os.Stat(filepath.Join(e.Path, "notes.txt")); err != nil || !strings.HasSuffix(e.Name, "-2025") {
+			t.Errorf("project %s at %s not renamed: %v", e.Name, e.Path, err)
+		}
+	}
+
+	j, err := journal.Load(filepath.Join(dir, "journal.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// An archive, a status change and a rename per project
+	if len(j.Records) != 9 {
+		t.Errorf("got %d journal records, want 9", len(j.Records))
+	}
+}
+
+func TestRunEachResolvesFlagPlaceholders(t *testing.T) {
+	dir, paths := eachTestSetup(t)
+	setEachFlags(t, false, 3)
+	setDryRun(t, false)
+	setArchiveFlags(t, false, "")
+	setOutputFormat(t, OutputJSON)
+	t.Cleanup(func() { archiveCmd.Flags().Lookup("file").Changed = false })
+
+	out := filepath.Join(dir, "out.tar.gz")
+	err := runEach(&cobra.Command{}, []string{"projects", "archive", "--file", out})
+	if err == nil || !strings.Contains(err.Error(), "placeholder") {
+		t.Fatalf("runEach(archive --file %s) = %v, want refusal", out, err)
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Error("archive written despite the fixed --file")
+	}
+
+	pattern := filepath.Join(dir, "{name}-final.tar.gz")
+	if err := runEach(&cobra.Command{}, []string{"projects", "archive", "--file", pattern}); err != nil {
+		t.Fatalf("runEach(archive --file %s) error: %v", pattern, err)
+	}
+	for _, p := range paths {
+		if _, err := os.Stat(filepath.Join(dir, filepath.Base(p)+"-final.tar.gz")); err != nil {
+			t.Errorf("%s not archived to its own file: %v", p, err)
+		}
+	}
+}
@@ -20,7 +20,7 @@ var exportCmd = &cobra.Command{
 }
 
 func init() {
-	exportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "output file path (default: <id>.yaml)")
+	exportCmd.Flags().StringVarP(&exportOutput, "file", "f", "", "output file path (default: <id>.yaml)")
 }
 
 func runExport(cmd *cobra.Command, args []string) error {
@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// formatFlag holds the --format value shared by the listing commands.
+var formatFlag string
+
+// addFormatFlag registers --format on cmd.
+func addFormatFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&formatFlag, "format", "", "custom output as a Go template, or @name for a format defined in config")
+}
+
+// formatFuncs are the helper functions available inside --format templates.
+var formatFuncs = template.FuncMap{
+	"date":  formatDate,
+	"bytes": formatSize,
+	"age":   formatAge,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"join":  strings.Join,
+}
+
+// resolveFormat expands an @name reference using the formats section of the
+// config. Plain format strings are returned unchanged.
+func resolveFormat(format string) (string, error) {
+	if !strings.HasPrefix(format, "@") {
+		return format, nil
+	}
+	name := format[1:]
+	cfg, err := loadConfig()
+	if err != nil {
+		return "", err
+	}
+	f, ok := cfg.Format(name)
+	if !ok {
+		return "", &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("format %q not defined in config", name)}
+	}
+	return f, nil
+}
+
+// writeFormatted renders data through the --format template. Slices are
+// rendered once per element, each on its own line.
+func writeFormatted(w io.Writer, data any) error {
+	format, err := resolveFormat(formatFlag)
+	if err != nil {
+		return err
+	}
+
+	tmpl, err := template.New("format").Funcs(formatFuncs).Parse(format)
+	if err != nil {
+		return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("invalid format: %v", err)}
+	}
+
+	items := []any{data}
+	if v := reflect.ValueOf(data); v.Kind() == reflect.Slice {
+		items = make([]any, v.Len())
+		for i := range items {
+			items[i] = v.Index(i).Interface()
+		}
+	}
+
+	for _, item := range items {
+		var b strings.Builder
+		if err := tmpl.Execute(&b, item); err != nil {
+			return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("rendering format: %v", err)}
+		}
+		line := b.String()
+		if !strings.HasSuffix(line, "\n") {
+			line += "\n"
+		}
+		if _, err := io.WriteString(w, line); err != nil {
+			return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("cannot write output: %v", err), Err: err}
+		}
+	}
+	return nil
+}
+
+// formatDate formats a time with an optional layout (default 2006-01-02).
+func formatDate(t any, layout ...string) string {
+	tm, ok := asTime(t)
+	if !ok {
+		return ""
+	}
+	l := "2006-01-02"
+	if len(layout) > 0 {
+		l = layout[0]
+	}
+	return tm.Format(l)
+}
+
+// formatSize renders an integer byte count in human-readable units.
+func formatSize(n any) string {
+	v := reflect.ValueOf(n)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return formatBytes(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return formatBytes(int64(v.Uint()))
+	}
+	return ""
+}
+
+// formatAge renders the time elapsed since t, e.g. "3d ago".
+func formatAge(t any) string {
+	tm, ok := asTime(t)
+	if !ok {
+		return ""
+	}
+	return relativeAge(tm, time.Now())
+}
+
+func relativeAge(t, now time.Time) string {
+	d := now.Sub(t)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	case d < 30*24*time.Hour:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	case d < 365*24*time.Hour:
+		return fmt.Sprintf("%dmo ago", int(d.Hours()/(24*30)))
+	default:
+		return fmt.Sprintf("%dy ago", int(d.Hours()/(24*365)))
+	}
+}
+
+func asTime(v any) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, !t.IsZero()
+	case *time.Time:
+		if t == nil {
+			return time.Time{}, false
+		}
+		return *t, !t.IsZero()
+	}
+	return time.Time{}, false
+}
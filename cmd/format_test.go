@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fwartner/prjct/internal/index"
+	"github.com/spf13/cobra"
+)
+
+// setFormatFlag sets the package-level formatFlag for the duration of the test.
+func setFormatFlag(t *testing.T, val string) {
+	t.Helper()
+	old := formatFlag
+	formatFlag = val
+	t.Cleanup(func() { formatFlag = old })
+}
+
+func TestWriteFormattedSlice(t *testing.T) {
+	setFormatFlag(t, "{{.Name}} [{{.TemplateID}}] {{.Path}}")
+	views := []projectView{
+		{Name: "alpha", TemplateID: "dev", Path: "/a"},
+		{Name: "beta", TemplateID: "video", Path: "/b"},
+	}
+
+	var buf bytes.Buffer
+	if err := writeFormatted(&buf, views); err != nil {
+		t.Fatalf("writeFormatted() error: %v", err)
+	}
+	want := "alpha [dev] /a\nbeta [video] /b\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestWriteFormattedInvalidTemplate(t *testing.T) {
+	setFormatFlag(t, "{{.Name")
+
+	err := writeFormatted(&bytes.Buffer{}, projectView{})
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatal("expected ExitError for invalid template")
+	}
+}
+
+func TestRunSearchNamedFormat(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	content := `formats:
+  short: "{{.Name}}|{{upper .TemplateID}}"
+templates:
+  - id: dev
+    name: Dev
+    base_path: /tmp
+    directories:
+      - name: src
+`
+	if err := os.WriteFile(cfgPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeTestIndex(t, dir, []index.Entry{
+		{Name: "alpha", TemplateID: "dev", Path: "/a", CreatedAt: time.Now()},
+	})
+	setConfigPath(t, cfgPath)
+	setSearchTemplate(t, "")
+	setFormatFlag(t, "@short")
+	buf := setOutputFormat(t, "")
+
+	if err := runSearch(&cobra.Command{}, []string{}); err != nil {
+		t.Fatalf("runSearch() error: %v", err)
+	}
+	if buf.String() != "alpha|DEV\n" {
+		t.Errorf("got %q, want %q", buf.String(), "alpha|DEV\n")
+	}
+}
+
+func TestRunSearchUnknownNamedFormat(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := writeTestConfigAt(t, filepath.Join(dir, "config.yaml"), dir)
+	writeTestIndex(t, dir, []index.Entry{})
+	setConfigPath(t, cfgPath)
+	setSearchTemplate(t, "")
+	setFormatFlag(t, "@missing")
+
+	err := runSearch(&cobra.Command{}, []string{})
+	if err == nil {
+		t.Fatal("expected error for undefined format")
+	}
+}
+
+func TestFormatHelpers(t *testing.T) {
+	ts := time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC)
+	if got := formatDate(ts); got != "2026-03-04" {
+		t.Errorf("formatDate() = %q", got)
+	}
+	if got := formatDate(&ts, "Jan 2"); got != "Mar 4" {
+		t.Errorf("formatDate(layout) = %q", got)
+	}
+	if got := formatDate((*time.Time)(nil)); got != "" {
+		t.Errorf("formatDate(nil) = %q, want empty", got)
+	}
+	if got := formatSize(int64(2048)); got != "2.0 KB" {
+		t.Errorf("formatSize() = %q", got)
+	}
+	if got := formatSize(3); got != "3 B" {
+		t.Errorf("formatSize(int) = %q", got)
+	}
+}
+
+func TestRelativeAge(t *testing.T) {
+	now := time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		ago  time.Duration
+		want string
+	}{
+		{10 * time.Second, "just now"},
+		{5 * time.Minute, "5m ago"},
+		{3 * time.Hour, "3h ago"},
+		{4 * 24 * time.Hour, "4d ago"},
+		{60 * 24 * time.Hour, "2mo ago"},
+		{800 * 24 * time.Hour, "2y ago"},
+	}
+	for _, tt := range tests {
+		if got := relativeAge(now.Add(-tt.ago), now); got != tt.want {
+			t.Errorf("relativeAge(-%v) = %q, want %q", tt.ago, got, tt.want)
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/fwartner/prjct/internal/config"
+	"github.com/fwartner/prjct/internal/journal"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
 )
@@ -72,6 +73,7 @@ func runImport(cmd *cobra.Command, args []string) error {
 
 	added := 0
 	skipped := 0
+	var addedIDs []string
 	for _, t := range imported.Templates {
 		if existing[t.ID] {
 			fmt.Printf("  skip: %q (ID conflict)\n", t.ID)
@@ -80,6 +82,7 @@ func runImport(cmd *cobra.Command, args []string) error {
 		}
 		cfg.Templates = append(cfg.Templates, t)
 		existing[t.ID] = true
+		addedIDs = append(addedIDs, t.ID)
 		added++
 		fmt.Printf("  add:  %q (%s)\n", t.ID, t.Name)
 	}
@@ -88,6 +91,14 @@ func runImport(cmd *cobra.Command, args []string) error {
 		if err := cfg.Save(cfgPath); err != nil {
 			return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("saving config: %v", err)}
 		}
+		recordJournal(journal.Record{
+			Operation: journal.OpImport,
+			Details: map[string]string{
+				"source":    source,
+				"config":    cfgPath,
+				"templates": strings.Join(addedIDs, ","),
+			},
+		})
 	}
 
 	fmt.Printf("\nImported %d template(s), skipped %d\n", added, skipped)
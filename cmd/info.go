@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/fwartner/prjct/internal/index"
@@ -19,6 +20,22 @@ file and directory counts, age, template, and notes.`,
 	RunE: runInfo,
 }
 
+// infoView is the machine-readable result of the info command.
+type infoView struct {
+	projectView `yaml:",inline"`
+	AgeDays     int        `json:"age_days" yaml:"age_days"`
+	Accessible  bool       `json:"accessible" yaml:"accessible"`
+	AccessError string     `json:"access_error,omitempty" yaml:"access_error,omitempty"`
+	Dirs        int        `json:"dirs" yaml:"dirs"`
+	Files       int        `json:"files" yaml:"files"`
+	SizeBytes   int64      `json:"size_bytes" yaml:"size_bytes"`
+	ModifiedAt  *time.Time `json:"modified_at,omitempty" yaml:"modified_at,omitempty"`
+}
+
+func init() {
+	addFormatFlag(infoCmd)
+}
+
 func runInfo(cmd *cobra.Command, args []string) error {
 	idxPath, err := resolveIndexPath()
 	if err != nil {
@@ -36,16 +53,22 @@ func runInfo(cmd *cobra.Command, args []string) error {
 	}
 
 	entry := results[0]
+	view := buildInfo(entry)
+
+	if formatFlag != "" {
+		return writeFormatted(outputStdout, view)
+	}
+	if isStructuredOutput() {
+		return writeOutput("info", view)
+	}
 
 	fmt.Printf("Name:     %s\n", entry.Name)
 	fmt.Printf("Template: %s (%s)\n", entry.TemplateName, entry.TemplateID)
 	fmt.Printf("Path:     %s\n", entry.Path)
 	fmt.Printf("Created:  %s\n", entry.CreatedAt.Format("2006-01-02 15:04:05"))
 
-	age := time.Since(entry.CreatedAt)
-	days := int(age.Hours() / 24)
-	if days > 0 {
-		fmt.Printf("Age:      %d day(s)\n", days)
+	if view.AgeDays > 0 {
+		fmt.Printf("Age:      %d day(s)\n", view.AgeDays)
 	} else {
 		fmt.Printf("Age:      <1 day\n")
 	}
@@ -53,44 +76,26 @@ func runInfo(cmd *cobra.Command, args []string) error {
 	if entry.Status != "" {
 		fmt.Printf("Status:   %s\n", entry.Status)
 	}
+	if len(entry.Tags) > 0 {
+		fmt.Printf("Tags:     %s\n", strings.Join(entry.Tags, ", "))
+	}
 
-	// Filesystem stats
-	info, err := os.Stat(entry.Path)
-	if err != nil {
-		fmt.Printf("\n  (directory not accessible: %v)\n", err)
-	} else if info.IsDir() {
-		var totalSize int64
-		dirCount := 0
-		fileCount := 0
-		var lastMod time.Time
-
-		_ = filepath.WalkDir(entry.Path, func(path string, d os.DirEntry, walkErr error) error {
-			if walkErr != nil {
-				return nil
-			}
-			if d.IsDir() {
-				dirCount++
-			} else {
-				fileCount++
-				fi, fiErr := d.Info()
-				if fiErr == nil {
-					totalSize += fi.Size()
-					if fi.ModTime().After(lastMod) {
-						lastMod = fi.ModTime()
-					}
-				}
-			}
-			return nil
-		})
-
-		fmt.Printf("Dirs:     %d\n", dirCount)
-		fmt.Printf("Files:    %d\n", fileCount)
-		fmt.Printf("Size:     %s\n", formatBytes(totalSize))
-		if !lastMod.IsZero() {
-			fmt.Printf("Modified: %s\n", lastMod.Format("2006-01-02 15:04:05"))
+	if !view.Accessible {
+		fmt.Printf("\n  (directory not accessible: %s)\n", view.AccessError)
+	} else {
+		fmt.Printf("Dirs:     %d\n", view.Dirs)
+		fmt.Printf("Files:    %d\n", view.Files)
+		fmt.Printf("Size:     %s\n", formatBytes(view.SizeBytes))
+		if view.ModifiedAt != nil {
+			fmt.Printf("Modified: %s\n", view.ModifiedAt.Format("2006-01-02 15:04:05"))
 		}
 	}
 
+	if len(entry.Metadata) > 0 {
+		fmt.Println("Metadata:")
+		printMetadata(entry.Metadata)
+	}
+
 	if len(entry.Notes) > 0 {
 		fmt.Println("Notes:")
 		for i, n := range entry.Notes {
@@ -101,6 +106,49 @@ func runInfo(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// buildInfo gathers filesystem statistics for entry.
+func buildInfo(entry index.Entry) infoView {
+	view := infoView{
+		projectView: newProjectView(entry),
+		AgeDays:     int(time.Since(entry.CreatedAt).Hours() / 24),
+	}
+
+	info, err := os.Stat(entry.Path)
+	if err != nil {
+		view.AccessError = err.Error()
+		return view
+	}
+	if !info.IsDir() {
+		view.AccessError = "not a directory"
+		return view
+	}
+	view.Accessible = true
+
+	var lastMod time.Time
+	_ = filepath.WalkDir(entry.Path, func(path string, d os.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return nil
+		}
+		if d.IsDir() {
+			view.Dirs++
+		} else {
+			view.Files++
+			fi, fiErr := d.Info()
+			if fiErr == nil {
+				view.SizeBytes += fi.Size()
+				if fi.ModTime().After(lastMod) {
+					lastMod = fi.ModTime()
+				}
+			}
+		}
+		return nil
+	})
+	if !lastMod.IsZero() {
+		view.ModifiedAt = &lastMod
+	}
+	return view
+}
+
 func formatBytes(b int64) string {
 	const (
 		kb = 1024
@@ -28,7 +28,7 @@ configuration that can be used to recreate it.`,
 func init() {
 	initCmd.Flags().StringVar(&initID, "id", "", "template ID (default: directory name)")
 	initCmd.Flags().StringVar(&initName, "name", "", "template display name (default: directory name)")
-	initCmd.Flags().StringVarP(&initOutput, "output", "o", "", "output file (default: stdout)")
+	initCmd.Flags().StringVarP(&initOutput, "file", "f", "", "output file (default: stdout)")
 }
 
 func runInit(cmd *cobra.Command, args []string) error {
@@ -20,6 +20,16 @@ var listCmd = &cobra.Command{
 
 func init() {
 	listCmd.Flags().StringVarP(&listTag, "tag", "t", "", "filter templates by tag")
+	addFormatFlag(listCmd)
+}
+
+// templateView is the stable output schema for a configured template.
+type templateView struct {
+	ID       string   `json:"id" yaml:"id"`
+	Name     string   `json:"name" yaml:"name"`
+	BasePath string   `json:"base_path" yaml:"base_path"`
+	Extends  string   `json:"extends,omitempty" yaml:"extends,omitempty"`
+	Tags     []string `json:"tags" yaml:"tags"`
 }
 
 func runList(cmd *cobra.Command, args []string) error {
@@ -33,6 +43,20 @@ func runList(cmd *cobra.Command, args []string) error {
 		tags = []string{listTag}
 	}
 
+	if formatFlag != "" || isStructuredOutput() {
+		views := []templateView{}
+		for _, t := range cfg.Templates {
+			if !t.MatchesTags(tags) {
+				continue
+			}
+			views = append(views, templateView{ID: t.ID, Name: t.Name, BasePath: t.BasePath, Extends: t.Extends, Tags: nonNil(t.Tags)})
+		}
+		if formatFlag != "" {
+			return writeFormatted(outputStdout, views)
+		}
+		return writeOutput("list", views)
+	}
+
 	fmt.Println("Available templates:")
 	fmt.Println()
 
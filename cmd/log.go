@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fwartner/prjct/internal/journal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	logLimit int
+	logGroup string
+	logSince string
+	logUntil string
+	logOp    string
+)
+
+var logCmd = &cobra.Command{
+	Use:   "log",
+	Short: "Show the operation history",
+	Long: `Lists journaled operations newest first with the IDs accepted by
+"prjct undo <id>" and "prjct redo <id>". Undone operations are marked.
+Records written by one command invocation share an operation ID (OP);
+use --group to show only those, e.g. all creates of one bulk run.
+
+--since, --until and --op also search the rotated monthly journal files.
+Times are dates (2026-03-01), RFC 3339 timestamps, or ages such as 7d or
+12h.`,
+	Args: cobra.NoArgs,
+	RunE: runLog,
+}
+
+// logRecordView is the machine-readable form of a journal record.
+type logRecordView struct {
+	ID        int               `json:"id" yaml:"id"`
+	Group     string            `json:"group,omitempty" yaml:"group,omitempty"`
+	Timestamp time.Time         `json:"timestamp" yaml:"timestamp"`
+	Operation string            `json:"operation" yaml:"operation"`
+	Details   map[string]string `json:"details" yaml:"details"`
+	Created   []string          `json:"created,omitempty" yaml:"created,omitempty"`
+	Removed   []string          `json:"removed,omitempty" yaml:"removed,omitempty"`
+	Indexed   []string          `json:"indexed,omitempty" yaml:"indexed,omitempty"`
+	UndoneAt  *time.Time        `json:"undone_at,omitempty" yaml:"undone_at,omitempty"`
+}
+
+func init() {
+	logCmd.Flags().IntVarP(&logLimit, "limit", "n", 20, "maximum number of records to show (0 for all)")
+	logCmd.Flags().StringVar(&logGroup, "group", "", "only show records of one operation ID")
+	logCmd.Flags().StringVar(&logSince, "since", "", "only show records at or after this time")
+	logCmd.Flags().StringVar(&logUntil, "until", "", "only show records at or before this time")
+	logCmd.Flags().StringVar(&logOp, "op", "", "only show records of this operation type")
+}
+
+func runLog(cmd *cobra.Command, args []string) error {
+	jPath, err := resolveJournalPath()
+	if err != nil {
+		return &ExitError{Code: ExitGeneral, Message: err.Error()}
+	}
+
+	all, err := loadLogRecords(jPath)
+	if err != nil {
+		return err
+	}
+
+	// Newest first
+	var records []journal.Record
+	for i := len(all) - 1; i >= 0; i-- {
+		if logLimit > 0 && len(records) == logLimit {
+			break
+		}
+		if logGroup != "" && all[i].Group != logGroup {
+			continue
+		}
+		records = append(records, all[i])
+	}
+
+	if isStructuredOutput() {
+		views := make([]logRecordView, 0, len(records))
+		for _, r := range records {
+			v := logRecordView{
+				ID:        r.ID,
+				Group:     r.Group,
+				Timestamp: r.Timestamp,
+				Operation: string(r.Operation),
+				Details:   r.Details,
+				Created:   r.Created,
+				Removed:   r.Removed,
+				Indexed:   r.Indexed,
+			}
+			if r.Undone != nil {
+				at := r.Undone.At
+				v.UndoneAt = &at
+			}
+			views = append(views, v)
+		}
+		return writeOutput("log", views)
+	}
+
+	if len(records) == 0 {
+		fmt.Println("No operations recorded.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintf(w, "  ID\tOP\tTIME\tOPERATION\tSTATE\tSUMMARY\n")
+	fmt.Fprintf(w, "  --\t--\t----\t---------\t-----\t-------\n")
+	for _, r := range records {
+		state := "done"
+		if r.Undone != nil {
+			state = "undone"
+		}
+		group := r.Group
+		if group == "" {
+			group = "-"
+		}
+		fmt.Fprintf(w, "  %d\t%s\t%s\t%s\t%s\t%s\n", r.ID, group, r.Timestamp.Format("2006-01-02 15:04"), r.Operation, state, recordSummary(&r))
+	}
+	w.Flush()
+	return nil
+}
+
+// loadLogRecords returns the active journal, or the matching records of the
+// active and rotated journals when a time or operation filter is set.
+func loadLogRecords(jPath string) ([]journal.Record, error) {
+	if logSince == "" && logUntil == "" && logOp == "" {
+		j, err := journal.Load(jPath)
+		if err != nil {
+			return nil, &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("cannot read journal: %v", err)}
+		}
+		return j.Records, nil
+	}
+
+	var q journal.Query
+	var err error
+	if q.Since, err = parseLogTime(logSince, false); err != nil {
+		return nil, err
+	}
+	if q.Until, err = parseLogTime(logUntil, true); err != nil {
+		return nil, err
+	}
+	q.Op = journal.OpType(logOp)
+
+	records, err := journal.Search(jPath, q)
+	if err != nil {
+		return nil, &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("cannot search journal: %v", err)}
+	}
+	return records, nil
+}
+
+// parseLogTime parses a --since/--until value: a date, an RFC 3339
+// timestamp, or an age like 7d or 12h. A date used as an upper bound
+// covers the whole day.
+func parseLogTime(s string, endOfDay bool) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		if endOfDay {
+			t = t.Add(24*time.Hour - time.Nanosecond)
+		}
+		return t, nil
+	}
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return time.Now().AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+		return time.Now().Add(-d), nil
+	}
+	return time.Time{}, &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("invalid time %q: use YYYY-MM-DD, RFC 3339, or an age like 7d", s)}
+}
+
+// recordSummary describes a record in one line for log and error output.
+func recordSummary(r *journal.Record) string {
+	d := r.Details
+	switch r.Operation {
+	case journal.OpRename:
+		return fmt.Sprintf("%s -> %s", d["old_path"], d["new_path"])
+	case journal.OpImport:
+		return fmt.Sprintf("%s from %s", d["templates"], d["source"])
+	case journal.OpStatus:
+		return fmt.Sprintf("%s: %s -> %s", d["path"], statusLabel(d["from"]), d["to"])
+	case journal.OpNote:
+		return fmt.Sprintf("%s: %q", d["path"], d["note"])
+	case journal.OpTag:
+		var parts []string
+		for _, t := range splitList(d["added"]) {
+			parts = append(parts, "+"+t)
+		}
+		for _, t := range splitList(d["removed"]) {
+			parts = append(parts, "-"+t)
+		}
+		return fmt.Sprintf("%s: %s", d["path"], strings.Join(parts, " "))
+	case journal.OpMigrate:
+		return fmt.Sprintf("%s (v%s -> v%s)", d["path"], d["from"], d["to"])
+	case journal.OpTidy:
+		return fmt.Sprintf("%s (%d action(s))", d["path"], len(migrationActions(r)))
+	case journal.OpSync:
+		dirs, files := syncedPaths(r)
+		if len(files) > 0 {
+			return fmt.Sprintf("%s (+%d dirs, %d files)", d["path"], len(dirs), len(files))
+		}
+		return fmt.Sprintf("%s (+%d dirs)", d["path"], len(r.Created))
+	case journal.OpClean:
+		return fmt.Sprintf("%s (-%d dirs)", d["path"], len(r.Removed))
+	case journal.OpClone:
+		return fmt.Sprintf("%s -> %s", d["source"], d["path"])
+	case journal.OpArchive:
+		if len(r.Removed) > 0 {
+			return fmt.Sprintf("%s -> %s (original deleted)", d["path"], d["archive"])
+		}
+		return fmt.Sprintf("%s -> %s", d["path"], d["archive"])
+	case journal.OpRestore:
+		return fmt.Sprintf("%s -> %s", d["archive"], d["path"])
+	case journal.OpReindex:
+		return fmt.Sprintf("%d project(s) indexed", len(r.Indexed))
+	}
+	return d["path"]
+}
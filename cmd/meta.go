@@ -0,0 +1,300 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fwartner/prjct/internal/config"
+	"github.com/fwartner/prjct/internal/index"
+	"github.com/fwartner/prjct/internal/journal"
+	"github.com/spf13/cobra"
+)
+
+var metaUnsetForce bool
+
+var metaCmd = &cobra.Command{
+	Use:   "meta",
+	Short: "Show or edit custom project metadata",
+	Long: `Manages custom metadata fields such as client, due date or budget.
+Fields are declared per template under "metadata" and prompted at
+creation; values of declared fields are validated against their type.`,
+}
+
+var metaGetCmd = &cobra.Command{
+	Use:   "get <query> [key]",
+	Short: "Show metadata of a project",
+	Args:  cobra.RangeArgs(1, 2),
+	RunE:  runMetaGet,
+}
+
+var metaSetCmd = &cobra.Command{
+	Use:   "set <query> <key=value>...",
+	Short: "Set metadata fields on a project",
+	Args:  cobra.MinimumNArgs(2),
+	RunE:  runMetaSet,
+}
+
+var metaUnsetCmd = &cobra.Command{
+	Use:   "unset <query> <key>...",
+	Short: "Remove metadata fields from a project",
+	Long: `Removes metadata fields from the first matching project. Fields the
+template declares as required are kept unless --force is given.`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: runMetaUnset,
+}
+
+func init() {
+	metaCmd.AddCommand(metaGetCmd)
+	metaCmd.AddCommand(metaSetCmd)
+	metaCmd.AddCommand(metaUnsetCmd)
+	metaUnsetCmd.Flags().BoolVar(&metaUnsetForce, "force", false, "also remove fields the template requires")
+}
+
+func runMetaGet(cmd *cobra.Command, args []string) error {
+	_, entry, err := findProject(args[0])
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 2 {
+		v, ok := entry.Metadata[args[1]]
+		if !ok {
+			return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("%s has no metadata field %q", entry.Name, args[1])}
+		}
+		fmt.Println(v)
+		return nil
+	}
+
+	if isStructuredOutput() {
+		meta := entry.Metadata
+		if meta == nil {
+			meta = map[string]string{}
+		}
+		return writeOutput("meta", meta)
+	}
+
+	fmt.Printf("Metadata for %s (%s):\n", entry.Name, entry.Path)
+	if len(entry.Metadata) == 0 {
+		fmt.Println("  (no metadata)")
+		return nil
+	}
+	printMetadata(entry.Metadata)
+	return nil
+}
+
+func runMetaSet(cmd *cobra.Command, args []string) error {
+	idxPath, entry, err := findProject(args[0])
+	if err != nil {
+		return err
+	}
+
+	values, err := parseKeyValues(args[1:])
+	if err != nil {
+		return err
+	}
+
+	// Validate declared fields when the project's template is known
+	if cfg, cfgErr := loadConfig(); cfgErr == nil {
+		if tmpl, tErr := cfg.ResolveTemplate(entry.TemplateID); tErr == nil {
+			for k, v := range values {
+				if f := tmpl.FindMetaField(k); f != nil {
+					if err := f.Check(v); err != nil {
+						return &ExitError{Code: ExitGeneral, Message: err.Error()}
+					}
+				}
+			}
+		}
+	}
+
+	return updateMetadata(idxPath, entry, values, nil)
+}
+
+func runMetaUnset(cmd *cobra.Command, args []string) error {
+	idxPath, entry, err := findProject(args[0])
+	if err != nil {
+		return err
+	}
+
+	// Required fields are enforced at creation; keep them that way
+	if !metaUnsetForce {
+		if cfg, cfgErr := loadConfig(); cfgErr == nil {
+			if tmpl, tErr := cfg.ResolveTemplate(entry.TemplateID); tErr == nil {
+				for _, k := range args[1:] {
+					if f := tmpl.FindMetaField(k); f != nil && f.Required {
+						return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("%q is required by template %q (use --force to remove it anyway)", k, tmpl.ID)}
+					}
+				}
+			}
+		}
+	}
+	return updateMetadata(idxPath, entry, nil, args[1:])
+}
+
+// updateMetadata applies set and unset to the entry's metadata and journals
+// the previous values.
+func updateMetadata(idxPath string, entry index.Entry, set map[string]string, unset []string) error {
+	details := map[string]string{"path": entry.Path}
+	for k, v := range set {
+		details["meta."+k] = v
+		details["old."+k] = entry.Metadata[k]
+	}
+	for _, k := range unset {
+		details["meta."+k] = ""
+		details["old."+k] = entry.Metadata[k]
+	}
+
+	if dryRun {
+		for _, k := range sortedKeys(set) {
+			fmt.Printf("  [DRY-RUN] set %s=%s\n", k, set[k])
+		}
+		for _, k := range unset {
+			fmt.Printf("  [DRY-RUN] unset %s\n", k)
+		}
+		return nil
+	}
+
+	err := index.Update(idxPath, entry.Path, func(e *index.Entry) {
+		if e.Metadata == nil {
+			e.Metadata = make(map[string]string)
+		}
+		for k, v := range set {
+			e.Metadata[k] = v
+		}
+		for _, k := range unset {
+			delete(e.Metadata, k)
+		}
+	})
+	if err != nil {
+		return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("failed to save metadata: %v", err)}
+	}
+
+	recordJournal(journal.Record{
+		Operation: journal.OpMeta,
+		Details:   details,
+	})
+
+	fmt.Printf("Metadata updated for %s\n", entry.Name)
+	return nil
+}
+
+// findProject loads the index and returns the first entry matching query.
+func findProject(query string) (string, index.Entry, error) {
+	idxPath, err := resolveIndexPath()
+	if err != nil {
+		return "", index.Entry{}, err
+	}
+
+	idx, err := index.Load(idxPath)
+	if err != nil {
+		return "", index.Entry{}, &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("cannot load index: %v", err)}
+	}
+
+	results := index.Search(idx, query)
+	if len(results) == 0 {
+		return "", index.Entry{}, &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("no project matching %q", query)}
+	}
+	return idxPath, results[0], nil
+}
+
+// parseKeyValues parses key=value arguments.
+func parseKeyValues(args []string) (map[string]string, error) {
+	values := make(map[string]string, len(args))
+	for _, a := range args {
+		k, v, ok := strings.Cut(a, "=")
+		k = strings.TrimSpace(k)
+		if !ok || k == "" {
+			return nil, &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("invalid metadata %q: expected key=value", a)}
+		}
+		values[k] = strings.TrimSpace(v)
+	}
+	return values, nil
+}
+
+// buildMetadata collects the metadata for a new project: persisted
+// variables, then declared fields from provided values, prompts (when
+// scanner is non-nil) or defaults. Undeclared provided keys are kept as-is.
+func buildMetadata(tmpl *config.Template, vars, provided map[string]string, scanner *bufio.Scanner) (map[string]string, error) {
+	meta := make(map[string]string)
+	for _, v := range tmpl.Variables {
+		if v.Persist {
+			meta[v.Name] = vars[v.Name]
+		}
+	}
+
+	for _, f := range tmpl.Metadata {
+		val, ok := provided[f.Name]
+		if !ok && scanner != nil {
+			// Re-prompt until the value is valid or input ends
+			for {
+				var more bool
+				val, more = promptValue(scanner, f.Prompt, f.Name, f.Default)
+				err := f.Check(val)
+				if err == nil {
+					break
+				}
+				if !more {
+					return nil, &ExitError{Code: ExitUserCancelled, Message: fmt.Sprintf("cancelled: %v", err)}
+				}
+				fmt.Printf("  %v\n", err)
+			}
+		} else if !ok {
+			val = f.Default
+		}
+		if err := f.Check(val); err != nil {
+			return nil, &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("invalid metadata: %v", err)}
+		}
+		if val != "" {
+			meta[f.Name] = val
+		}
+	}
+
+	for k, v := range provided {
+		if tmpl.FindMetaField(k) == nil {
+			meta[k] = v
+		}
+	}
+
+	if len(meta) == 0 {
+		return nil, nil
+	}
+	return meta, nil
+}
+
+// promptValue prints a prompt with an optional default and reads one line.
+// An empty answer yields the default. The second result is false once
+// input is exhausted.
+func promptValue(scanner *bufio.Scanner, prompt, name, def string) (string, bool) {
+	if prompt == "" {
+		prompt = name
+	}
+	if def != "" {
+		fmt.Printf("%s [%s]: ", prompt, def)
+	} else {
+		fmt.Printf("%s: ", prompt)
+	}
+	if !scanner.Scan() {
+		return def, false
+	}
+	val := strings.TrimSpace(scanner.Text())
+	if val == "" {
+		val = def
+	}
+	return val, true
+}
+
+func printMetadata(meta map[string]string) {
+	for _, k := range sortedKeys(meta) {
+		fmt.Printf("  %s: %s\n", k, meta[k])
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fwartner/prjct/internal/config"
+	"github.com/fwartner/prjct/internal/index"
+	"github.com/spf13/cobra"
+)
+
+// writeMetaTestConfig writes a config whose template declares metadata fields.
+func writeMetaTestConfig(t *testing.T, basePath string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `templates:
+  - id: test
+    name: Test
+    base_path: ` + basePath + `
+    variables:
+      - name: client
+        default: Internal
+        persist: true
+    metadata:
+      - name: due
+        type: date
+        required: true
+      - name: budget
+        type: number
+        default: "0"
+    directories:
+      - name: src
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func setCreateMeta(t *testing.T, vals []string) {
+	t.Helper()
+	old := createMeta
+	createMeta = vals
+	t.Cleanup(func() { createMeta = old })
+}
+
+func TestRunRootWithMetadata(t *testing.T) {
+	base := t.TempDir()
+	cfgPath := writeMetaTestConfig(t, base)
+	setConfigPath(t, cfgPath)
+	setCreateMeta(t, []string{"due=2026-05-01", "po=42"})
+
+	if err := runRoot(&cobra.Command{}, []string{"test", "Billing"}); err != nil {
+		t.Fatalf("runRoot() error: %v", err)
+	}
+
+	idx, _ := index.Load(filepath.Join(filepath.Dir(cfgPath), "projects.json"))
+	if len(idx.Projects) != 1 {
+		t.Fatalf("expected 1 indexed project, got %d", len(idx.Projects))
+	}
+	meta := idx.Projects[0].Metadata
+	want := map[string]string{"client": "Internal", "due": "2026-05-01", "budget": "0", "po": "42"}
+	for k, v := range want {
+		if meta[k] != v {
+			t.Errorf("metadata[%s] = %q, want %q", k, meta[k], v)
+		}
+	}
+}
+
+func TestRunRootMissingRequiredMetadata(t *testing.T) {
+	base := t.TempDir()
+	cfgPath := writeMetaTestConfig(t, base)
+	setConfigPath(t, cfgPath)
+	setCreateMeta(t, nil)
+
+	err := runRoot(&cobra.Command{}, []string{"test", "NoDue"})
+	if err == nil {
+		t.Fatal("expected error for missing required metadata")
+	}
+	if _, statErr := os.Stat(filepath.Join(base, "NoDue")); !os.IsNotExist(statErr) {
+		t.Error("project should not be created when metadata is invalid")
+	}
+}
+
+func TestRunMetaSetAndUnset(t *testing.T) {
+	base := t.TempDir()
+	cfgPath := writeMetaTestConfig(t, base)
+	setConfigPath(t, cfgPath)
+	idxPath := writeTestIndex(t, filepath.Dir(cfgPath), []index.Entry{
+		{Name: "Proj", TemplateID: "test", Path: filepath.Join(base, "Proj"), CreatedAt: time.Now()},
+	})
+
+	if err := runMetaSet(&cobra.Command{}, []string{"Proj", "due=2026-06-01", "client=Acme"}); err != nil {
+		t.Fatalf("runMetaSet() error: %v", err)
+	}
+	idx, _ := index.Load(idxPath)
+	if idx.Projects[0].Metadata["client"] != "Acme" || idx.Projects[0].Metadata["due"] != "2026-06-01" {
+		t.Errorf("metadata = %v", idx.Projects[0].Metadata)
+	}
+
+	if err := runMetaUnset(&cobra.Command{}, []string{"Proj", "client"}); err != nil {
+		t.Fatalf("runMetaUnset() error: %v", err)
+	}
+	idx, _ = index.Load(idxPath)
+	if _, ok := idx.Projects[0].Metadata["client"]; ok {
+		t.Error("client should be removed")
+	}
+}
+
+func TestRunMetaUnsetRequired(t *testing.T) {
+	base := t.TempDir()
+	cfgPath := writeMetaTestConfig(t, base)
+	setConfigPath(t, cfgPath)
+	idxPath := writeTestIndex(t, filepath.Dir(cfgPath), []index.Entry{
+		{Name: "Proj", TemplateID: "test", Path: base, CreatedAt: time.Now(), Metadata: map[string]string{"due": "2026-06-01"}},
+	})
+	old := metaUnsetForce
+	t.Cleanup(func() { metaUnsetForce = old })
+
+	metaUnsetForce = false
+	err := runMetaUnset(&cobra.Command{}, []string{"Proj", "due"})
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatalf("expected ExitError for a required field, got %v", err)
+	}
+	idx, _ := index.Load(idxPath)
+	if idx.Projects[0].Metadata["due"] != "2026-06-01" {
+		t.Fatal("required field should be kept")
+	}
+
+	metaUnsetForce = true
+	if err := runMetaUnset(&cobra.Command{}, []string{"Proj", "due"}); err != nil {
+		t.Fatalf("runMetaUnset(--force) error: %v", err)
+	}
+	idx, _ = index.Load(idxPath)
+	if _, ok := idx.Projects[0].Metadata["due"]; ok {
+		t.Error("--force should remove the required field")
+	}
+}
+
+func TestRunMetaSetInvalidType(t *testing.T) {
+	base := t.TempDir()
+	cfgPath := writeMetaTestConfig(t, base)
+	setConfigPath(t, cfgPath)
+	writeTestIndex(t, filepath.Dir(cfgPath), []index.Entry{
+		{Name: "Proj", TemplateID: "test", Path: base, CreatedAt: time.Now()},
+	})
+
+	err := runMetaSet(&cobra.Command{}, []string{"Proj", "budget=lots"})
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatalf("expected ExitError, got %v", err)
+	}
+}
+
+func TestParseKeyValues(t *testing.T) {
+	got, err := parseKeyValues([]string{"a=1", "b = two words", "c="})
+	if err != nil {
+		t.Fatalf("parseKeyValues() error: %v", err)
+	}
+	if got["a"] != "1" || got["b"] != "two words" || got["c"] != "" {
+		t.Errorf("parseKeyValues() = %v", got)
+	}
+	if _, err := parseKeyValues([]string{"novalue"}); err == nil {
+		t.Error("expected error for missing '='")
+	}
+}
+
+func TestBuildMetadataPersistedVariables(t *testing.T) {
+	tmpl := &config.Template{
+		Variables: []config.Variable{{Name: "client", Persist: true}, {Name: "lang"}},
+	}
+	vars := map[string]string{"client": "Acme", "lang": "go"}
+	meta, err := buildMetadata(tmpl, vars, nil, nil)
+	if err != nil {
+		t.Fatalf("buildMetadata() error: %v", err)
+	}
+	if len(meta) != 1 || meta["client"] != "Acme" {
+		t.Errorf("meta = %v, want only client", meta)
+	}
+}
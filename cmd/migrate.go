@@ -0,0 +1,542 @@
+package cmd
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fwartner/prjct/internal/config"
+	"github.com/fwartner/prjct/internal/index"
+	"github.com/fwartner/prjct/internal/journal"
+	tmplpkg "github.com/fwartner/prjct/internal/template"
+	"github.com/spf13/cobra"
+)
+
+var migrateAllOf string
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate [query]",
+	Short: "Upgrade projects to the current version of their template",
+	Long: `Applies a template's migrations to projects created with an older
+version of it, e.g. after renaming Color to 05_Color or moving Graphics
+under Edits:
+
+  version: 3
+  migrations:
+    - version: 2
+      steps:
+        - rename: Color
+          to: 05_Color
+    - version: 3
+      steps:
+        - move: Graphics
+          to: Edits/Graphics
+        - merge: Old_Audio
+          to: Audio
+        - delete_if_empty: Temp
+
+Migrate the first project matching a query, or every project of a template
+with --all-of. Use --dry-run to see the plan first. Files are never deleted
+or overwritten: steps whose source is missing or whose target already
+exists are skipped and reported. Each migrated project is journaled and can
+be reverted with "prjct undo".`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runMigrate,
+}
+
+// migrateView is the machine-readable plan or result for one project.
+type migrateView struct {
+	Name    string   `json:"name" yaml:"name"`
+	Path    string   `json:"path" yaml:"path"`
+	From    int      `json:"from_version" yaml:"from_version"`
+	To      int      `json:"to_version" yaml:"to_version"`
+	Actions []string `json:"actions" yaml:"actions"`
+	Skipped []string `json:"skipped" yaml:"skipped"`
+	Applied bool     `json:"applied" yaml:"applied"`
+	Error   string   `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// Migration actions. Paths are relative to the project root.
+const (
+	actionMove  = "move"
+	actionMkdir = "mkdir"
+	actionRmdir = "rmdir"
+)
+
+// migrateAction is a single filesystem change of a migration.
+type migrateAction struct {
+	Op   string
+	From string
+	To   string // move only
+}
+
+func (a migrateAction) String() string {
+	if a.Op == actionMove {
+		return fmt.Sprintf("move %s → %s", a.From, a.To)
+	}
+	return a.Op + " " + a.From
+}
+
+func init() {
+	migrateCmd.Flags().StringVar(&migrateAllOf, "all-of", "", "migrate every project of this template")
+}
+
+func runMigrate(cmd *cobra.Command, args []string) error {
+	if (len(args) == 0) == (migrateAllOf == "") {
+		return &ExitError{Code: ExitGeneral, Message: "give a project query or --all-of <template>"}
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	idxPath, err := resolveIndexPath()
+	if err != nil {
+		return err
+	}
+	idx, err := index.Load(idxPath)
+	if err != nil {
+		return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("cannot load index: %v", err)}
+	}
+
+	var entries []index.Entry
+	if migrateAllOf != "" {
+		if cfg.FindTemplate(migrateAllOf) == nil {
+			return &ExitError{Code: ExitTemplateNotFound, Message: fmt.Sprintf("template %q not found in config", migrateAllOf)}
+		}
+		for _, e := range idx.Projects {
+			if e.TemplateID == migrateAllOf {
+				entries = append(entries, e)
+			}
+		}
+	} else {
+		results := index.Search(idx, args[0])
+		if len(results) == 0 {
+			return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("no project matching %q", args[0])}
+		}
+		entries = results[:1]
+	}
+
+	views := []migrateView{}
+	failed := 0
+	for _, entry := range entries {
+		tmpl, err := cfg.ResolveTemplate(entry.TemplateID)
+		if err != nil {
+			return &ExitError{Code: ExitTemplateNotFound, Message: fmt.Sprintf("template %q not found in config", entry.TemplateID)}
+		}
+		from, to := projectVersion(entry), tmpl.CurrentVersion()
+		if from >= to {
+			if verbose && !isStructuredOutput() {
+				fmt.Printf("%s: up to date (version %d)\n", entry.Name, from)
+			}
+			continue
+		}
+		if _, err := os.Stat(entry.Path); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: skipping %s: %v\n", entry.Name, err)
+			continue
+		}
+
+		actions, skipped := planMigration(tmpl, entry, from)
+		view := migrateView{
+			Name:    entry.Name,
+			Path:    entry.Path,
+			From:    from,
+			To:      to,
+			Actions: []string{},
+			Skipped: nonNil(skipped),
+		}
+		for _, a := range actions {
+			view.Actions = append(view.Actions, a.String())
+		}
+		if !dryRun {
+			if err := applyMigration(idxPath, entry, tmpl.ID, from, to, actions); err != nil {
+				view.Error = err.Error()
+				failed++
+			} else {
+				view.Applied = true
+			}
+		}
+		views = append(views, view)
+	}
+
+	var exitErr *ExitError
+	if failed > 0 {
+		exitErr = &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("%d project(s) could not be migrated", failed)}
+	}
+	if isStructuredOutput() {
+		if exitErr != nil {
+			return writeOutputError("migrate", views, exitErr)
+		}
+		return writeOutput("migrate", views)
+	}
+
+	if len(views) == 0 {
+		fmt.Println("All projects are up to date.")
+		return nil
+	}
+	for _, v := range views {
+		fmt.Printf("%s: version %d → %d\n", v.Name, v.From, v.To)
+		prefix := "  "
+		if dryRun {
+			prefix = "  [DRY-RUN] "
+		}
+		for _, a := range v.Actions {
+			fmt.Printf("%s%s\n", prefix, a)
+		}
+		for _, s := range v.Skipped {
+			fmt.Printf("  skip: %s\n", s)
+		}
+		if v.Error != "" {
+			fmt.Printf("  failed: %s\n", v.Error)
+		}
+	}
+	if dryRun {
+		fmt.Printf("\nDry run — would migrate %d project(s)\n", len(views))
+	} else {
+		fmt.Printf("\nMigrated %d of %d project(s)\n", len(views)-failed, len(views))
+	}
+	if exitErr != nil {
+		return exitErr
+	}
+	return nil
+}
+
+// projectVersion returns the template version a project's layout follows.
+func projectVersion(entry index.Entry) int {
+	if entry.TemplateVersion < 1 {
+		return 1
+	}
+	return entry.TemplateVersion
+}
+
+// planMigration returns the actions that bring entry from version from to
+// the template's current version, and the steps that had to be skipped.
+// Steps are planned against a snapshot of the project so later steps see
+// the effect of earlier ones.
+func planMigration(tmpl *config.Template, entry index.Entry, from int) ([]migrateAction, []string) {
+	tree := newMigrateTree(entry.Path)
+	vars := creationVars(tmpl, entry)
+	var skipped []string
+
+	for _, m := range tmpl.Migrations {
+		if m.Version <= from {
+			continue
+		}
+		for _, step := range m.Steps {
+			kind, src := step.Kind()
+			src = cleanRel(tmplpkg.Resolve(src, vars))
+			dst := cleanRel(tmplpkg.Resolve(step.To, vars))
+			if kind == config.StepRename {
+				dst = path.Join(path.Dir(src), dst)
+			}
+			// Variables may resolve to paths the config check could not see
+			if leavesRoot(src) || leavesRoot(dst) {
+				skipped = append(skipped, fmt.Sprintf("v%d %s %s: leaves the project", m.Version, kind, src))
+				continue
+			}
+			if reason := tree.apply(kind, src, dst); reason != "" {
+				skipped = append(skipped, fmt.Sprintf("v%d %s %s: %s", m.Version, kind, src, reason))
+			}
+		}
+	}
+	return tree.actions, skipped
+}
+
+func cleanRel(p string) string {
+	return strings.TrimPrefix(path.Clean(filepath.ToSlash(p)), "/")
+}
+
+// leavesRoot reports whether a path cleaned by cleanRel points outside the
+// directory it is relative to.
+func leavesRoot(rel string) bool {
+	return rel == ".." || strings.HasPrefix(rel, "../")
+}
+
+// migrateTree is a snapshot of a project's directories and files that
+// migration steps are planned against.
+type migrateTree struct {
+	// paths maps slash-separated relative paths to whether they are
+	// directories.
+	paths   map[string]bool
+	actions []migrateAction
+}
+
+func newMigrateTree(root string) *migrateTree {
+	t := &migrateTree{paths: make(map[string]bool)}
+	_ = filepath.WalkDir(root, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		rel, relErr := filepath.Rel(root, p)
+		if relErr != nil || rel == "." {
+			return nil
+		}
+		t.paths[filepath.ToSlash(rel)] = d.IsDir()
+		return nil
+	})
+	return t
+}
+
+func (t *migrateTree) exists(p string) bool {
+	_, ok := t.paths[p]
+	return ok
+}
+
+func (t *migrateTree) isDir(p string) bool {
+	return t.paths[p]
+}
+
+// below returns p and every path below it.
+func (t *migrateTree) below(p string) []string {
+	var out []string
+	for q := range t.paths {
+		if q == p || strings.HasPrefix(q, p+"/") {
+			out = append(out, q)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// children returns the direct children of directory p.
+func (t *migrateTree) children(p string) []string {
+	var out []string
+	for q := range t.paths {
+		if path.Dir(q) == p {
+			out = append(out, q)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// apply plans one step and returns why it was skipped, if it was.
+func (t *migrateTree) apply(kind, src, dst string) string {
+	if src == "." || (dst == "." && kind != config.StepDeleteIfEmpty) {
+		return "cannot change the project root"
+	}
+	if !t.exists(src) {
+		return "not found"
+	}
+	if !t.isDir(src) {
+		return "not a directory"
+	}
+
+	switch kind {
+	case config.StepRename, config.StepMove:
+		if t.exists(dst) {
+			return dst + " already exists"
+		}
+		if strings.HasPrefix(dst, src+"/") {
+			return "cannot move a directory into itself"
+		}
+		t.mkdirAll(path.Dir(dst))
+		t.move(src, dst)
+	case config.StepMerge:
+		if !t.exists(dst) {
+			t.mkdirAll(path.Dir(dst))
+			t.move(src, dst)
+			return ""
+		}
+		if !t.isDir(dst) {
+			return dst + " is not a directory"
+		}
+		if strings.HasPrefix(dst, src+"/") {
+			return "cannot merge a directory into itself"
+		}
+		if conflicts := t.merge(src, dst); len(conflicts) > 0 {
+			return "kept " + strings.Join(conflicts, ", ") + " (exists in " + dst + ")"
+		}
+	case config.StepDeleteIfEmpty:
+		for _, p := range t.below(src) {
+			if !t.isDir(p) {
+				return "contains files"
+			}
+		}
+		t.removeTree(src)
+	}
+	return ""
+}
+
+func (t *migrateTree) move(src, dst string) {
+	t.actions = append(t.actions, migrateAction{Op: actionMove, From: src, To: dst})
+	for _, p := range t.below(src) {
+		isDir := t.paths[p]
+		delete(t.paths, p)
+		t.paths[dst+strings.TrimPrefix(p, src)] = isDir
+	}
+}
+
+func (t *migrateTree) mkdirAll(dir string) {
+	if dir == "." || t.exists(dir) {
+		return
+	}
+	t.mkdirAll(path.Dir(dir))
+	t.actions = append(t.actions, migrateAction{Op: actionMkdir, From: dir})
+	t.paths[dir] = true
+}
+
+// merge moves the contents of src into dst, descending into directories
+// that exist in both, and removes src if nothing is left in it. It returns
+// the files that could not be moved because dst has them too.
+func (t *migrateTree) merge(src, dst string) []string {
+	var conflicts []string
+	for _, child := range t.children(src) {
+		target := path.Join(dst, path.Base(child))
+		switch {
+		case !t.exists(target):
+			t.move(child, target)
+		case t.isDir(child) && t.isDir(target):
+			conflicts = append(conflicts, t.merge(child, target)...)
+		default:
+			conflicts = append(conflicts, child)
+		}
+	}
+	if len(t.children(src)) == 0 {
+		t.actions = append(t.actions, migrateAction{Op: actionRmdir, From: src})
+		delete(t.paths, src)
+	}
+	return conflicts
+}
+
+// removeTree removes the empty directory tree at dir, deepest first.
+func (t *migrateTree) removeTree(dir string) {
+	paths := t.below(dir)
+	for i := len(paths) - 1; i >= 0; i-- {
+		t.actions = append(t.actions, migrateAction{Op: actionRmdir, From: paths[i]})
+		delete(t.paths, paths[i])
+	}
+}
+
+// applyMigration carries out the actions, rolling back on failure, then
+// records the new version in the index and journals the migration.
+func applyMigration(idxPath string, entry index.Entry, templateID string, from, to int, actions []migrateAction) error {
+	if err := runMigrateActions(entry.Path, actions, false); err != nil {
+		return err
+	}
+
+	_ = index.Update(idxPath, entry.Path, func(e *index.Entry) {
+		e.TemplateVersion = to
+	})
+
+	rec := journal.Record{
+		Operation: journal.OpMigrate,
+		Details: map[string]string{
+			"path":     entry.Path,
+			"template": templateID,
+			"from":     strconv.Itoa(from),
+			"to":       strconv.Itoa(to),
+		},
+	}
+	recordActions(&rec, entry.Path, actions)
+	recordJournal(rec)
+	return nil
+}
+
+// recordActions adds actions performed in the project at root to rec, as
+// details that migrationActions reads back and as created and removed
+// paths.
+func recordActions(rec *journal.Record, root string, actions []migrateAction) {
+	for i, a := range actions {
+		rec.Details[fmt.Sprintf("action.%03d", i+1)] = strings.Join([]string{a.Op, a.From, a.To}, "\t")
+		switch a.Op {
+		case actionMove:
+			rec.Removed = append(rec.Removed, filepath.Join(root, filepath.FromSlash(a.From)))
+			rec.Created = append(rec.Created, filepath.Join(root, filepath.FromSlash(a.To)))
+		case actionMkdir:
+			rec.Created = append(rec.Created, filepath.Join(root, filepath.FromSlash(a.From)))
+		case actionRmdir:
+			rec.Removed = append(rec.Removed, filepath.Join(root, filepath.FromSlash(a.From)))
+		}
+	}
+}
+
+// runMigrateActions performs actions in the project at root, or reverts
+// them in reverse order if reverse is set. If one fails, the ones already
+// done are undone again.
+func runMigrateActions(root string, actions []migrateAction, reverse bool) error {
+	order := make([]migrateAction, len(actions))
+	copy(order, actions)
+	if reverse {
+		slices.Reverse(order)
+	}
+	for i, a := range order {
+		if err := runMigrateAction(root, a, reverse); err != nil {
+			for j := i - 1; j >= 0; j-- {
+				_ = runMigrateAction(root, order[j], !reverse)
+			}
+			return fmt.Errorf("%s: %w", a, err)
+		}
+	}
+	return nil
+}
+
+// runMigrateAction performs an action in the project at root, or its
+// inverse if reverse is set.
+func runMigrateAction(root string, a migrateAction, reverse bool) error {
+	from := filepath.Join(root, filepath.FromSlash(a.From))
+	switch {
+	case a.Op == actionMove:
+		to := filepath.Join(root, filepath.FromSlash(a.To))
+		if reverse {
+			from, to = to, from
+		}
+		if _, err := os.Lstat(to); err == nil {
+			return fmt.Errorf("%s already exists", to)
+		}
+		return os.Rename(from, to)
+	case (a.Op == actionMkdir) != reverse:
+		return os.Mkdir(from, 0755)
+	default:
+		return os.Remove(from)
+	}
+}
+
+// migrationActions reads the actions recorded in a journal record.
+func migrationActions(rec *journal.Record) []migrateAction {
+	// Keys are numbered from action.001; sort by number, since the
+	// padding no longer keeps them in order past 999.
+	byNum := make(map[int]string)
+	for k, v := range rec.Details {
+		if n, err := strconv.Atoi(strings.TrimPrefix(k, "action.")); err == nil && strings.HasPrefix(k, "action.") {
+			byNum[n] = v
+		}
+	}
+	actions := make([]migrateAction, 0, len(byNum))
+	for _, n := range slices.Sorted(maps.Keys(byNum)) {
+		parts := strings.SplitN(byNum[n], "\t", 3)
+		for len(parts) < 3 {
+			parts = append(parts, "")
+		}
+		actions = append(actions, migrateAction{Op: parts[0], From: parts[1], To: parts[2]})
+	}
+	return actions
+}
+
+// replayMigration applies the recorded actions of rec, or reverts them in
+// reverse order, and sets the project's template version accordingly.
+func replayMigration(rec *journal.Record, reverse bool) error {
+	root := rec.Details["path"]
+	actions := migrationActions(rec)
+	if err := runMigrateActions(root, actions, reverse); err != nil {
+		return &ExitError{Code: ExitGeneral, Message: err.Error()}
+	}
+
+	version, _ := strconv.Atoi(rec.Details["to"])
+	if reverse {
+		version, _ = strconv.Atoi(rec.Details["from"])
+	}
+	if idxPath, err := resolveIndexPath(); err == nil {
+		_ = index.Update(idxPath, root, func(e *index.Entry) {
+			e.TemplateVersion = version
+		})
+	}
+	fmt.Printf("Project %s at template version %d (%d action(s))\n", root, version, len(actions))
+	return nil
+}
@@ -0,0 +1,222 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/fwartner/prjct/internal/config"
+	"github.com/fwartner/prjct/internal/index"
+	"github.com/fwartner/prjct/internal/journal"
+	"github.com/spf13/cobra"
+)
+
+const migrateTestTemplate = `
+    version: 3
+    migrations:
+      - version: 2
+        steps:
+          - rename: Color
+            to: 05_Color
+      - version: 3
+        steps:
+          - move: Graphics
+            to: Edits/Graphics
+          - merge: Old_Audio
+            to: Audio
+          - delete_if_empty: Temp
+          - rename: Missing
+            to: Found
+    directories:
+      - name: 05_Color
+      - name: Edits
+        children:
+          - name: Graphics
+      - name: Audio
+`
+
+// migrateTestSetup creates a project at template version 1 and one that is
+// already current.
+func migrateTestSetup(t *testing.T) (dir, project string) {
+	t.Helper()
+	dir = t.TempDir()
+	base := filepath.Join(dir, "projects")
+	project = filepath.Join(base, "old")
+	for _, d := range []string{"Color", "Graphics/logos", "Old_Audio", "Audio", "Temp/sub"} {
+		if err := os.MkdirAll(filepath.Join(project, d), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, f := range []string{"Color/grade.drx", "Old_Audio/vo.wav", "Audio/music.wav"} {
+		if err := os.WriteFile(filepath.Join(project, f), []byte(f), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	current := filepath.Join(base, "current")
+	if err := os.MkdirAll(filepath.Join(current, "05_Color"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	cfgPath := filepath.Join(dir, "config.yaml")
+	cfg := "templates:\n  - id: video\n    name: Video\n    base_path: " + base + migrateTestTemplate
+	if err := os.WriteFile(cfgPath, []byte(cfg), 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeTestIndex(t, dir, []index.Entry{
+		{Name: "old", TemplateID: "video", Path: project},
+		{Name: "current", TemplateID: "video", Path: current, TemplateVersion: 3},
+	})
+	setConfigPath(t, cfgPath)
+
+	old := migrateAllOf
+	migrateAllOf = "video"
+	t.Cleanup(func() { migrateAllOf = old })
+	return dir, project
+}
+
+func TestRunMigrateDryRun(t *testing.T) {
+	_, project := migrateTestSetup(t)
+	setDryRun(t, true)
+	buf := setOutputFormat(t, OutputJSON)
+
+	if err := runMigrate(&cobra.Command{}, nil); err != nil {
+		t.Fatalf("runMigrate() error: %v", err)
+	}
+	var env struct {
+		Data []migrateView `json:"data"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &env); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, buf.String())
+	}
+	if len(env.Data) != 1 {
+		t.Fatalf("got %d projects, want only the outdated one: %+v", len(env.Data), env.Data)
+	}
+	v := env.Data[0]
+	if v.From != 1 || v.To != 3 || v.Applied {
+		t.Errorf("view = %+v, want unapplied 1 → 3", v)
+	}
+	want := []string{
+		"move Color → 05_Color",
+		"mkdir Edits",
+		"move Graphics → Edits/Graphics",
+		"move Old_Audio/vo.wav → Audio/vo.wav",
+		"rmdir Old_Audio",
+		"rmdir Temp/sub",
+		"rmdir Temp",
+	}
+	if len(v.Actions) != len(want) {
+		t.Fatalf("actions = %q, want %q", v.Actions, want)
+	}
+	for i := range want {
+		if v.Actions[i] != want[i] {
+			t.Errorf("action %d = %q, want %q", i, v.Actions[i], want[i])
+		}
+	}
+	if len(v.Skipped) != 1 {
+		t.Errorf("skipped = %q, want the missing rename", v.Skipped)
+	}
+	if _, err := os.Stat(filepath.Join(project, "Color")); err != nil {
+		t.Errorf("dry run changed the project: %v", err)
+	}
+}
+
+func TestRunMigrateAndUndo(t *testing.T) {
+	dir, project := migrateTestSetup(t)
+	setDryRun(t, false)
+	setOutputFormat(t, OutputJSON)
+	idxPath := filepath.Join(dir, "projects.json")
+
+	if err := runMigrate(&cobra.Command{}, nil); err != nil {
+		t.Fatalf("runMigrate() error: %v", err)
+	}
+	for _, p := range []string{"05_Color/grade.drx", "Edits/Graphics/logos", "Audio/vo.wav", "Audio/music.wav"} {
+		if _, err := os.Stat(filepath.Join(project, p)); err != nil {
+			t.Errorf("%s missing after migration: %v", p, err)
+		}
+	}
+	for _, p := range []string{"Color", "Graphics", "Old_Audio", "Temp"} {
+		if _, err := os.Stat(filepath.Join(project, p)); !os.IsNotExist(err) {
+			t.Errorf("%s still exists after migration", p)
+		}
+	}
+	if e, ok := findEntry(idxPath, project); !ok || e.TemplateVersion != 3 {
+		t.Errorf("template version = %d, want 3", e.TemplateVersion)
+	}
+
+	if err := runUndo(&cobra.Command{}, nil); err != nil {
+		t.Fatalf("runUndo() error: %v", err)
+	}
+	for _, p := range []string{"Color/grade.drx", "Graphics/logos", "Old_Audio/vo.wav", "Temp/sub"} {
+		if _, err := os.Stat(filepath.Join(project, p)); err != nil {
+			t.Errorf("%s missing after undo: %v", p, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(project, "Edits")); !os.IsNotExist(err) {
+		t.Error("Edits should be removed by undo")
+	}
+	if e, _ := findEntry(idxPath, project); e.TemplateVersion != 1 {
+		t.Errorf("template version after undo = %d, want 1", e.TemplateVersion)
+	}
+
+	if err := runRedo(&cobra.Command{}, nil); err != nil {
+		t.Fatalf("runRedo() error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(project, "Edits", "Graphics")); err != nil {
+		t.Errorf("redo did not move Graphics: %v", err)
+	}
+	if e, _ := findEntry(idxPath, project); e.TemplateVersion != 3 {
+		t.Errorf("template version after redo = %d, want 3", e.TemplateVersion)
+	}
+}
+
+func TestRunMigrateNeedsTarget(t *testing.T) {
+	migrateTestSetup(t)
+	if err := runMigrate(&cobra.Command{}, []string{"old"}); err == nil {
+		t.Error("expected an error for both a query and --all-of")
+	}
+	migrateAllOf = ""
+	if err := runMigrate(&cobra.Command{}, nil); err == nil {
+		t.Error("expected an error without a query or --all-of")
+	}
+}
+
+func TestPlanMigrationLeavesRoot(t *testing.T) {
+	project := filepath.Join(t.TempDir(), "proj")
+	if err := os.MkdirAll(filepath.Join(project, "Audio"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// Variables can resolve to paths the config validation cannot check
+	tmpl := &config.Template{
+		ID: "video",
+		Migrations: []config.Migration{{Version: 2, Steps: []config.MigrationStep{
+			{Move: "Audio", To: "{target}/Audio"},
+			{Move: "{target}/Other", To: "Other"},
+		}}},
+	}
+	entry := index.Entry{Name: "proj", TemplateID: "video", Path: project, Variables: map[string]string{"target": ".."}}
+
+	actions, skipped := planMigration(tmpl, entry, 1)
+	if len(actions) != 0 {
+		t.Errorf("actions = %v, want none", actions)
+	}
+	if len(skipped) != 2 {
+		t.Errorf("skipped = %v, want both steps", skipped)
+	}
+}
+
+func TestMigrationActionsOrder(t *testing.T) {
+	var want []migrateAction
+	for i := range 1200 {
+		want = append(want, migrateAction{Op: actionMkdir, From: fmt.Sprintf("dir%d", i)})
+	}
+	rec := &journal.Record{Details: map[string]string{"path": "/p"}}
+	recordActions(rec, "/p", want)
+
+	got := migrationActions(rec)
+	if !slices.Equal(got, want) {
+		t.Fatalf("migrationActions() returned %d actions out of order", len(got))
+	}
+}
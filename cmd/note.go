@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/fwartner/prjct/internal/index"
+	"github.com/fwartner/prjct/internal/journal"
 	"github.com/spf13/cobra"
 )
 
@@ -49,6 +50,10 @@ func runNote(cmd *cobra.Command, args []string) error {
 
 	// Add mode
 	noteText := args[1]
+	if dryRun {
+		fmt.Printf("  [DRY-RUN] add note to %s: %s\n", entry.Name, noteText)
+		return nil
+	}
 	err = index.Update(idxPath, entry.Path, func(e *index.Entry) {
 		e.Notes = append(e.Notes, noteText)
 	})
@@ -56,6 +61,11 @@ func runNote(cmd *cobra.Command, args []string) error {
 		return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("failed to save note: %v", err)}
 	}
 
+	recordJournal(journal.Record{
+		Operation: journal.OpNote,
+		Details:   map[string]string{"path": entry.Path, "note": noteText},
+	})
+
 	fmt.Printf("Note added to %s\n", entry.Name)
 	return nil
 }
@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"time"
+
+	"github.com/fwartner/prjct/internal/index"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats accepted by the global --output flag.
+const (
+	OutputTable  = "table"
+	OutputJSON   = "json"
+	OutputYAML   = "yaml"
+	OutputNDJSON = "ndjson"
+)
+
+// OutputSchemaVersion is bumped whenever a machine-readable result shape
+// changes in a backwards-incompatible way.
+const OutputSchemaVersion = 1
+
+var outputFormat string
+
+// outputStdout is the writer used for machine-readable output. Overridden in tests.
+var outputStdout io.Writer = os.Stdout
+
+// outputEnvelope wraps every machine-readable result.
+type outputEnvelope struct {
+	SchemaVersion int          `json:"schema_version" yaml:"schema_version"`
+	Command       string       `json:"command" yaml:"command"`
+	Data          any          `json:"data,omitempty" yaml:"data,omitempty"`
+	Error         *outputError `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// outputError is the machine-readable form of an ExitError.
+type outputError struct {
+	Code    int    `json:"code" yaml:"code"`
+	Message string `json:"message" yaml:"message"`
+}
+
+// projectView is the stable output schema for an indexed project.
+type projectView struct {
+	Name          string             `json:"name" yaml:"name"`
+	TemplateID    string             `json:"template_id" yaml:"template_id"`
+	TemplateName  string             `json:"template_name" yaml:"template_name"`
+	Path          string             `json:"path" yaml:"path"`
+	CreatedAt     time.Time          `json:"created_at" yaml:"created_at"`
+	Status        string             `json:"status,omitempty" yaml:"status,omitempty"`
+	StatusHistory []statusChangeView `json:"status_history,omitempty" yaml:"status_history,omitempty"`
+	Metadata      map[string]string  `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+	Tags          []string           `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Notes         []string           `json:"notes,omitempty" yaml:"notes,omitempty"`
+}
+
+// statusChangeView is the stable output schema for a status transition.
+type statusChangeView struct {
+	From string    `json:"from,omitempty" yaml:"from,omitempty"`
+	To   string    `json:"to" yaml:"to"`
+	At   time.Time `json:"at" yaml:"at"`
+}
+
+func newProjectView(e index.Entry) projectView {
+	v := projectView{
+		Name:         e.Name,
+		TemplateID:   e.TemplateID,
+		TemplateName: e.TemplateName,
+		Path:         e.Path,
+		CreatedAt:    e.CreatedAt,
+		Status:       e.Status,
+		Metadata:     e.Metadata,
+		Tags:         e.Tags,
+		Notes:        e.Notes,
+	}
+	for _, h := range e.StatusHistory {
+		v.StatusHistory = append(v.StatusHistory, statusChangeView{From: h.From, To: h.To, At: h.At})
+	}
+	return v
+}
+
+func projectViews(entries []index.Entry) []projectView {
+	views := make([]projectView, 0, len(entries))
+	for _, e := range entries {
+		views = append(views, newProjectView(e))
+	}
+	return views
+}
+
+// validateOutputFormat rejects unknown --output values.
+func validateOutputFormat(cmd *cobra.Command, args []string) error {
+	switch outputFormat {
+	case "", OutputTable, OutputJSON, OutputYAML, OutputNDJSON:
+		return nil
+	}
+	format := outputFormat
+	outputFormat = "" // report this error as plain text
+	return &ExitError{
+		Code:    ExitGeneral,
+		Message: fmt.Sprintf("invalid output format %q: use table, json, yaml, or ndjson", format),
+	}
+}
+
+// isStructuredOutput reports whether a machine-readable format was requested.
+func isStructuredOutput() bool {
+	switch outputFormat {
+	case OutputJSON, OutputYAML, OutputNDJSON:
+		return true
+	}
+	return false
+}
+
+// writeOutput emits data for command in the requested machine-readable format.
+// In ndjson mode, slice results are streamed as one envelope per element.
+func writeOutput(command string, data any) error {
+	if err := encodeOutput(outputStdout, command, data, nil); err != nil {
+		return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("cannot write output: %v", err), Err: err}
+	}
+	return nil
+}
+
+// writeOutputError emits data together with exitErr in a single envelope and
+// returns exitErr marked as reported, so Execute does not print it again.
+func writeOutputError(command string, data any, exitErr *ExitError) error {
+	_ = encodeOutput(outputStdout, command, data, &outputError{Code: exitErr.Code, Message: exitErr.Message})
+	exitErr.reported = true
+	return exitErr
+}
+
+func encodeOutput(w io.Writer, command string, data any, outErr *outputError) error {
+	switch outputFormat {
+	case OutputNDJSON:
+		enc := json.NewEncoder(w)
+		if v := reflect.ValueOf(data); outErr == nil && v.Kind() == reflect.Slice {
+			for i := 0; i < v.Len(); i++ {
+				env := outputEnvelope{SchemaVersion: OutputSchemaVersion, Command: command, Data: v.Index(i).Interface()}
+				if err := enc.Encode(env); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		return enc.Encode(outputEnvelope{SchemaVersion: OutputSchemaVersion, Command: command, Data: data, Error: outErr})
+	case OutputYAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(outputEnvelope{SchemaVersion: OutputSchemaVersion, Command: command, Data: data, Error: outErr}); err != nil {
+			return err
+		}
+		return enc.Close()
+	default:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(outputEnvelope{SchemaVersion: OutputSchemaVersion, Command: command, Data: data, Error: outErr})
+	}
+}
+
+// commandName returns the name used in output envelopes for cmd.
+// Project creation via the root command is reported as "create".
+func commandName(cmd *cobra.Command) string {
+	if cmd == nil || cmd == rootCmd {
+		return "create"
+	}
+	return cmd.Name()
+}
+
+// nonNil returns s, or an empty slice if s is nil, so that lists are always
+// encoded as arrays rather than null.
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
@@ -0,0 +1,222 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fwartner/prjct/internal/index"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// setOutputFormat sets the package-level outputFormat for the duration of the
+// test and returns a buffer capturing structured output.
+func setOutputFormat(t *testing.T, format string) *bytes.Buffer {
+	t.Helper()
+	oldFormat := outputFormat
+	oldStdout := outputStdout
+	buf := &bytes.Buffer{}
+	outputFormat = format
+	outputStdout = buf
+	t.Cleanup(func() {
+		outputFormat = oldFormat
+		outputStdout = oldStdout
+	})
+	return buf
+}
+
+func TestValidateOutputFormat(t *testing.T) {
+	for _, f := range []string{"", OutputTable, OutputJSON, OutputYAML, OutputNDJSON} {
+		setOutputFormat(t, f)
+		if err := validateOutputFormat(nil, nil); err != nil {
+			t.Errorf("validateOutputFormat(%q) error: %v", f, err)
+		}
+	}
+
+	setOutputFormat(t, "xml")
+	err := validateOutputFormat(nil, nil)
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatal("expected ExitError for invalid format")
+	}
+	if isStructuredOutput() {
+		t.Error("invalid format should fall back to table output")
+	}
+}
+
+func TestRunSearchJSON(t *testing.T) {
+	dir := t.TempDir()
+	entries := []index.Entry{
+		{Name: "alpha", TemplateID: "dev", Path: "/a", CreatedAt: time.Now()},
+		{Name: "beta", TemplateID: "video", Path: "/b", CreatedAt: time.Now()},
+	}
+	writeTestIndex(t, dir, entries)
+	setConfigPath(t, filepath.Join(dir, "config.yaml"))
+	setSearchTemplate(t, "")
+	buf := setOutputFormat(t, OutputJSON)
+
+	if err := runSearch(&cobra.Command{}, []string{}); err != nil {
+		t.Fatalf("runSearch() error: %v", err)
+	}
+
+	var env struct {
+		SchemaVersion int           `json:"schema_version"`
+		Command       string        `json:"command"`
+		Data          []projectView `json:"data"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &env); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, buf.String())
+	}
+	if env.SchemaVersion != OutputSchemaVersion || env.Command != "search" {
+		t.Errorf("envelope = %d/%q, want %d/search", env.SchemaVersion, env.Command, OutputSchemaVersion)
+	}
+	if len(env.Data) != 2 || env.Data[0].Name != "alpha" {
+		t.Errorf("data = %+v, want alpha and beta", env.Data)
+	}
+}
+
+func TestRunSearchNDJSON(t *testing.T) {
+	dir := t.TempDir()
+	entries := []index.Entry{
+		{Name: "alpha", TemplateID: "dev", Path: "/a", CreatedAt: time.Now()},
+		{Name: "beta", TemplateID: "video", Path: "/b", CreatedAt: time.Now()},
+	}
+	writeTestIndex(t, dir, entries)
+	setConfigPath(t, filepath.Join(dir, "config.yaml"))
+	setSearchTemplate(t, "")
+	buf := setOutputFormat(t, OutputNDJSON)
+
+	if err := runSearch(&cobra.Command{}, []string{}); err != nil {
+		t.Fatalf("runSearch() error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2:\n%s", len(lines), buf.String())
+	}
+	for _, line := range lines {
+		var env outputEnvelope
+		if err := json.Unmarshal([]byte(line), &env); err != nil {
+			t.Errorf("invalid NDJSON line %q: %v", line, err)
+		}
+	}
+}
+
+func TestRunStatsYAML(t *testing.T) {
+	dir := t.TempDir()
+	entries := []index.Entry{
+		{Name: "a", TemplateID: "video", Path: "/a", CreatedAt: time.Now()},
+		{Name: "b", TemplateID: "video", Path: "/b", CreatedAt: time.Now()},
+	}
+	writeTestIndex(t, dir, entries)
+	setConfigPath(t, filepath.Join(dir, "config.yaml"))
+	buf := setOutputFormat(t, OutputYAML)
+
+	if err := runStats(&cobra.Command{}, nil); err != nil {
+		t.Fatalf("runStats() error: %v", err)
+	}
+
+	var env struct {
+		Data statsView `yaml:"data"`
+	}
+	if err := yaml.Unmarshal(buf.Bytes(), &env); err != nil {
+		t.Fatalf("invalid YAML: %v\n%s", err, buf.String())
+	}
+	if env.Data.Total != 2 || len(env.Data.Templates) != 1 || env.Data.Templates[0].Count != 2 {
+		t.Errorf("data = %+v, want 2 video projects", env.Data)
+	}
+}
+
+func TestRunRootDryRunJSON(t *testing.T) {
+	base := t.TempDir()
+	cfgPath := writeTestConfig(t, base)
+	setConfigPath(t, cfgPath)
+	setDryRun(t, true)
+	buf := setOutputFormat(t, OutputJSON)
+
+	if err := runRoot(&cobra.Command{}, []string{"test", "Planned"}); err != nil {
+		t.Fatalf("runRoot() error: %v", err)
+	}
+
+	var env struct {
+		Data createView `json:"data"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &env); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, buf.String())
+	}
+	if !env.Data.DryRun {
+		t.Error("dry_run should be true")
+	}
+	// root + src + docs
+	if len(env.Data.Directories) != 3 {
+		t.Errorf("directories = %v, want 3 planned entries", env.Data.Directories)
+	}
+	if _, err := os.Stat(filepath.Join(base, "Planned")); !os.IsNotExist(err) {
+		t.Error("dry run should not create the project")
+	}
+}
+
+func TestWriteOutputErrorMarksReported(t *testing.T) {
+	buf := setOutputFormat(t, OutputJSON)
+
+	err := writeOutputError("doctor", map[string]int{"errors": 1}, &ExitError{Code: ExitConfigInvalid, Message: "bad"})
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatal("expected ExitError")
+	}
+	if !exitErr.reported {
+		t.Error("error should be marked as reported")
+	}
+
+	var env outputEnvelope
+	if err := json.Unmarshal(buf.Bytes(), &env); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if env.Error == nil || env.Error.Code != ExitConfigInvalid {
+		t.Errorf("error = %+v, want code %d", env.Error, ExitConfigInvalid)
+	}
+	if env.Data == nil {
+		t.Error("data should be emitted alongside the error")
+	}
+}
+
+func TestOutputFlagNotShadowed(t *testing.T) {
+	tests := []struct {
+		cmd  *cobra.Command
+		file *string
+	}{
+		{archiveCmd, &archiveOutput},
+		{exportCmd, &exportOutput},
+		{initCmd, &initOutput},
+		{readmeCmd, &readmeOutput},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cmd.Name(), func(t *testing.T) {
+			oldFormat, oldFile := outputFormat, *tt.file
+			t.Cleanup(func() {
+				outputFormat, *tt.file = oldFormat, oldFile
+				_ = tt.cmd.Flags().Set("output", OutputTable)
+				_ = tt.cmd.Flags().Set("file", "")
+			})
+
+			if err := tt.cmd.ParseFlags([]string{"--output", "json", "--file", "out.file"}); err != nil {
+				t.Fatalf("ParseFlags() error: %v", err)
+			}
+			if outputFormat != OutputJSON {
+				t.Errorf("outputFormat = %q, want json", outputFormat)
+			}
+			if *tt.file != "out.file" {
+				t.Errorf("--file = %q, want out.file", *tt.file)
+			}
+			if !isStructuredOutput() {
+				t.Error("structured output should be on")
+			}
+		})
+	}
+}
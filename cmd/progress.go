@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fwartner/prjct/internal/archive"
+)
+
+// progressInterval is how often the progress line is redrawn on a terminal.
+const progressInterval = 200 * time.Millisecond
+
+// archiveProgress renders archive progress on stderr: one line redrawn in
+// place on a terminal, otherwise a line for every tenth of the total.
+type archiveProgress struct {
+	out    io.Writer
+	tty    bool
+	start  time.Time
+	last   time.Time
+	decile int
+	width  int
+}
+
+func newArchiveProgress() *archiveProgress {
+	return &archiveProgress{out: os.Stderr, tty: isTerminal(os.Stderr), start: time.Now(), decile: -1}
+}
+
+// update is passed to archive.Options.Progress.
+func (p *archiveProgress) update(s archive.Progress) {
+	now := time.Now()
+	if p.tty {
+		if now.Sub(p.last) < progressInterval && s.Bytes < s.Total.Bytes {
+			return
+		}
+		p.last = now
+		line := progressLine(s, now.Sub(p.start))
+		pad := ""
+		if n := p.width - len(line); n > 0 {
+			pad = strings.Repeat(" ", n)
+		}
+		p.width = len(line)
+		fmt.Fprintf(p.out, "\r%s%s", line, pad)
+		return
+	}
+
+	decile := 10
+	if s.Total.Bytes > 0 {
+		decile = int(s.Bytes * 10 / s.Total.Bytes)
+	}
+	if decile > p.decile {
+		p.decile = decile
+		fmt.Fprintln(p.out, progressLine(s, now.Sub(p.start)))
+	}
+}
+
+// finish ends the progress line on a terminal.
+func (p *archiveProgress) finish() {
+	if p.tty && p.width > 0 {
+		fmt.Fprintln(p.out)
+	}
+}
+
+// progressLine formats bytes and files done, throughput and the estimated
+// time remaining, e.g. "1.2 GB / 4.0 GB (30%)  120/410 files  85.3 MB/s  ETA 33s".
+func progressLine(s archive.Progress, elapsed time.Duration) string {
+	percent := 100
+	if s.Total.Bytes > 0 {
+		percent = int(s.Bytes * 100 / s.Total.Bytes)
+	}
+	line := fmt.Sprintf("  %s / %s (%d%%)  %d/%d files",
+		formatBytes(s.Bytes), formatBytes(s.Total.Bytes), percent, s.Files, s.Total.Files)
+
+	secs := elapsed.Seconds()
+	if secs < 1 || s.Bytes == 0 {
+		return line
+	}
+	rate := float64(s.Bytes) / secs
+	line += fmt.Sprintf("  %s/s", formatBytes(int64(rate)))
+	if remaining := s.Total.Bytes - s.Bytes; remaining > 0 {
+		eta := time.Duration(float64(remaining) / rate * float64(time.Second))
+		line += "  ETA " + eta.Round(time.Second).String()
+	}
+	return line
+}
+
+// isTerminal reports whether f is attached to a terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fwartner/prjct/internal/archive"
+)
+
+func TestProgressLine(t *testing.T) {
+	s := archive.Progress{Files: 1, Bytes: 10 << 20, Total: archive.Totals{Files: 4, Bytes: 40 << 20}}
+
+	got := progressLine(s, 500*time.Millisecond)
+	if got != "  10.0 MB / 40.0 MB (25%)  1/4 files" {
+		t.Errorf("progressLine() = %q", got)
+	}
+
+	got = progressLine(s, 2*time.Second)
+	if !strings.Contains(got, "5.0 MB/s") || !strings.HasSuffix(got, "ETA 6s") {
+		t.Errorf("progressLine() = %q, want throughput and ETA", got)
+	}
+}
+
+func TestArchiveProgressDeciles(t *testing.T) {
+	var out bytes.Buffer
+	p := &archiveProgress{out: &out, start: time.Now(), decile: -1}
+	total := archive.Totals{Files: 1, Bytes: 100}
+	for b := int64(0); b <= 100; b += 5 {
+		p.update(archive.Progress{Bytes: b, Total: total})
+	}
+	if lines := strings.Count(out.String(), "\n"); lines != 11 {
+		t.Errorf("got %d progress lines, want one per tenth:\n%s", lines, out.String())
+	}
+}
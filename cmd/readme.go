@@ -21,7 +21,7 @@ structure with file listings. Useful for documenting project layouts.`,
 }
 
 func init() {
-	readmeCmd.Flags().StringVarP(&readmeOutput, "output", "o", "", "output file (default: stdout)")
+	readmeCmd.Flags().StringVarP(&readmeOutput, "file", "f", "", "output file (default: stdout)")
 }
 
 func runReadme(cmd *cobra.Command, args []string) error {
@@ -18,6 +18,10 @@ var recentCmd = &cobra.Command{
 	RunE:  runRecent,
 }
 
+func init() {
+	addFormatFlag(recentCmd)
+}
+
 func runRecent(cmd *cobra.Command, args []string) error {
 	n := 10
 	if len(args) == 1 {
@@ -44,17 +48,24 @@ func runRecent(cmd *cobra.Command, args []string) error {
 		entries = entries[:n]
 	}
 
+	if formatFlag != "" {
+		return writeFormatted(outputStdout, projectViews(entries))
+	}
+	if isStructuredOutput() {
+		return writeOutput("recent", projectViews(entries))
+	}
+
 	if len(entries) == 0 {
 		fmt.Println("No projects indexed yet.")
 		return nil
 	}
 
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
-	fmt.Fprintf(w, "  NAME\tTEMPLATE\tPATH\tCREATED\n")
-	fmt.Fprintf(w, "  ----\t--------\t----\t-------\n")
+	fmt.Fprintf(w, "  NAME\tTEMPLATE\tSTATUS\tPATH\tCREATED\n")
+	fmt.Fprintf(w, "  ----\t--------\t------\t----\t-------\n")
 	for _, e := range entries {
 		created := e.CreatedAt.Format("2006-01-02 15:04")
-		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n", e.Name, e.TemplateID, e.Path, created)
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s\n", e.Name, e.TemplateID, statusLabel(e.Status), e.Path, created)
 	}
 	w.Flush()
 
@@ -0,0 +1,386 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fwartner/prjct/internal/archive"
+	"github.com/fwartner/prjct/internal/config"
+	"github.com/fwartner/prjct/internal/fscopy"
+	"github.com/fwartner/prjct/internal/index"
+	"github.com/fwartner/prjct/internal/journal"
+	"github.com/fwartner/prjct/internal/project"
+	tmplpkg "github.com/fwartner/prjct/internal/template"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var redoCmd = &cobra.Command{
+	Use:   "redo [id | --group id]",
+	Short: "Re-apply an undone operation",
+	Long: `Re-applies an operation reverted by "prjct undo". Without an ID the
+most recently undone operation is redone. Redo is refused while operations
+made after the undo touch the same project or template. With --group,
+every undone operation of one invocation is redone, oldest first.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runRedo,
+}
+
+var redoGroup string
+
+func init() {
+	redoCmd.Flags().StringVar(&redoGroup, "group", "", "redo all undone operations of one invocation")
+}
+
+func runRedo(cmd *cobra.Command, args []string) error {
+	jPath, err := resolveJournalPath()
+	if err != nil {
+		return &ExitError{Code: ExitGeneral, Message: err.Error()}
+	}
+
+	j, err := journal.Load(jPath)
+	if err != nil {
+		return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("cannot read journal: %v", err)}
+	}
+
+	if redoGroup != "" {
+		if len(args) > 0 {
+			return &ExitError{Code: ExitGeneral, Message: "give an operation ID or --group, not both"}
+		}
+		return redoGroupRecords(jPath, j, redoGroup)
+	}
+
+	rec, err := selectRecord(j, args, j.LastUndone)
+	if err != nil {
+		return err
+	}
+	if rec == nil {
+		fmt.Println("Nothing to redo.")
+		return nil
+	}
+	if rec.Undone == nil {
+		return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("operation #%d has not been undone", rec.ID)}
+	}
+	if conflicts := j.Conflicts(rec); len(conflicts) > 0 {
+		return &ExitError{Code: ExitGeneral, Message: dependencyMessage("redo", rec, conflicts, "undo them first")}
+	}
+
+	fmt.Printf("Operation #%d: %s at %s\n", rec.ID, rec.Operation, rec.Timestamp.Format("2006-01-02 15:04:05"))
+	printDetails(rec)
+
+	if dryRun {
+		fmt.Println("Dry run — would re-apply the above operation.")
+		return nil
+	}
+
+	if redone, err := applyRedo(jPath, rec); !redone {
+		return err
+	}
+	fmt.Println("Redo complete.")
+	return nil
+}
+
+// redoGroupRecords re-applies the undone records of one invocation, oldest
+// first. Nothing is redone while later operations conflict with any of
+// them.
+func redoGroupRecords(jPath string, j *journal.Journal, group string) error {
+	var recs []*journal.Record
+	for i := range j.Records {
+		if r := &j.Records[i]; r.Group == group && r.Undone != nil {
+			recs = append(recs, r)
+		}
+	}
+	if len(recs) == 0 {
+		return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("no undone operations of group %s", group)}
+	}
+	for _, rec := range recs {
+		if conflicts := j.Conflicts(rec); len(conflicts) > 0 {
+			return &ExitError{Code: ExitGeneral, Message: dependencyMessage("redo", rec, conflicts, "undo them first")}
+		}
+	}
+
+	for _, rec := range recs {
+		fmt.Printf("Operation #%d: %s at %s\n", rec.ID, rec.Operation, rec.Timestamp.Format("2006-01-02 15:04:05"))
+		printDetails(rec)
+		if dryRun {
+			continue
+		}
+		if _, err := applyRedo(jPath, rec); err != nil {
+			return err
+		}
+	}
+	if dryRun {
+		fmt.Printf("Dry run — would re-apply %d operation(s) of group %s.\n", len(recs), group)
+		return nil
+	}
+	fmt.Println("Redo complete.")
+	return nil
+}
+
+// applyRedo re-applies rec and clears its undo marker in the journal at
+// jPath and in rec itself, reporting whether it did.
+func applyRedo(jPath string, rec *journal.Record) (bool, error) {
+	err := redoRecord(rec)
+	if errors.Is(err, errNotUndoable) {
+		fmt.Printf("Cannot redo operation type %q automatically.\n", rec.Operation)
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if err := journal.Mark(jPath, rec.ID, func(r *journal.Record) {
+		r.Undone = nil
+	}); err != nil {
+		return false, &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("cannot update journal: %v", err)}
+	}
+	rec.Undone = nil
+	return true, nil
+}
+
+// redoRecord re-applies an operation using the state saved by undoRecord.
+func redoRecord(rec *journal.Record) error {
+	idxPath, _ := resolveIndexPath()
+	path := rec.Details["path"]
+	state := rec.Undone.State
+
+	switch rec.Operation {
+	case journal.OpCreate:
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		tmpl, err := cfg.ResolveTemplate(rec.Details["template"])
+		if err != nil {
+			return &ExitError{Code: ExitTemplateNotFound, Message: fmt.Sprintf("template %q not found in config", rec.Details["template"])}
+		}
+		name := rec.Details["name"]
+		opts := project.CreateOptions{Verbose: verbose, Variables: tmplpkg.BuiltinVars(name, time.Now())}
+		for _, v := range tmpl.Variables {
+			opts.Variables[v.Name] = v.Default
+		}
+		// Recreate with the variables of the original project, if recorded
+		if saved, ok := savedEntry(state); ok && len(saved.Variables) > 0 {
+			opts.Variables = maps.Clone(saved.Variables)
+			opts.SkipOptional = make(map[string]bool)
+			for _, d := range saved.SkippedOptional {
+				opts.SkipOptional[d] = true
+			}
+		}
+		result, err := project.Create(tmpl, name, opts)
+		if err != nil {
+			return mapCreateError(err)
+		}
+		restoreEntry(idxPath, state, newIndexEntry(cfg, tmpl, name, result.ProjectPath, nil, nil, opts))
+		fmt.Printf("Recreated: %s\n", result.ProjectPath)
+		return nil
+
+	case journal.OpClone:
+		source := rec.Details["source"]
+		if source == "" {
+			return errNotUndoable
+		}
+		opts := cloneOptions{
+			WithFiles:       rec.Details["with_files"] == "true",
+			OldName:         rec.Details["source_name"],
+			NewName:         rec.Details["name"],
+			ReplaceContents: rec.Details["replace_contents"] == "true",
+			Method:          fscopy.Copy,
+		}
+		if m, err := fscopy.ParseMethod(rec.Details["link"]); err == nil {
+			opts.Method = m
+		}
+		if exclude := rec.Details["exclude"]; exclude != "" {
+			opts.Exclude = strings.Split(exclude, "\n")
+		}
+		if _, err := cloneTree(source, path, opts); err != nil {
+			return &ExitError{Code: ExitCreateFailed, Message: fmt.Sprintf("clone failed: %v", err)}
+		}
+		if clone, ok := savedEntry(state); ok {
+			rerenderClone(idxPath, &clone, source, opts)
+		}
+		restoreEntry(idxPath, state, index.Entry{Name: rec.Details["name"], Path: path, CreatedAt: time.Now()})
+		fmt.Printf("Cloned again: %s\n", path)
+		return nil
+
+	case journal.OpRename:
+		oldPath, newPath := rec.Details["old_path"], rec.Details["new_path"]
+		if err := renameProject(idxPath, oldPath, newPath, rec.Details["new_name"]); err != nil {
+			return err
+		}
+		fmt.Printf("Renamed: %s -> %s\n", oldPath, newPath)
+		return nil
+
+	case journal.OpNote:
+		if err := updateEntry(idxPath, path, func(e *index.Entry) {
+			e.Notes = append(e.Notes, rec.Details["note"])
+		}); err != nil {
+			return err
+		}
+		fmt.Printf("Note re-added to %s\n", path)
+		return nil
+
+	case journal.OpSync:
+		dirs, files := syncedPaths(rec)
+		created, err := makeDirs(dirs)
+		if err != nil {
+			return err
+		}
+		hashes := make(map[string]string)
+		for _, rel := range files {
+			filePath := filepath.Join(path, filepath.FromSlash(rel))
+			content := rec.Details["file."+rel]
+			current, err := os.ReadFile(filePath)
+			if err == nil && string(current) != rec.Details["old."+rel] {
+				fmt.Printf("  kept %s (modified)\n", filePath)
+				continue
+			}
+			if _, err := writeSyncedFile(filePath, []byte(content)); err != nil {
+				return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("cannot write %s: %v", filePath, err)}
+			}
+			hashes[rel] = project.HashContent([]byte(content))
+		}
+		if len(hashes) > 0 {
+			setFileHashes(idxPath, path, hashes)
+		}
+		fmt.Printf("Synced %d missing directory(ies), %d file(s)\n", created, len(hashes))
+		return nil
+
+	case journal.OpClean:
+		removed, kept := removeEmptyDirs(rec.Removed)
+		fmt.Printf("Removed %d empty directory(ies)\n", len(removed))
+		for _, dir := range kept {
+			fmt.Printf("  kept %s (not empty)\n", dir)
+		}
+		return nil
+
+	case journal.OpImport:
+		return redoImport(rec)
+
+	case journal.OpMigrate:
+		return replayMigration(rec, false)
+
+	case journal.OpTidy:
+		return replayTidy(rec, false)
+
+	case journal.OpArchive:
+		opts := archive.Options{Name: filepath.Base(path), Template: rec.Details["template"], Format: archive.Format(rec.Details["format"])}
+		opts.Level, _ = strconv.Atoi(rec.Details["level"])
+		opts.Exclude = splitList(rec.Details["exclude"])
+		opts.Only = splitList(rec.Details["only"])
+		opts.Split, _ = strconv.ParseInt(rec.Details["split"], 10, 64)
+		if _, err := archive.Create(rec.Details["archive"], path, opts); err != nil {
+			return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("archive failed: %v", err)}
+		}
+		fmt.Printf("Archived: %s\n", rec.Details["archive"])
+		return nil
+
+	case journal.OpRestore:
+		if _, err := restoreArchive(rec.Details["archive"], path); err != nil {
+			return err
+		}
+		if oldPath := rec.Details["old_path"]; oldPath != "" {
+			_ = index.Update(idxPath, oldPath, func(e *index.Entry) {
+				e.Path = path
+			})
+		}
+		if len(rec.Indexed) > 0 {
+			restoreEntry(idxPath, state, index.Entry{Name: filepath.Base(path), Path: path, CreatedAt: time.Now()})
+		}
+		fmt.Printf("Restored: %s\n", path)
+		return nil
+
+	case journal.OpReindex:
+		var entries []index.Entry
+		if err := json.Unmarshal([]byte(state["entries"]), &entries); err != nil {
+			return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("cannot read saved index entries: %v", err)}
+		}
+		for _, e := range entries {
+			_ = index.Add(idxPath, e)
+		}
+		fmt.Printf("Re-indexed %d project(s)\n", len(entries))
+		return nil
+
+	case journal.OpStatus:
+		if err := updateEntry(idxPath, path, func(e *index.Entry) {
+			e.SetStatus(rec.Details["to"], time.Now())
+		}); err != nil {
+			return err
+		}
+		fmt.Printf("Status of %s set to %s\n", path, rec.Details["to"])
+		return nil
+
+	case journal.OpMeta:
+		if err := updateEntry(idxPath, path, func(e *index.Entry) {
+			applyMetaDetails(e, rec.Details, "meta.")
+		}); err != nil {
+			return err
+		}
+		fmt.Printf("Metadata of %s re-applied\n", path)
+		return nil
+
+	case journal.OpTag:
+		if err := updateEntry(idxPath, path, func(e *index.Entry) {
+			e.AddTags(splitList(rec.Details["added"])...)
+			e.RemoveTags(splitList(rec.Details["removed"])...)
+		}); err != nil {
+			return err
+		}
+		fmt.Printf("Tags of %s re-applied\n", path)
+		return nil
+	}
+	return errNotUndoable
+}
+
+// redoImport adds back the templates removed by undoImport.
+func redoImport(rec *journal.Record) error {
+	var templates []config.Template
+	if err := yaml.Unmarshal([]byte(rec.Undone.State["templates"]), &templates); err != nil {
+		return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("cannot read saved templates: %v", err)}
+	}
+
+	cfgPath := rec.Details["config"]
+	cfg, err := config.Load(cfgPath)
+	if err != nil {
+		return &ExitError{Code: ExitConfigInvalid, Message: fmt.Sprintf("loading config: %v", err)}
+	}
+	for _, t := range templates {
+		if cfg.FindTemplate(t.ID) != nil {
+			fmt.Printf("  skip: %q (ID conflict)\n", t.ID)
+			continue
+		}
+		cfg.Templates = append(cfg.Templates, t)
+		fmt.Printf("  add:  %q (%s)\n", t.ID, t.Name)
+	}
+	if err := cfg.Save(cfgPath); err != nil {
+		return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("saving config: %v", err)}
+	}
+	return nil
+}
+
+// restoreEntry re-adds the index entry saved at undo time, or fallback if
+// none was saved.
+func restoreEntry(idxPath string, state map[string]string, fallback index.Entry) {
+	entry := fallback
+	if saved, ok := savedEntry(state); ok {
+		entry = saved
+	}
+	_ = index.Add(idxPath, entry)
+}
+
+// savedEntry returns the index entry an undo saved in its state.
+func savedEntry(state map[string]string) (index.Entry, bool) {
+	var saved index.Entry
+	data := state["entry"]
+	if data == "" || json.Unmarshal([]byte(data), &saved) != nil {
+		return index.Entry{}, false
+	}
+	return saved, true
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/fwartner/prjct/internal/config"
 	"github.com/fwartner/prjct/internal/index"
+	"github.com/fwartner/prjct/internal/journal"
 	"github.com/spf13/cobra"
 )
 
@@ -61,6 +62,7 @@ func runReindex(cmd *cobra.Command, args []string) error {
 
 	added := 0
 	scanned := 0
+	var indexed []string
 	for _, tmpl := range templates {
 		expanded, err := config.ExpandPath(tmpl.BasePath)
 		if err != nil {
@@ -102,6 +104,7 @@ func runReindex(cmd *cobra.Command, args []string) error {
 				CreatedAt:    info.ModTime(),
 			})
 			existing[projectPath] = true
+			indexed = append(indexed, projectPath)
 			added++
 
 			if verbose {
@@ -114,6 +117,18 @@ func runReindex(cmd *cobra.Command, args []string) error {
 		return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("cannot save index: %v", err)}
 	}
 
+	if len(indexed) > 0 {
+		details := map[string]string{}
+		if reindexTemplate != "" {
+			details["template"] = reindexTemplate
+		}
+		recordJournal(journal.Record{
+			Operation: journal.OpReindex,
+			Details:   details,
+			Indexed:   indexed,
+		})
+	}
+
 	total := len(idx.Projects)
 	fmt.Printf("Indexed %d new project(s) across %d template(s) (%d total)\n", added, scanned, total)
 
@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/fwartner/prjct/internal/index"
+	"github.com/fwartner/prjct/internal/journal"
 	"github.com/spf13/cobra"
 )
 
@@ -306,3 +307,34 @@ func TestRunReindexPreservesExisting(t *testing.T) {
 		t.Error("pre-existing entry was not preserved")
 	}
 }
+
+func TestRunReindexJournalAndUndo(t *testing.T) {
+	cfgDir, _ := setupReindexEnv(t)
+	setReindexTemplate(t, "")
+	setVerbose(t, false)
+
+	if err := runReindex(&cobra.Command{}, nil); err != nil {
+		t.Fatalf("runReindex() error: %v", err)
+	}
+
+	rec, _ := journal.Last(filepath.Join(cfgDir, "journal.json"))
+	if rec == nil || rec.Operation != journal.OpReindex || len(rec.Indexed) != 3 {
+		t.Fatalf("journal record = %+v, want reindex of 3", rec)
+	}
+
+	if err := runUndo(&cobra.Command{}, nil); err != nil {
+		t.Fatalf("runUndo() error: %v", err)
+	}
+	idx, _ := index.Load(filepath.Join(cfgDir, "projects.json"))
+	if len(idx.Projects) != 0 {
+		t.Errorf("indexed %d projects after undo, want 0", len(idx.Projects))
+	}
+
+	if err := runRedo(&cobra.Command{}, nil); err != nil {
+		t.Fatalf("runRedo() error: %v", err)
+	}
+	idx, _ = index.Load(filepath.Join(cfgDir, "projects.json"))
+	if len(idx.Projects) != 3 {
+		t.Errorf("indexed %d projects after redo, want 3", len(idx.Projects))
+	}
+}
@@ -5,8 +5,6 @@ import (
 	"os"
 	"path/filepath"
 
-	"time"
-
 	"github.com/fwartner/prjct/internal/index"
 	"github.com/fwartner/prjct/internal/journal"
 	"github.com/fwartner/prjct/internal/project"
@@ -50,6 +48,10 @@ func runRename(cmd *cobra.Command, args []string) error {
 	if _, err := os.Stat(newPath); err == nil {
 		return &ExitError{Code: ExitProjectExists, Message: fmt.Sprintf("directory already exists: %s", newPath)}
 	}
+	if dryRun {
+		fmt.Printf("  [DRY-RUN] rename %s → %s\n", oldPath, newPath)
+		return nil
+	}
 
 	if err := os.Rename(oldPath, newPath); err != nil {
 		return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("rename failed: %v", err)}
@@ -63,19 +65,17 @@ func runRename(cmd *cobra.Command, args []string) error {
 		fmt.Fprintf(os.Stderr, "Warning: index update failed: %v\n", err)
 	}
 
-	// Best-effort journal recording
-	if jPath, jErr := resolveJournalPath(); jErr == nil {
-		_ = journal.Append(jPath, journal.Record{
-			Timestamp: time.Now(),
-			Operation: journal.OpRename,
-			Details: map[string]string{
-				"old_path": oldPath,
-				"new_path": newPath,
-				"old_name": entry.Name,
-				"new_name": newName,
-			},
-		})
-	}
+	recordJournal(journal.Record{
+		Operation: journal.OpRename,
+		Details: map[string]string{
+			"old_path": oldPath,
+			"new_path": newPath,
+			"old_name": entry.Name,
+			"new_name": newName,
+		},
+		Created: []string{newPath},
+		Removed: []string{oldPath},
+	})
 
 	fmt.Printf("Renamed: %s\n", oldPath)
 	fmt.Printf("     To: %s\n", newPath)
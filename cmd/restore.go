@@ -0,0 +1,289 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fwartner/prjct/internal/archive"
+	"github.com/fwartner/prjct/internal/config"
+	"github.com/fwartner/prjct/internal/index"
+	"github.com/fwartner/prjct/internal/journal"
+	"github.com/spf13/cobra"
+)
+
+var restoreTo string
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore <query|archive-file>",
+	Short: "Restore an archived project",
+	Long: `Extracts a project archive back to its original location, or into
+--to <dir>. The argument is either an archive file or a query for an
+indexed project, whose archive is looked up in the journal. The format
+(tar, tar.gz or zip) is detected from the file contents. Split archives
+are given by their base name or first volume; each volume is checked
+against the manifest before they are stitched together.
+
+Entries that would escape the target directory are rejected. If the
+archive has a manifest (embedded or <archive>.manifest.json), every file is
+verified against its SHA-256 checksum before the project is moved into
+place. The project's status is set back to active if the workflow defines
+it and allows the transition.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRestore,
+}
+
+func init() {
+	restoreCmd.Flags().StringVar(&restoreTo, "to", "", "restore into this directory instead of the original location")
+}
+
+func runRestore(cmd *cobra.Command, args []string) error {
+	idxPath, err := resolveIndexPath()
+	if err != nil {
+		return err
+	}
+
+	archivePath, entry, err := resolveRestoreSource(idxPath, args[0])
+	if err != nil {
+		return err
+	}
+
+	target, err := restoreTarget(archivePath, entry)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(target); err == nil {
+		return &ExitError{Code: ExitProjectExists, Message: fmt.Sprintf("restore target already exists: %s", target)}
+	}
+
+	if dryRun {
+		fmt.Printf("Dry run — would restore %s to %s\n", archivePath, target)
+		return nil
+	}
+
+	verified, err := restoreArchive(archivePath, target)
+	if err != nil {
+		return err
+	}
+
+	rec := journal.Record{
+		Operation: journal.OpRestore,
+		Details:   map[string]string{"path": target, "archive": archivePath},
+		Created:   []string{target},
+	}
+	if entry != nil {
+		if entry.Path != target {
+			rec.Details["old_path"] = entry.Path
+			_ = index.Update(idxPath, entry.Path, func(e *index.Entry) {
+				e.Path = target
+			})
+			entry.Path = target
+		}
+		recordJournal(rec)
+		setWorkflowStatus(idxPath, *entry, "active", true)
+	} else {
+		rec.Indexed = []string{target}
+		recordJournal(rec)
+		wf := config.DefaultWorkflow()
+		if cfg, err := loadConfig(); err == nil {
+			wf = cfg.ResolveWorkflow()
+		}
+		now := time.Now()
+		e := index.Entry{Name: filepath.Base(target), Path: target, CreatedAt: now}
+		e.SetStatus(wf.InitialStatus(), now)
+		_ = index.Add(idxPath, e)
+	}
+
+	fmt.Printf("Restored: %s\n", archivePath)
+	fmt.Printf("      To: %s\n", target)
+	if verified > 0 {
+		fmt.Printf("Verified: %d file(s)\n", verified)
+	}
+	return nil
+}
+
+// resolveRestoreSource returns the archive to restore and the index entry
+// it belongs to, if known. arg is an archive file or a project query.
+func resolveRestoreSource(idxPath, arg string) (string, *index.Entry, error) {
+	if archive.Exists(arg) {
+		archivePath, err := filepath.Abs(archive.BasePath(arg))
+		if err != nil {
+			return "", nil, &ExitError{Code: ExitGeneral, Message: err.Error()}
+		}
+		return archivePath, entryForArchive(idxPath, archivePath), nil
+	}
+
+	_, entry, err := findProject(arg)
+	if err != nil {
+		return "", nil, err
+	}
+	archivePath := archiveForEntry(entry.Path)
+	if !archive.Exists(archivePath) {
+		return "", nil, &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("no archive found for %s (looked for %s)", entry.Name, archivePath)}
+	}
+	return archivePath, &entry, nil
+}
+
+// archiveForEntry returns the archive most recently written for the project
+// at projectPath according to the journal, or <path>.<format> next to it.
+func archiveForEntry(projectPath string) string {
+	if jPath, err := resolveJournalPath(); err == nil {
+		records, _ := journal.Search(jPath, journal.Query{Op: journal.OpArchive})
+		for i := len(records) - 1; i >= 0; i-- {
+			r := records[i]
+			if r.Details["path"] != projectPath || r.Undone != nil {
+				continue
+			}
+			if archive.Exists(r.Details["archive"]) {
+				return r.Details["archive"]
+			}
+		}
+	}
+	for _, f := range archive.Formats {
+		if archive.Exists(projectPath + f.Ext()) {
+			return projectPath + f.Ext()
+		}
+	}
+	return projectPath + archive.DefaultFormat.Ext()
+}
+
+// entryForArchive finds the index entry whose project was archived to
+// archivePath, using the journal.
+func entryForArchive(idxPath, archivePath string) *index.Entry {
+	jPath, err := resolveJournalPath()
+	if err != nil {
+		return nil
+	}
+	records, _ := journal.Search(jPath, journal.Query{Op: journal.OpArchive})
+	for i := len(records) - 1; i >= 0; i-- {
+		if records[i].Details["archive"] != archivePath {
+			continue
+		}
+		if e, ok := findEntry(idxPath, records[i].Details["path"]); ok {
+			return &e
+		}
+	}
+	return nil
+}
+
+// restoreTarget decides where the project is restored: into --to, the
+// indexed project path, the source recorded in the manifest, or next to
+// the archive.
+func restoreTarget(archivePath string, entry *index.Entry) (string, error) {
+	m, err := archive.ReadSidecar(archivePath)
+	if err != nil {
+		return "", &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("cannot read manifest: %v", err)}
+	}
+
+	var original string
+	switch {
+	case entry != nil:
+		original = entry.Path
+	case m != nil && m.Source != "":
+		original = m.Source
+	default:
+		root, err := archive.RootName(archivePath)
+		if err != nil {
+			return "", &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("cannot read archive: %v", err)}
+		}
+		original = filepath.Join(filepath.Dir(archivePath), root)
+	}
+
+	if restoreTo == "" {
+		return original, nil
+	}
+	dir, err := config.ExpandPath(restoreTo)
+	if err != nil {
+		return "", &ExitError{Code: ExitGeneral, Message: err.Error()}
+	}
+	return filepath.Join(dir, filepath.Base(original)), nil
+}
+
+// restoreArchive checks the volumes of a split archive, extracts it into a
+// staging directory next to target, verifies it against the manifest if
+// there is one, and moves it into place. It returns the number of verified files.
+func restoreArchive(archivePath, target string) (int, error) {
+	parent := filepath.Dir(target)
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return 0, &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("cannot create %s: %v", parent, err)}
+	}
+	mismatches, err := archive.VerifyVolumes(archivePath)
+	if err != nil {
+		return 0, &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("cannot verify volumes: %v", err)}
+	}
+	if len(mismatches) > 0 {
+		return 0, &ExitError{Code: ExitGeneral, Message: mismatchMessage("restore aborted: damaged archive volumes", mismatches)}
+	}
+
+	staging, err := os.MkdirTemp(parent, ".prjct-restore-")
+	if err != nil {
+		return 0, &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("cannot create staging directory: %v", err)}
+	}
+	defer os.RemoveAll(staging)
+
+	res, err := archive.Extract(archivePath, staging)
+	if err != nil {
+		if errors.Is(err, archive.ErrUnsafePath) {
+			return 0, &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("refusing to restore: %v", err), Err: err}
+		}
+		return 0, &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("restore failed: %v", err), Err: err}
+	}
+	extracted := filepath.Join(staging, res.Root)
+
+	m := res.Manifest
+	if m == nil {
+		if m, err = archive.ReadSidecar(archivePath); err != nil {
+			return 0, &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("cannot read manifest: %v", err)}
+		}
+	}
+	verified := 0
+	if m != nil {
+		mismatches, err := m.Verify(extracted)
+		if err != nil {
+			return 0, &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("verification failed: %v", err)}
+		}
+		if len(mismatches) > 0 {
+			return 0, &ExitError{Code: ExitGeneral, Message: mismatchMessage("restore aborted: archive does not match its manifest", mismatches)}
+		}
+		verified = len(m.Files)
+	}
+
+	if err := os.Rename(extracted, target); err != nil {
+		return 0, &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("cannot move restored project into place: %v", err)}
+	}
+	if m != nil {
+		printOmitted(m)
+	}
+	return verified, nil
+}
+
+// mismatchMessage formats verification failures, listing at most ten.
+func mismatchMessage(title string, mismatches []archive.Mismatch) string {
+	msg := fmt.Sprintf("%s (%d file(s))", title, len(mismatches))
+	for i, m := range mismatches {
+		if i == 10 {
+			msg += fmt.Sprintf("\n  ... and %d more", len(mismatches)-10)
+			break
+		}
+		msg += fmt.Sprintf("\n  %s: %s", m.Path, m.Reason)
+	}
+	return msg
+}
+
+// printOmitted tells what the archive intentionally left out.
+func printOmitted(m *archive.Manifest) {
+	if len(m.Only) > 0 {
+		fmt.Printf("Note: archive contains only paths matching %s\n", strings.Join(m.Only, ", "))
+	}
+	if len(m.Omitted) > 0 {
+		fmt.Printf("Note: %d path(s) were excluded when archiving (%s)\n", len(m.Omitted), strings.Join(m.Exclude, ", "))
+		if verbose {
+			for _, p := range m.Omitted {
+				fmt.Printf("  - %s\n", p)
+			}
+		}
+	}
+}
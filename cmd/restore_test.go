@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fwartner/prjct/internal/index"
+	"github.com/fwartner/prjct/internal/journal"
+	"github.com/spf13/cobra"
+)
+
+func setRestoreTo(t *testing.T, dir string) {
+	t.Helper()
+	old := restoreTo
+	restoreTo = dir
+	t.Cleanup(func() { restoreTo = old })
+}
+
+// archiveTestProject creates a project with one file, archives it with
+// --delete and returns its path.
+func archiveTestProject(t *testing.T, dir string) string {
+	t.Helper()
+	projectDir := filepath.Join(dir, "myproject")
+	if err := os.MkdirAll(filepath.Join(projectDir, "src"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(projectDir, "src", "main.go"), []byte("package main"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeTestIndex(t, dir, []index.Entry{
+		{Name: "myproject", TemplateID: "dev", Path: projectDir, CreatedAt: time.Now(), Status: "active"},
+	})
+	setConfigPath(t, filepath.Join(dir, "config.yaml"))
+	setArchiveFlags(t, true, "")
+	if err := runArchive(&cobra.Command{}, []string{"myproject"}); err != nil {
+		t.Fatalf("runArchive() error: %v", err)
+	}
+	return projectDir
+}
+
+func TestRunRestoreByQuery(t *testing.T) {
+	dir := t.TempDir()
+	projectDir := archiveTestProject(t, dir)
+	setRestoreTo(t, "")
+
+	if err := runRestore(&cobra.Command{}, []string{"myproject"}); err != nil {
+		t.Fatalf("runRestore() error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(projectDir, "src", "main.go"))
+	if err != nil || string(data) != "package main" {
+		t.Fatalf("restored file = %q, %v", data, err)
+	}
+	entry, ok := findEntry(filepath.Join(dir, "projects.json"), projectDir)
+	if !ok || entry.Status != "active" {
+		t.Errorf("entry = %+v, want status active", entry)
+	}
+
+	records, _ := journal.Load(filepath.Join(dir, "journal.json"))
+	var found bool
+	for _, r := range records.Records {
+		if r.Operation == journal.OpRestore && r.Details["path"] == projectDir {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("restore not recorded in the journal")
+	}
+}
+
+func TestRunRestoreArchiveFileTo(t *testing.T) {
+	dir := t.TempDir()
+	projectDir := archiveTestProject(t, dir)
+	dest := filepath.Join(dir, "restored")
+	setRestoreTo(t, dest)
+
+	if err := runRestore(&cobra.Command{}, []string{projectDir + ".tar.gz"}); err != nil {
+		t.Fatalf("runRestore() error: %v", err)
+	}
+
+	target := filepath.Join(dest, "myproject")
+	if _, err := os.Stat(filepath.Join(target, "src", "main.go")); err != nil {
+		t.Fatalf("restored file missing: %v", err)
+	}
+	if _, err := os.Stat(projectDir); !os.IsNotExist(err) {
+		t.Error("original location should stay empty with --to")
+	}
+	if _, ok := findEntry(filepath.Join(dir, "projects.json"), target); !ok {
+		t.Error("index entry should point to the new location")
+	}
+
+	if err := runUndo(&cobra.Command{}, []string{}); err != nil {
+		t.Fatalf("runUndo() error: %v", err)
+	}
+	if err := runUndo(&cobra.Command{}, []string{}); err != nil {
+		t.Fatalf("runUndo() error: %v", err)
+	}
+	if _, err := os.Stat(target); !os.IsNotExist(err) {
+		t.Error("undo should remove the restored project")
+	}
+	if _, ok := findEntry(filepath.Join(dir, "projects.json"), projectDir); !ok {
+		t.Error("undo should point the index entry back to the original path")
+	}
+}
+
+func TestRunRestoreTargetExists(t *testing.T) {
+	dir := t.TempDir()
+	projectDir := archiveTestProject(t, dir)
+	setRestoreTo(t, "")
+	if err := os.Mkdir(projectDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	err := runRestore(&cobra.Command{}, []string{"myproject"})
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != ExitProjectExists {
+		t.Fatalf("runRestore() error = %v, want ExitProjectExists", err)
+	}
+}
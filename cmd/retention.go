@@ -0,0 +1,316 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fwartner/prjct/internal/archive"
+	"github.com/fwartner/prjct/internal/config"
+	"github.com/fwartner/prjct/internal/index"
+	"github.com/fwartner/prjct/internal/journal"
+	"github.com/spf13/cobra"
+)
+
+var retentionTemplate string
+
+var retentionCmd = &cobra.Command{
+	Use:   "retention",
+	Short: "Apply template retention policies",
+	Long: `Templates can declare retention rules that archive or flag projects
+whose files have not been modified for a number of days:
+
+  retention:
+    - status: delivered
+      inactive_days: 90
+      destination: /mnt/cold
+      delete: true
+    - inactive_days: 180
+      action: flag
+      tag: stale
+
+The first matching rule applies. Inactivity is measured from the newest file
+modification time in the project, as shown by "prjct info".`,
+}
+
+var retentionRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Archive or flag inactive projects",
+	Long: `Evaluates the retention rules of every template against the indexed
+projects and applies the matching actions. Archives go through the same code
+path as "prjct archive": they are verified before an original is deleted and
+every action is recorded in the journal. Use --dry-run to see the report
+without changing anything.`,
+	Args: cobra.NoArgs,
+	RunE: runRetentionRun,
+}
+
+// Retention results.
+const (
+	retentionPlanned = "planned"
+	retentionDone    = "done"
+	retentionFailed  = "failed"
+)
+
+// retentionActionView is one retention action in the report.
+type retentionActionView struct {
+	Name         string `json:"name" yaml:"name"`
+	Path         string `json:"path" yaml:"path"`
+	Template     string `json:"template" yaml:"template"`
+	Status       string `json:"status,omitempty" yaml:"status,omitempty"`
+	InactiveDays int    `json:"inactive_days" yaml:"inactive_days"`
+	Action       string `json:"action" yaml:"action"`
+	Target       string `json:"target" yaml:"target"`
+	Result       string `json:"result" yaml:"result"`
+	Error        string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+func init() {
+	retentionCmd.AddCommand(retentionRunCmd)
+	retentionRunCmd.Flags().StringVarP(&retentionTemplate, "template", "t", "", "only apply the rules of this template")
+}
+
+func runRetentionRun(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	idxPath, err := resolveIndexPath()
+	if err != nil {
+		return err
+	}
+	if retentionTemplate != "" && cfg.FindTemplate(retentionTemplate) == nil {
+		return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("template %q not found", retentionTemplate)}
+	}
+
+	actions, err := applyRetention(cfg, idxPath, retentionTemplate, !dryRun, !isStructuredOutput())
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, a := range actions {
+		if a.Result == retentionFailed {
+			failed++
+		}
+	}
+	var exitErr *ExitError
+	if failed > 0 {
+		exitErr = &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("%d retention action(s) failed", failed)}
+	}
+
+	if isStructuredOutput() {
+		if exitErr != nil {
+			return writeOutputError("retention", actions, exitErr)
+		}
+		return writeOutput("retention", actions)
+	}
+
+	if len(actions) == 0 {
+		fmt.Println("No projects due for retention.")
+		return nil
+	}
+	printRetentionReport(actions)
+	if dryRun {
+		fmt.Printf("Dry run — %d action(s) planned\n", len(actions))
+	} else {
+		fmt.Printf("Applied %d of %d action(s)\n", len(actions)-failed, len(actions))
+	}
+	if exitErr != nil {
+		return exitErr
+	}
+	return nil
+}
+
+// applyRetention evaluates the retention rules against all indexed projects
+// and, if apply is set, carries out the matching actions. Failed actions are
+// reported in the result rather than stopping the run.
+func applyRetention(cfg *config.Config, idxPath, templateID string, apply, progress bool) ([]retentionActionView, error) {
+	idx, err := index.Load(idxPath)
+	if err != nil {
+		return nil, &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("cannot load index: %v", err)}
+	}
+
+	wf := cfg.ResolveWorkflow()
+	rules := make(map[string][]config.RetentionRule)
+	actions := []retentionActionView{}
+	for _, entry := range idx.Projects {
+		if templateID != "" && entry.TemplateID != templateID {
+			continue
+		}
+		templateRules, ok := rules[entry.TemplateID]
+		if !ok {
+			if tmpl, err := cfg.ResolveTemplate(entry.TemplateID); err == nil {
+				templateRules = tmpl.Retention
+			}
+			rules[entry.TemplateID] = templateRules
+		}
+		if len(templateRules) == 0 {
+			continue
+		}
+
+		status := entry.Status
+		if status == "" {
+			status = wf.InitialStatus()
+		}
+		if status == "archived" {
+			continue
+		}
+
+		view := buildInfo(entry)
+		if !view.Accessible {
+			continue
+		}
+		lastMod := entry.CreatedAt
+		if view.ModifiedAt != nil {
+			lastMod = *view.ModifiedAt
+		}
+		idle := int(time.Since(lastMod).Hours() / 24)
+
+		rule := matchRetention(templateRules, status, idle)
+		if rule == nil {
+			continue
+		}
+		action, ok := planRetention(*rule, entry, status, idle)
+		if !ok {
+			continue
+		}
+		if apply {
+			if err := runRetentionAction(idxPath, *rule, entry, &action, progress); err != nil {
+				action.Result = retentionFailed
+				action.Error = err.Error()
+			} else {
+				action.Result = retentionDone
+			}
+		}
+		actions = append(actions, action)
+	}
+	return actions, nil
+}
+
+// matchRetention returns the first rule that applies to a project in status
+// that has been inactive for idle days.
+func matchRetention(rules []config.RetentionRule, status string, idle int) *config.RetentionRule {
+	for i, r := range rules {
+		if r.Status != "" && r.Status != status {
+			continue
+		}
+		if idle >= r.InactiveDays {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+// planRetention describes the action rule takes on entry. It reports false
+// if there is nothing to do, e.g. the project already carries the tag.
+func planRetention(rule config.RetentionRule, entry index.Entry, status string, idle int) (retentionActionView, bool) {
+	action := retentionActionView{
+		Name:         entry.Name,
+		Path:         entry.Path,
+		Template:     entry.TemplateID,
+		Status:       status,
+		InactiveDays: idle,
+		Result:       retentionPlanned,
+	}
+	if rule.Action == config.RetentionFlag {
+		tag := rule.Tag
+		if tag == "" {
+			tag = config.DefaultRetentionTag
+		}
+		if entry.HasTag(tag) {
+			return action, false
+		}
+		action.Action = config.RetentionFlag
+		action.Target = index.NormalizeTag(tag)
+		return action, true
+	}
+
+	action.Action = config.RetentionArchive
+	if rule.Delete {
+		action.Action += " + delete"
+	}
+	action.Target = rule.Destination
+	if action.Target == "" {
+		action.Target = filepath.Dir(entry.Path)
+	}
+	return action, true
+}
+
+// runRetentionAction carries out a planned action and records the archive
+// path in action.Target.
+func runRetentionAction(idxPath string, rule config.RetentionRule, entry index.Entry, action *retentionActionView, progress bool) error {
+	if rule.Action == config.RetentionFlag {
+		err := index.Update(idxPath, entry.Path, func(e *index.Entry) {
+			e.AddTags(action.Target)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to save tags: %w", err)
+		}
+		recordJournal(journal.Record{
+			Operation: journal.OpTag,
+			Details: map[string]string{
+				"path":  entry.Path,
+				"added": action.Target,
+			},
+		})
+		return nil
+	}
+
+	opts, err := templateArchiveOptions(entry)
+	if err != nil {
+		return err
+	}
+	dest := filepath.Dir(entry.Path)
+	if rule.Destination != "" {
+		if dest, err = config.ExpandPath(rule.Destination); err != nil {
+			return err
+		}
+		if err := os.MkdirAll(dest, 0755); err != nil {
+			return fmt.Errorf("cannot create destination: %w", err)
+		}
+	}
+	output, err := archiveProject(idxPath, archiveJob{
+		entry:          entry,
+		output:         freeArchivePath(dest, filepath.Base(entry.Path), opts.Format.Ext()),
+		opts:           opts,
+		delete:         rule.Delete,
+		deleteFiltered: rule.DeleteFiltered,
+		progress:       progress,
+		silent:         true,
+	})
+	if output != "" {
+		action.Target = output
+	}
+	return err
+}
+
+// freeArchivePath returns <dir>/<name><ext>, or the first free name with a
+// " (n)" suffix if an archive of that name exists, so that projects with
+// the same name, or a project archived twice, never overwrite an archive.
+func freeArchivePath(dir, name, ext string) string {
+	candidate := filepath.Join(dir, name+ext)
+	for n := 2; archive.Exists(candidate); n++ {
+		candidate = filepath.Join(dir, fmt.Sprintf("%s (%d)%s", name, n, ext))
+	}
+	return candidate
+}
+
+// printRetentionReport prints the actions as a table.
+func printRetentionReport(actions []retentionActionView) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintf(w, "  PROJECT\tTEMPLATE\tSTATUS\tIDLE\tACTION\tTARGET\tRESULT\n")
+	fmt.Fprintf(w, "  -------\t--------\t------\t----\t------\t------\t------\n")
+	for _, a := range actions {
+		result := a.Result
+		if a.Error != "" {
+			result += ": " + a.Error
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			a.Name, a.Template, a.Status, strconv.Itoa(a.InactiveDays)+"d", a.Action, a.Target, result)
+	}
+	w.Flush()
+}
@@ -0,0 +1,303 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fwartner/prjct/internal/archive"
+	"github.com/fwartner/prjct/internal/config"
+	"github.com/fwartner/prjct/internal/index"
+	"github.com/fwartner/prjct/internal/journal"
+	"github.com/spf13/cobra"
+)
+
+// retentionTestSetup creates an old delivered project, a recent delivered
+// project and an old active project, with rules archiving delivered projects
+// after 90 days and flagging anything else after 30.
+func retentionTestSetup(t *testing.T) (dir string, projects map[string]string) {
+	t.Helper()
+	dir = t.TempDir()
+	base := filepath.Join(dir, "projects")
+	cold := filepath.Join(dir, "cold")
+	old := time.Now().AddDate(0, 0, -120)
+
+	projects = map[string]string{}
+	var entries []index.Entry
+	for _, p := range []struct {
+		name, status string
+		modified     time.Time
+	}{
+		{"old-delivered", "delivered", old},
+		{"new-delivered", "delivered", time.Now()},
+		{"old-active", "active", old},
+	} {
+		path := filepath.Join(base, p.name)
+		file := filepath.Join(path, "edit.prproj")
+		if err := os.MkdirAll(path, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(p.name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(file, p.modified, p.modified); err != nil {
+			t.Fatal(err)
+		}
+		projects[p.name] = path
+		entries = append(entries, index.Entry{Name: p.name, TemplateID: "video", Path: path, Status: p.status, CreatedAt: old})
+	}
+
+	cfgPath := filepath.Join(dir, "config.yaml")
+	cfg := "templates:\n  - id: video\n    name: Video\n    base_path: " + base +
+		"\n    retention:\n      - status: delivered\n        inactive_days: 90\n        destination: " + cold +
+		"\n        delete: true\n      - inactive_days: 30\n        action: flag\n    directories:\n      - name: src\n"
+	if err := os.WriteFile(cfgPath, []byte(cfg), 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeTestIndex(t, dir, entries)
+	setConfigPath(t, cfgPath)
+	return dir, projects
+}
+
+func TestMatchRetention(t *testing.T) {
+	rules := []config.RetentionRule{
+		{Status: "delivered", InactiveDays: 90},
+		{InactiveDays: 30, Action: "flag"},
+	}
+	tests := []struct {
+		status string
+		idle   int
+		want   int // index of the rule, -1 for none
+	}{
+		{"delivered", 120, 0},
+		{"delivered", 60, 1},
+		{"active", 120, 1},
+		{"active", 10, -1},
+	}
+	for _, tt := range tests {
+		got := matchRetention(rules, tt.status, tt.idle)
+		switch {
+		case tt.want < 0 && got != nil:
+			t.Errorf("matchRetention(%s, %d) = %+v, want none", tt.status, tt.idle, got)
+		case tt.want >= 0 && got != &rules[tt.want]:
+			t.Errorf("matchRetention(%s, %d) = %+v, want rule %d", tt.status, tt.idle, got, tt.want)
+		}
+	}
+}
+
+func TestRunRetentionDryRun(t *testing.T) {
+	dir, projects := retentionTestSetup(t)
+	setDryRun(t, true)
+	buf := setOutputFormat(t, OutputJSON)
+
+	if err := runRetentionRun(&cobra.Command{}, nil); err != nil {
+		t.Fatalf("runRetentionRun() error: %v", err)
+	}
+	var env struct {
+		Data []retentionActionView `json:"data"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &env); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, buf.String())
+	}
+	if len(env.Data) != 2 {
+		t.Fatalf("got %d actions, want 2: %+v", len(env.Data), env.Data)
+	}
+	for _, a := range env.Data {
+		if a.Result != retentionPlanned {
+			t.Errorf("%s: result = %s, want planned", a.Name, a.Result)
+		}
+	}
+	for _, p := range projects {
+		if _, err := os.Stat(p); err != nil {
+			t.Errorf("dry run touched %s: %v", p, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "cold")); !os.IsNotExist(err) {
+		t.Error("dry run created the destination")
+	}
+}
+
+func TestRunRetentionApplies(t *testing.T) {
+	dir, projects := retentionTestSetup(t)
+	setDryRun(t, false)
+	setOutputFormat(t, OutputJSON)
+
+	if err := runRetentionRun(&cobra.Command{}, nil); err != nil {
+		t.Fatalf("runRetentionRun() error: %v", err)
+	}
+
+	archivePath := filepath.Join(dir, "cold", "old-delivered.tar.gz")
+	if _, err := os.Stat(archivePath); err != nil {
+		t.Fatalf("archive not written to destination: %v", err)
+	}
+	if _, err := os.Stat(projects["old-delivered"]); !os.IsNotExist(err) {
+		t.Error("original should be deleted after verification")
+	}
+	if _, err := os.Stat(projects["new-delivered"]); err != nil {
+		t.Errorf("recent project touched: %v", err)
+	}
+
+	idx, err := index.Load(filepath.Join(dir, "projects.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range idx.Projects {
+		switch e.Name {
+		case "old-delivered":
+			if e.Status != "archived" {
+				t.Errorf("status = %q, want archived", e.Status)
+			}
+		case "old-active":
+			if !e.HasTag("stale") {
+				t.Errorf("tags = %v, want stale", e.Tags)
+			}
+		case "new-delivered":
+			if len(e.Tags) != 0 || e.Status != "delivered" {
+				t.Errorf("recent project changed: %+v", e)
+			}
+		}
+	}
+
+	records, err := journal.Search(filepath.Join(dir, "journal.json"), journal.Query{Op: journal.OpArchive})
+	if err != nil || len(records) != 1 || records[0].Details["archive"] != archivePath {
+		t.Errorf("archive journal records = %+v, %v", records, err)
+	}
+
+	// A second run has nothing left to do
+	buf := setOutputFormat(t, OutputJSON)
+	if err := runRetentionRun(&cobra.Command{}, nil); err != nil {
+		t.Fatalf("second run error: %v", err)
+	}
+	var env struct {
+		Data []retentionActionView `json:"data"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &env); err != nil || len(env.Data) != 0 {
+		t.Errorf("second run actions = %+v, %v", env.Data, err)
+	}
+}
+
+func TestRunRetentionRefusesDeleteWithExclude(t *testing.T) {
+	dir, projects := retentionTestSetup(t)
+	setDryRun(t, false)
+	setOutputFormat(t, OutputJSON)
+
+	// Add an archive exclude list to the template of the fixture
+	cfgPath := filepath.Join(dir, "config.yaml")
+	data, err := os.ReadFile(cfgPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := strings.Replace(string(data), "    retention:\n", "    archive:\n      exclude: ['*.cache']\n    retention:\n", 1)
+	if err := os.WriteFile(cfgPath, []byte(cfg), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runRetentionRun(&cobra.Command{}, nil); err == nil {
+		t.Fatal("expected an error for delete with template excludes")
+	}
+	if _, err := os.Stat(projects["old-delivered"]); err != nil {
+		t.Errorf("original should be kept: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "cold", "old-delivered.tar.gz")); !os.IsNotExist(err) {
+		t.Error("no archive should be written when delete is refused")
+	}
+}
+
+func TestRunRetentionSameNameProjects(t *testing.T) {
+	dir := t.TempDir()
+	cold := filepath.Join(dir, "cold")
+	old := time.Now().AddDate(0, 0, -120)
+
+	var entries []index.Entry
+	var paths []string
+	for _, year := range []string{"2024", "2025"} {
+		path := filepath.Join(dir, "projects", year, "client-a")
+		file := filepath.Join(path, "edit.prproj")
+		if err := os.MkdirAll(path, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(year), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(file, old, old); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+		entries = append(entries, index.Entry{Name: "client-a " + year, TemplateID: "video", Path: path, Status: "delivered", CreatedAt: old})
+	}
+
+	cfgPath := filepath.Join(dir, "config.yaml")
+	cfg := "templates:\n  - id: video\n    name: Video\n    base_path: " + filepath.Join(dir, "projects") +
+		"\n    retention:\n      - inactive_days: 90\n        destination: " + cold +
+		"\n        delete: true\n    directories:\n      - name: src\n"
+	if err := os.WriteFile(cfgPath, []byte(cfg), 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeTestIndex(t, dir, entries)
+	setConfigPath(t, cfgPath)
+	setDryRun(t, false)
+	setOutputFormat(t, OutputJSON)
+
+	if err := runRetentionRun(&cobra.Command{}, nil); err != nil {
+		t.Fatalf("runRetentionRun() error: %v", err)
+	}
+
+	records, err := journal.Search(filepath.Join(dir, "journal.json"), journal.Query{Op: journal.OpArchive})
+	if err != nil || len(records) != 2 {
+		t.Fatalf("archive journal records = %+v, %v", records, err)
+	}
+	want := map[string]string{
+		filepath.Join(cold, "client-a.tar.gz"):     "",
+		filepath.Join(cold, "client-a (2).tar.gz"): "",
+	}
+	for _, r := range records {
+		if _, ok := want[r.Details["archive"]]; !ok {
+			t.Errorf("unexpected archive %s", r.Details["archive"])
+		}
+		want[r.Details["archive"]] = r.Details["path"]
+	}
+	// Both archives still hold their own project
+	for archivePath, projectPath := range want {
+		if projectPath == "" {
+			t.Errorf("%s was not written", archivePath)
+			continue
+		}
+		year := filepath.Base(filepath.Dir(projectPath))
+		target := filepath.Join(dir, "restored", year, "client-a")
+		if _, err := restoreArchive(archivePath, target); err != nil {
+			t.Fatalf("restore %s: %v", archivePath, err)
+		}
+		data, err := os.ReadFile(filepath.Join(target, "edit.prproj"))
+		if err != nil || string(data) != year {
+			t.Errorf("%s holds %q, want the project from %s", archivePath, data, projectPath)
+		}
+	}
+}
+
+func TestRunRetentionIgnoresArchiveFlags(t *testing.T) {
+	dir, _ := retentionTestSetup(t)
+	setDryRun(t, false)
+	setOutputFormat(t, OutputJSON)
+
+	// Flags left over from an archive command in the same process
+	oldFormat, oldLevel, oldOnly, oldSplit := archiveFormat, archiveLevel, archiveOnly, archiveSplit
+	archiveFormat, archiveLevel, archiveOnly, archiveSplit = "zip", 9, []string{"*.mov"}, "1k"
+	t.Cleanup(func() {
+		archiveFormat, archiveLevel, archiveOnly, archiveSplit = oldFormat, oldLevel, oldOnly, oldSplit
+	})
+
+	if err := runRetentionRun(&cobra.Command{}, nil); err != nil {
+		t.Fatalf("runRetentionRun() error: %v", err)
+	}
+	archivePath := filepath.Join(dir, "cold", "old-delivered.tar.gz")
+	m, _, err := archive.VerifyArchive(archivePath, nil)
+	if err != nil {
+		t.Fatalf("template format not used: %v", err)
+	}
+	if len(m.Files) != 1 || len(m.Volumes) != 0 {
+		t.Errorf("manifest = %+v, want one file in one unsplit archive", m)
+	}
+}
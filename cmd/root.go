@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -18,7 +19,9 @@ import (
 	"github.com/spf13/cobra"
 )
 
-// Exit codes for deterministic automation.
+// Exit codes for deterministic automation. Every code is distinct and
+// below 126, which shells reserve; 1-9 report errors, 10 and up report
+// checks that ran but failed. Codes never change once released.
 const (
 	ExitOK               = 0
 	ExitGeneral          = 1
@@ -30,6 +33,7 @@ const (
 	ExitCreateFailed     = 7
 	ExitInvalidName      = 8
 	ExitUserCancelled    = 9
+	ExitAuditFailed      = 10
 )
 
 // ExitError wraps an error with a specific exit code.
@@ -37,6 +41,9 @@ type ExitError struct {
 	Code    int
 	Message string
 	Err     error
+
+	// reported is set once the error has been written as structured output.
+	reported bool
 }
 
 func (e *ExitError) Error() string { return e.Message }
@@ -47,6 +54,9 @@ var (
 	configPath string
 	dryRun     bool
 	profile    string
+	createMeta []string
+	createTags []string
+	createSkip []string
 )
 
 var rootCmd = &cobra.Command{
@@ -55,10 +65,11 @@ var rootCmd = &cobra.Command{
 	Long: `prjct is a cross-platform CLI that creates predefined directory
 structures from YAML-configured templates. Provide a template and
 project name as arguments, or run interactively.`,
-	SilenceUsage:  true,
-	SilenceErrors: true,
-	Args:          cobra.MaximumNArgs(2),
-	RunE:          runRoot,
+	SilenceUsage:      true,
+	SilenceErrors:     true,
+	Args:              cobra.MaximumNArgs(2),
+	PersistentPreRunE: preRun,
+	RunE:              runRoot,
 }
 
 func init() {
@@ -66,6 +77,11 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&configPath, "config", "", "config file path (overrides default)")
 	rootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "preview changes without creating anything")
 	rootCmd.PersistentFlags().StringVar(&profile, "profile", "", "config profile name (loads config.<profile>.yaml)")
+	rootCmd.PersistentFlags().StringVar(&outputFormat, "output", OutputTable, "output format: table, json, yaml, or ndjson")
+	rootCmd.Flags().StringArrayVar(&createMeta, "meta", nil, "set a metadata field on the new project (key=value, repeatable)")
+	rootCmd.Flags().StringArrayVar(&createTags, "tag", nil, "tag the new project (repeatable)")
+	rootCmd.Flags().StringArrayVar(&createSkip, "skip-optional", nil, "do not create an optional directory (repeatable)")
+	_ = rootCmd.RegisterFlagCompletionFunc("tag", completeTags)
 
 	rootCmd.AddCommand(listCmd)
 	rootCmd.AddCommand(configCmd)
@@ -93,22 +109,52 @@ func init() {
 	rootCmd.AddCommand(validateCmd)
 	rootCmd.AddCommand(bulkCmd)
 	rootCmd.AddCommand(undoCmd)
+	rootCmd.AddCommand(redoCmd)
+	rootCmd.AddCommand(logCmd)
+	rootCmd.AddCommand(restoreCmd)
+	rootCmd.AddCommand(retentionCmd)
+	rootCmd.AddCommand(migrateCmd)
+	rootCmd.AddCommand(auditCmd)
+	rootCmd.AddCommand(eachCmd)
+	rootCmd.AddCommand(tidyCmd)
 	rootCmd.AddCommand(readmeCmd)
 	rootCmd.AddCommand(watchCmd)
+	rootCmd.AddCommand(statusCmd)
+	rootCmd.AddCommand(metaCmd)
+	rootCmd.AddCommand(tagCmd)
+}
+
+// preRun runs before every command: it validates --output and starts a new
+// journal group so all records of this invocation share an operation ID.
+func preRun(cmd *cobra.Command, args []string) error {
+	beginInvocation()
+	return validateOutputFormat(cmd, args)
 }
 
 // Execute runs the root command and returns an exit code.
 func Execute() int {
-	if err := rootCmd.Execute(); err != nil {
-		var exitErr *ExitError
-		if errors.As(err, &exitErr) {
-			fmt.Fprintln(os.Stderr, "Error:", exitErr.Message)
+	cmd, err := rootCmd.ExecuteC()
+	if err == nil {
+		return ExitOK
+	}
+
+	code := ExitGeneral
+	message := err.Error()
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		if exitErr.reported {
 			return exitErr.Code
 		}
-		fmt.Fprintln(os.Stderr, "Error:", err)
-		return ExitGeneral
+		code = exitErr.Code
+		message = exitErr.Message
+	}
+
+	if isStructuredOutput() {
+		_ = encodeOutput(outputStdout, commandName(cmd), nil, &outputError{Code: code, Message: message})
+		return code
 	}
-	return ExitOK
+	fmt.Fprintln(os.Stderr, "Error:", message)
+	return code
 }
 
 func runRoot(cmd *cobra.Command, args []string) error {
@@ -119,11 +165,14 @@ func runRoot(cmd *cobra.Command, args []string) error {
 
 	var tmpl *config.Template
 	var projectName string
+	var scanner *bufio.Scanner
 
 	switch len(args) {
 	case 0:
-		// Interactive mode
-		tmpl, projectName, err = interactive(cfg)
+		// Interactive mode — a single scanner is shared by all prompts so
+		// buffered input is not lost between them
+		scanner = bufio.NewScanner(os.Stdin)
+		tmpl, projectName, err = interactive(cfg, scanner)
 		if err != nil {
 			return err
 		}
@@ -155,31 +204,27 @@ func runRoot(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	providedMeta, err := parseKeyValues(createMeta)
+	if err != nil {
+		return err
+	}
+	tags, err := parseTags(createTags)
+	if err != nil {
+		return err
+	}
+
+	skip, err := parseSkipOptional(tmpl, createSkip)
+	if err != nil {
+		return err
+	}
+
 	// Build variables
 	vars := tmplpkg.BuiltinVars(sanitized, time.Now())
 
-	// Prompt for custom variables in interactive mode
-	if len(args) == 0 && len(tmpl.Variables) > 0 {
-		scanner := bufio.NewScanner(os.Stdin)
+	// Prompt for custom variables and metadata in interactive mode
+	if scanner != nil {
 		for _, v := range tmpl.Variables {
-			prompt := v.Prompt
-			if prompt == "" {
-				prompt = v.Name
-			}
-			if v.Default != "" {
-				fmt.Printf("%s [%s]: ", prompt, v.Default)
-			} else {
-				fmt.Printf("%s: ", prompt)
-			}
-			if scanner.Scan() {
-				val := strings.TrimSpace(scanner.Text())
-				if val == "" {
-					val = v.Default
-				}
-				vars[v.Name] = val
-			} else {
-				vars[v.Name] = v.Default
-			}
+			vars[v.Name], _ = promptValue(scanner, v.Prompt, v.Name, v.Default)
 		}
 	} else {
 		// Non-interactive: use defaults
@@ -188,11 +233,17 @@ func runRoot(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	meta, err := buildMetadata(tmpl, vars, providedMeta, scanner)
+	if err != nil {
+		return err
+	}
+
 	// Create directory structure
 	opts := project.CreateOptions{
-		Verbose:   verbose,
-		DryRun:    dryRun,
-		Variables: vars,
+		Verbose:      verbose,
+		DryRun:       dryRun,
+		Variables:    vars,
+		SkipOptional: skip,
 	}
 	result, err := project.Create(tmpl, sanitized, opts)
 	if err != nil {
@@ -202,26 +253,21 @@ func runRoot(cmd *cobra.Command, args []string) error {
 	// Best-effort index update — don't fail the command if indexing fails
 	if !dryRun {
 		if idxPath, idxErr := resolveIndexPath(); idxErr == nil {
-			_ = index.Add(idxPath, index.Entry{
-				Name:         sanitized,
-				TemplateID:   tmpl.ID,
-				TemplateName: tmpl.Name,
-				Path:         result.ProjectPath,
-				CreatedAt:    time.Now(),
-			})
-		}
-		// Best-effort journal recording
-		if jPath, jErr := resolveJournalPath(); jErr == nil {
-			_ = journal.Append(jPath, journal.Record{
-				Timestamp: time.Now(),
-				Operation: journal.OpCreate,
-				Details: map[string]string{
-					"path":     result.ProjectPath,
-					"template": tmpl.ID,
-					"name":     sanitized,
-				},
-			})
+			_ = index.Add(idxPath, newIndexEntry(cfg, tmpl, sanitized, result.ProjectPath, meta, tags, opts))
 		}
+		recordJournal(journal.Record{
+			Operation: journal.OpCreate,
+			Details: map[string]string{
+				"path":     result.ProjectPath,
+				"template": tmpl.ID,
+				"name":     sanitized,
+			},
+			Created: []string{result.ProjectPath},
+		})
+	}
+
+	if isStructuredOutput() {
+		return writeOutput("create", newCreateView(tmpl, sanitized, result))
 	}
 
 	if dryRun {
@@ -240,9 +286,103 @@ func runRoot(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func interactive(cfg *config.Config) (*config.Template, string, error) {
-	scanner := bufio.NewScanner(os.Stdin)
+// createView is the machine-readable result of project creation. In dry-run
+// mode Directories and Files hold the full planned tree.
+type createView struct {
+	DryRun       bool     `json:"dry_run" yaml:"dry_run"`
+	Name         string   `json:"name" yaml:"name"`
+	TemplateID   string   `json:"template_id" yaml:"template_id"`
+	TemplateName string   `json:"template_name" yaml:"template_name"`
+	Path         string   `json:"path" yaml:"path"`
+	DirsCreated  int      `json:"dirs_created" yaml:"dirs_created"`
+	FilesCreated int      `json:"files_created" yaml:"files_created"`
+	Directories  []string `json:"directories" yaml:"directories"`
+	Files        []string `json:"files" yaml:"files"`
+}
+
+func newCreateView(tmpl *config.Template, name string, result *project.Result) createView {
+	return createView{
+		DryRun:       dryRun,
+		Name:         name,
+		TemplateID:   tmpl.ID,
+		TemplateName: result.TemplateName,
+		Path:         result.ProjectPath,
+		DirsCreated:  result.DirsCreated,
+		FilesCreated: result.FilesCreated,
+		Directories:  nonNil(result.Dirs),
+		Files:        nonNil(result.Files),
+	}
+}
+
+// newIndexEntry builds the index entry for a freshly created project,
+// starting it in the workflow's initial status. The variables and skipped
+// optional directories of opts are stored so the template can be resolved
+// the same way later, along with the hash of every generated file.
+func newIndexEntry(cfg *config.Config, tmpl *config.Template, name, path string, meta map[string]string, tags []string, opts project.CreateOptions) index.Entry {
+	now := time.Now()
+	e := index.Entry{
+		Name:         name,
+		TemplateID:   tmpl.ID,
+		TemplateName: tmpl.Name,
+		Path:         path,
+		CreatedAt:    now,
+		Metadata:     meta,
+	}
+	if tmpl.Version > 0 {
+		e.TemplateVersion = tmpl.Version
+	}
+	if len(opts.Variables) > 0 {
+		e.Variables = make(map[string]string, len(opts.Variables))
+		for k, v := range opts.Variables {
+			if k != "path" {
+				e.Variables[k] = v
+			}
+		}
+	}
+	for d := range opts.SkipOptional {
+		e.SkippedOptional = append(e.SkippedOptional, d)
+	}
+	sort.Strings(e.SkippedOptional)
+	if _, files := project.Layout(tmpl.Directories, opts); len(files) > 0 {
+		e.FileHashes = make(map[string]string, len(files))
+		for _, f := range files {
+			e.FileHashes[f.Path] = project.HashContent([]byte(f.Content))
+		}
+	}
+	e.AddTags(tags...)
+	e.SetStatus(cfg.ResolveWorkflow().InitialStatus(), now)
+	return e
+}
+
+// parseSkipOptional validates --skip-optional names against the optional
+// directories of tmpl.
+func parseSkipOptional(tmpl *config.Template, names []string) (map[string]bool, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	optional := make(map[string]bool)
+	var collect func(dirs []config.Directory)
+	collect = func(dirs []config.Directory) {
+		for _, d := range dirs {
+			if d.Optional {
+				optional[d.Name] = true
+			}
+			collect(d.Children)
+		}
+	}
+	collect(tmpl.Directories)
+
+	skip := make(map[string]bool, len(names))
+	for _, n := range names {
+		if !optional[n] {
+			return nil, &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("%q is not an optional directory of template %q", n, tmpl.ID)}
+		}
+		skip[n] = true
+	}
+	return skip, nil
+}
 
+func interactive(cfg *config.Config, scanner *bufio.Scanner) (*config.Template, string, error) {
 	// Display template menu
 	fmt.Println("Available templates:")
 	fmt.Println()
@@ -72,6 +72,22 @@ func setVerbose(t *testing.T, val bool) {
 	t.Cleanup(func() { verbose = old })
 }
 
+// setDryRun sets the package-level dryRun for the duration of the test.
+func setDryRun(t *testing.T, val bool) {
+	t.Helper()
+	old := dryRun
+	dryRun = val
+	t.Cleanup(func() { dryRun = old })
+}
+
+// withExecHook replaces project.ExecHook for the duration of the test.
+func withExecHook(t *testing.T, fn func(command, dir string) error) {
+	t.Helper()
+	old := project.ExecHook
+	project.ExecHook = fn
+	t.Cleanup(func() { project.ExecHook = old })
+}
+
 // withStdin replaces os.Stdin with a pipe containing the given input.
 func withStdin(t *testing.T, input string) {
 	t.Helper()
@@ -127,11 +143,17 @@ func TestExitErrorCodes(t *testing.T) {
 		"CreateFailed":     ExitCreateFailed,
 		"InvalidName":      ExitInvalidName,
 		"UserCancelled":    ExitUserCancelled,
+		"AuditFailed":      ExitAuditFailed,
 	}
+	seen := make(map[int]string)
 	for name, code := range codes {
-		if code < 0 || code > 9 {
-			t.Errorf("exit code %s = %d, want 0-9", name, code)
+		if code < 0 || code > 125 {
+			t.Errorf("exit code %s = %d, want 0-125", name, code)
+		}
+		if other, ok := seen[code]; ok {
+			t.Errorf("exit codes %s and %s are both %d", name, other, code)
 		}
+		seen[code] = name
 	}
 }
 
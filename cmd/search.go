@@ -12,6 +12,9 @@ import (
 
 var (
 	searchTemplate string
+	searchStatus   string
+	searchMeta     []string
+	searchTags     []string
 	searchFuzzy    bool
 )
 
@@ -20,15 +23,23 @@ var searchCmd = &cobra.Command{
 	Short: "Search indexed projects",
 	Long: `Search for previously created projects by name, template, or path.
 Run without a query to list all indexed projects.
-Use --template to filter by template ID.
-Use --fuzzy for approximate matching.`,
+Use --template to filter by template ID and --status to filter by status.
+Use --meta key=value to filter by metadata; the query also matches metadata values.
+Use --tag to filter by project tag; repeat it to require several tags.
+Use --fuzzy for approximate matching.
+Use --format for custom output, e.g. --format '{{.Name}} [{{.TemplateID}}] {{.Path}}'.`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: runSearch,
 }
 
 func init() {
 	searchCmd.Flags().StringVarP(&searchTemplate, "template", "t", "", "filter by template ID")
+	searchCmd.Flags().StringVar(&searchStatus, "status", "", "filter by lifecycle status")
+	searchCmd.Flags().StringArrayVar(&searchMeta, "meta", nil, "filter by metadata field (key=value, repeatable)")
+	searchCmd.Flags().StringArrayVar(&searchTags, "tag", nil, "filter by project tag (repeatable, all must match)")
+	_ = searchCmd.RegisterFlagCompletionFunc("tag", completeTags)
 	searchCmd.Flags().BoolVar(&searchFuzzy, "fuzzy", false, "enable fuzzy (approximate) matching")
+	addFormatFlag(searchCmd)
 }
 
 func runSearch(cmd *cobra.Command, args []string) error {
@@ -51,12 +62,32 @@ func runSearch(cmd *cobra.Command, args []string) error {
 	if searchFuzzy && query != "" {
 		results = index.FuzzySearch(idx, query, 2)
 	} else {
-		results = index.Search(idx, query)
+		results = index.SearchWithMeta(idx, query)
 	}
 
 	if searchTemplate != "" {
 		results = index.FilterByTemplate(results, searchTemplate)
 	}
+	if searchStatus != "" {
+		results = index.FilterByStatus(results, searchStatus)
+	}
+	metaFilter, err := parseKeyValues(searchMeta)
+	if err != nil {
+		return err
+	}
+	for k, v := range metaFilter {
+		results = index.FilterByMeta(results, k, v)
+	}
+	if len(searchTags) > 0 {
+		results = index.FilterByTags(results, searchTags)
+	}
+
+	if formatFlag != "" {
+		return writeFormatted(outputStdout, projectViews(results))
+	}
+	if isStructuredOutput() {
+		return writeOutput("search", projectViews(results))
+	}
 
 	if len(results) == 0 {
 		fmt.Println("Found 0 project(s)")
@@ -64,11 +95,11 @@ func runSearch(cmd *cobra.Command, args []string) error {
 	}
 
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
-	fmt.Fprintf(w, "  NAME\tTEMPLATE\tPATH\tCREATED\n")
-	fmt.Fprintf(w, "  ----\t--------\t----\t-------\n")
+	fmt.Fprintf(w, "  NAME\tTEMPLATE\tSTATUS\tPATH\tCREATED\n")
+	fmt.Fprintf(w, "  ----\t--------\t------\t----\t-------\n")
 	for _, e := range results {
 		created := e.CreatedAt.Format("2006-01-02 15:04")
-		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n", e.Name, e.TemplateID, e.Path, created)
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s\n", e.Name, e.TemplateID, statusLabel(e.Status), e.Path, created)
 	}
 	w.Flush()
 
@@ -76,6 +107,14 @@ func runSearch(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// statusLabel returns the status for table display.
+func statusLabel(status string) string {
+	if status == "" {
+		return "-"
+	}
+	return status
+}
+
 func resolveIndexPath() (string, error) {
 	// If --config is set, derive index path from its directory
 	if configPath != "" {
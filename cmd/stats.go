@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"sort"
 	"text/tabwriter"
 
 	"github.com/fwartner/prjct/internal/index"
@@ -12,10 +13,35 @@ import (
 var statsCmd = &cobra.Command{
 	Use:   "stats",
 	Short: "Show project statistics",
-	Long:  `Displays statistics about indexed projects grouped by template.`,
+	Long:  `Displays statistics about indexed projects grouped by template, status and tag.`,
 	RunE:  runStats,
 }
 
+// statsView is the machine-readable result of the stats command.
+type statsView struct {
+	Total     int                 `json:"total" yaml:"total"`
+	Templates []templateCountView `json:"templates" yaml:"templates"`
+	Statuses  []statusCountView   `json:"statuses" yaml:"statuses"`
+	Tags      []tagCountView      `json:"tags" yaml:"tags"`
+}
+
+// statusCountView holds the number of projects in one lifecycle status.
+type statusCountView struct {
+	Status string `json:"status" yaml:"status"`
+	Count  int    `json:"count" yaml:"count"`
+}
+
+// templateCountView holds the number of projects created from one template.
+type templateCountView struct {
+	ID    string `json:"id" yaml:"id"`
+	Name  string `json:"name" yaml:"name"`
+	Count int    `json:"count" yaml:"count"`
+}
+
+func init() {
+	addFormatFlag(statsCmd)
+}
+
 func runStats(cmd *cobra.Command, args []string) error {
 	idxPath, err := resolveIndexPath()
 	if err != nil {
@@ -27,34 +53,82 @@ func runStats(cmd *cobra.Command, args []string) error {
 		return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("cannot load index: %v", err)}
 	}
 
+	stats := buildStats(idx.Projects)
+
+	if formatFlag != "" {
+		return writeFormatted(outputStdout, stats)
+	}
+	if isStructuredOutput() {
+		return writeOutput("stats", stats)
+	}
+
 	if len(idx.Projects) == 0 {
 		fmt.Println("No projects indexed yet.")
 		return nil
 	}
 
-	// Count per template
-	counts := make(map[string]int)
-	names := make(map[string]string) // id → name
-	for _, e := range idx.Projects {
-		counts[e.TemplateID]++
-		if e.TemplateName != "" {
-			names[e.TemplateID] = e.TemplateName
-		}
-	}
-
-	fmt.Printf("Total projects: %d\n\n", len(idx.Projects))
+	fmt.Printf("Total projects: %d\n\n", stats.Total)
 
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
 	fmt.Fprintf(w, "  TEMPLATE\tNAME\tCOUNT\n")
 	fmt.Fprintf(w, "  --------\t----\t-----\n")
-	for id, count := range counts {
-		name := names[id]
+	for _, t := range stats.Templates {
+		name := t.Name
 		if name == "" {
 			name = "-"
 		}
-		fmt.Fprintf(w, "  %s\t%s\t%d\n", id, name, count)
+		fmt.Fprintf(w, "  %s\t%s\t%d\n", t.ID, name, t.Count)
 	}
 	w.Flush()
 
+	fmt.Println()
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintf(w, "  STATUS\tCOUNT\n")
+	fmt.Fprintf(w, "  ------\t-----\n")
+	for _, s := range stats.Statuses {
+		fmt.Fprintf(w, "  %s\t%d\n", statusLabel(s.Status), s.Count)
+	}
+	w.Flush()
+
+	if len(stats.Tags) > 0 {
+		fmt.Println()
+		w = tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		fmt.Fprintf(w, "  TAG\tCOUNT\n")
+		fmt.Fprintf(w, "  ---\t-----\n")
+		for _, t := range stats.Tags {
+			fmt.Fprintf(w, "  %s\t%d\n", t.Tag, t.Count)
+		}
+		w.Flush()
+	}
+
 	return nil
 }
+
+// buildStats counts projects per template, status and tag, each sorted by key.
+func buildStats(entries []index.Entry) statsView {
+	counts := make(map[string]int)
+	names := make(map[string]string) // id → name
+	statuses := make(map[string]int)
+	for _, e := range entries {
+		counts[e.TemplateID]++
+		if e.TemplateName != "" {
+			names[e.TemplateID] = e.TemplateName
+		}
+		statuses[e.Status]++
+	}
+
+	stats := statsView{Total: len(entries), Templates: []templateCountView{}, Statuses: []statusCountView{}, Tags: tagCounts(entries)}
+	for status, count := range statuses {
+		stats.Statuses = append(stats.Statuses, statusCountView{Status: status, Count: count})
+	}
+	sort.Slice(stats.Statuses, func(i, j int) bool {
+		return stats.Statuses[i].Status < stats.Statuses[j].Status
+	})
+	for id, count := range counts {
+		stats.Templates = append(stats.Templates, templateCountView{ID: id, Name: names[id], Count: count})
+	}
+	sort.Slice(stats.Templates, func(i, j int) bool {
+		return stats.Templates[i].ID < stats.Templates[j].ID
+	})
+	return stats
+}
@@ -0,0 +1,213 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fwartner/prjct/internal/config"
+	"github.com/fwartner/prjct/internal/index"
+	"github.com/fwartner/prjct/internal/journal"
+	"github.com/fwartner/prjct/internal/project"
+	tmplpkg "github.com/fwartner/prjct/internal/template"
+	"github.com/spf13/cobra"
+)
+
+var statusForce bool
+
+var statusCmd = &cobra.Command{
+	Use:   "status <query> [new-status]",
+	Short: "Show or change a project's lifecycle status",
+	Long: `Without a new status, shows the current status, its history and the
+allowed next statuses of the first matching project. With a new status,
+validates the transition against the configured workflow, records it
+in the status history and runs the status hook, if any.
+
+Use --force to skip the transition check.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: runStatus,
+}
+
+func init() {
+	statusCmd.Flags().BoolVar(&statusForce, "force", false, "allow transitions not defined in the workflow")
+}
+
+func runStatus(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	wf := cfg.ResolveWorkflow()
+
+	idxPath, err := resolveIndexPath()
+	if err != nil {
+		return err
+	}
+
+	idx, err := index.Load(idxPath)
+	if err != nil {
+		return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("cannot load index: %v", err)}
+	}
+
+	results := index.Search(idx, args[0])
+	if len(results) == 0 {
+		return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("no project matching %q", args[0])}
+	}
+
+	entry := results[0]
+	current := entry.Status
+	if current == "" {
+		current = wf.InitialStatus()
+	}
+
+	// View mode
+	if len(args) == 1 {
+		if isStructuredOutput() {
+			return writeOutput("status", newProjectView(entry))
+		}
+		fmt.Printf("%s: %s\n", entry.Name, current)
+		for _, h := range entry.StatusHistory {
+			from := h.From
+			if from == "" {
+				from = "-"
+			}
+			fmt.Printf("  %s  %s -> %s\n", h.At.Format("2006-01-02 15:04"), from, h.To)
+		}
+		if s := wf.FindStatus(current); s != nil && len(s.Transitions) > 0 {
+			fmt.Printf("Next: %s\n", strings.Join(s.Transitions, ", "))
+		}
+		return nil
+	}
+
+	newStatus := args[1]
+	target := wf.FindStatus(newStatus)
+	if target == nil {
+		names := make([]string, len(wf.Statuses))
+		for i, s := range wf.Statuses {
+			names[i] = s.Name
+		}
+		return &ExitError{
+			Code:    ExitGeneral,
+			Message: fmt.Sprintf("unknown status %q. Available: %s", newStatus, strings.Join(names, ", ")),
+		}
+	}
+	if newStatus == current {
+		fmt.Printf("%s is already %s\n", entry.Name, current)
+		return nil
+	}
+	if !statusForce && !wf.CanTransition(entry.Status, newStatus) {
+		return &ExitError{
+			Code:    ExitGeneral,
+			Message: fmt.Sprintf("transition %s -> %s not allowed (use --force to override)", current, newStatus),
+		}
+	}
+
+	if dryRun {
+		fmt.Printf("Dry run — would change %s: %s -> %s\n", entry.Name, current, newStatus)
+		return nil
+	}
+
+	changed, err := changeStatus(idxPath, entry, newStatus)
+	if err != nil {
+		return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("failed to update status: %v", err)}
+	}
+	if !changed {
+		fmt.Printf("%s is already %s\n", entry.Name, newStatus)
+		return nil
+	}
+
+	fmt.Printf("%s: %s -> %s\n", entry.Name, current, newStatus)
+
+	if err := runStatusHook(target, entry, current); err != nil {
+		return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("status changed but hook failed: %v", err)}
+	}
+	return nil
+}
+
+// changeStatus records a status transition for entry in the index and the
+// journal. Shared by every command that moves a project between statuses.
+// It reports whether the status changed; setting the current status again
+// is a no-op, so callers must not run the status hook then.
+func changeStatus(idxPath string, entry index.Entry, status string) (bool, error) {
+	if entry.Status == status {
+		return false, nil
+	}
+	now := time.Now()
+	if err := index.Update(idxPath, entry.Path, func(e *index.Entry) {
+		e.SetStatus(status, now)
+	}); err != nil {
+		return false, err
+	}
+
+	recordJournal(journal.Record{
+		Timestamp: now,
+		Operation: journal.OpStatus,
+		Details: map[string]string{
+			"path": entry.Path,
+			"from": entry.Status,
+			"to":   status,
+		},
+	})
+	return true, nil
+}
+
+// setWorkflowStatus moves entry into status on behalf of another command,
+// such as archive or restore, and runs the status hook if it changed. A
+// status the workflow does not define is left alone with a warning, as is
+// a transition the workflow does not allow when checkTransition is set.
+// Without a config the default workflow applies.
+func setWorkflowStatus(idxPath string, entry index.Entry, status string, checkTransition bool) {
+	wf := config.DefaultWorkflow()
+	if cfg, err := loadConfig(); err == nil {
+		wf = cfg.ResolveWorkflow()
+	}
+	target := wf.FindStatus(status)
+	if target == nil {
+		fmt.Fprintf(os.Stderr, "Warning: the workflow has no %q status; status of %s left unchanged\n", status, entry.Name)
+		return
+	}
+	if checkTransition && entry.Status != status && !wf.CanTransition(entry.Status, status) {
+		from := entry.Status
+		if from == "" {
+			from = wf.InitialStatus()
+		}
+		fmt.Fprintf(os.Stderr, "Warning: the workflow does not allow %s -> %s; status of %s left unchanged\n", from, status, entry.Name)
+		return
+	}
+
+	changed, err := changeStatus(idxPath, entry, status)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to update status: %v\n", err)
+		return
+	}
+	// A project already in status ran the hook back then
+	if changed {
+		if err := runStatusHook(target, entry, entry.Status); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: status hook failed: %v\n", err)
+		}
+	}
+}
+
+// runStatusHook executes the hook of the status a project just entered.
+// Hooks run in the project directory with {name}, {path}, {status} and
+// {from} resolved.
+func runStatusHook(s *config.Status, entry index.Entry, from string) error {
+	if s.Hook == "" {
+		return nil
+	}
+	if _, err := os.Stat(entry.Path); err != nil {
+		return fmt.Errorf("project directory not accessible: %w", err)
+	}
+	vars := map[string]string{
+		"name":   entry.Name,
+		"path":   entry.Path,
+		"status": s.Name,
+		"from":   from,
+	}
+	resolved := tmplpkg.Resolve(s.Hook, vars)
+	if verbose {
+		fmt.Fprintf(os.Stderr, "  hook: %s\n", resolved)
+	}
+	return project.ExecHook(resolved, entry.Path)
+}
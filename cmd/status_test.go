@@ -0,0 +1,220 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fwartner/prjct/internal/index"
+	"github.com/fwartner/prjct/internal/journal"
+	"github.com/spf13/cobra"
+)
+
+func setStatusForce(t *testing.T, val bool) {
+	t.Helper()
+	old := statusForce
+	statusForce = val
+	t.Cleanup(func() { statusForce = old })
+}
+
+func TestRunStatusTransition(t *testing.T) {
+	base := t.TempDir()
+	cfgPath := writeTestConfig(t, base)
+	setConfigPath(t, cfgPath)
+	setStatusForce(t, false)
+
+	projectDir := filepath.Join(base, "Proj")
+	_ = os.MkdirAll(projectDir, 0755)
+	idxPath := writeTestIndex(t, filepath.Dir(cfgPath), []index.Entry{
+		{Name: "Proj", TemplateID: "test", Path: projectDir, CreatedAt: time.Now(), Status: "active"},
+	})
+
+	if err := runStatus(&cobra.Command{}, []string{"Proj", "review"}); err != nil {
+		t.Fatalf("runStatus() error: %v", err)
+	}
+
+	idx, _ := index.Load(idxPath)
+	e := idx.Projects[0]
+	if e.Status != "review" {
+		t.Errorf("Status = %q, want review", e.Status)
+	}
+	if len(e.StatusHistory) != 1 || e.StatusHistory[0].From != "active" {
+		t.Errorf("StatusHistory = %+v", e.StatusHistory)
+	}
+
+	rec, _ := journal.Last(filepath.Join(filepath.Dir(cfgPath), "journal.json"))
+	if rec == nil || rec.Operation != journal.OpStatus || rec.Details["to"] != "review" {
+		t.Errorf("journal record = %+v, want status change", rec)
+	}
+}
+
+func TestRunStatusDisallowedTransition(t *testing.T) {
+	base := t.TempDir()
+	cfgPath := writeTestConfig(t, base)
+	setConfigPath(t, cfgPath)
+	setStatusForce(t, false)
+
+	writeTestIndex(t, filepath.Dir(cfgPath), []index.Entry{
+		{Name: "Proj", TemplateID: "test", Path: base, CreatedAt: time.Now(), Status: "planned"},
+	})
+
+	err := runStatus(&cobra.Command{}, []string{"Proj", "delivered"})
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatalf("expected ExitError, got %v", err)
+	}
+
+	// --force bypasses the workflow
+	setStatusForce(t, true)
+	if err := runStatus(&cobra.Command{}, []string{"Proj", "delivered"}); err != nil {
+		t.Fatalf("runStatus(--force) error: %v", err)
+	}
+}
+
+func TestRunStatusUnknownStatus(t *testing.T) {
+	base := t.TempDir()
+	cfgPath := writeTestConfig(t, base)
+	setConfigPath(t, cfgPath)
+
+	writeTestIndex(t, filepath.Dir(cfgPath), []index.Entry{
+		{Name: "Proj", TemplateID: "test", Path: base, CreatedAt: time.Now()},
+	})
+
+	if err := runStatus(&cobra.Command{}, []string{"Proj", "bogus"}); err == nil {
+		t.Fatal("expected error for unknown status")
+	}
+}
+
+func TestRunStatusHook(t *testing.T) {
+	base := t.TempDir()
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	content := `workflow:
+  statuses:
+    - name: active
+      transitions: [review]
+    - name: review
+      hook: "touch reviewed"
+templates:
+  - id: test
+    name: Test
+    base_path: ` + base + `
+    directories:
+      - name: src
+`
+	if err := os.WriteFile(cfgPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	setConfigPath(t, cfgPath)
+	setStatusForce(t, false)
+
+	var hookCmd, hookDir string
+	withExecHook(t, func(command, dir string) error {
+		hookCmd, hookDir = command, dir
+		return nil
+	})
+
+	writeTestIndex(t, filepath.Dir(cfgPath), []index.Entry{
+		{Name: "Proj", TemplateID: "test", Path: base, CreatedAt: time.Now()},
+	})
+
+	if err := runStatus(&cobra.Command{}, []string{"Proj", "review"}); err != nil {
+		t.Fatalf("runStatus() error: %v", err)
+	}
+	if hookCmd != "touch reviewed" || hookDir != base {
+		t.Errorf("hook = %q in %q", hookCmd, hookDir)
+	}
+}
+
+func TestArchiveAlreadyArchivedSkipsHook(t *testing.T) {
+	base := t.TempDir()
+	project := filepath.Join(base, "Proj")
+	_ = os.MkdirAll(filepath.Join(project, "src"), 0755)
+	_ = os.WriteFile(filepath.Join(project, "src", "a.txt"), []byte("a"), 0644)
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	content := `workflow:
+  statuses:
+    - name: active
+      transitions: [archived]
+    - name: archived
+      hook: "notify archived"
+templates:
+  - id: test
+    name: Test
+    base_path: ` + base + `
+    directories:
+      - name: src
+`
+	if err := os.WriteFile(cfgPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	setConfigPath(t, cfgPath)
+	setArchiveFlags(t, false, filepath.Join(t.TempDir(), "proj.tar.gz"))
+
+	hooks := 0
+	withExecHook(t, func(command, dir string) error {
+		hooks++
+		return nil
+	})
+	writeTestIndex(t, filepath.Dir(cfgPath), []index.Entry{
+		{Name: "Proj", TemplateID: "test", Path: project, Status: "archived", CreatedAt: time.Now()},
+	})
+
+	if err := runArchive(&cobra.Command{}, []string{"Proj"}); err != nil {
+		t.Fatalf("runArchive() error: %v", err)
+	}
+	if hooks != 0 {
+		t.Errorf("archived hook ran %d time(s) for an already archived project", hooks)
+	}
+	j, _ := journal.Load(filepath.Join(filepath.Dir(cfgPath), "journal.json"))
+	for _, r := range j.Records {
+		if r.Operation == journal.OpStatus {
+			t.Errorf("unexpected status record %+v", r)
+		}
+	}
+}
+
+func TestSetWorkflowStatus(t *testing.T) {
+	base := t.TempDir()
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	content := `workflow:
+  statuses:
+    - name: open
+      transitions: [archived]
+    - name: archived
+templates:
+  - id: test
+    name: Test
+    base_path: ` + base + `
+    directories:
+      - name: src
+`
+	if err := os.WriteFile(cfgPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	setConfigPath(t, cfgPath)
+	idxPath := writeTestIndex(t, filepath.Dir(cfgPath), []index.Entry{
+		{Name: "Proj", TemplateID: "test", Path: base, Status: "archived", CreatedAt: time.Now()},
+	})
+	status := func() string {
+		idx, _ := index.Load(idxPath)
+		return idx.Projects[0].Status
+	}
+	entry := index.Entry{Name: "Proj", Path: base, Status: "archived"}
+
+	// The workflow has no active status
+	setWorkflowStatus(idxPath, entry, "active", false)
+	if got := status(); got != "archived" {
+		t.Errorf("status = %q, want archived for an undefined status", got)
+	}
+	// archived -> open is not a transition of the workflow
+	setWorkflowStatus(idxPath, entry, "open", true)
+	if got := status(); got != "archived" {
+		t.Errorf("status = %q, want archived for a disallowed transition", got)
+	}
+	setWorkflowStatus(idxPath, entry, "open", false)
+	if got := status(); got != "open" {
+		t.Errorf("status = %q, want open without the transition check", got)
+	}
+}
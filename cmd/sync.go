@@ -1,25 +1,49 @@
 package cmd
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
-	"sort"
 
+	"github.com/fwartner/prjct/internal/config"
 	"github.com/fwartner/prjct/internal/index"
+	"github.com/fwartner/prjct/internal/journal"
 	"github.com/fwartner/prjct/internal/project"
+	tmplpkg "github.com/fwartner/prjct/internal/template"
 	"github.com/spf13/cobra"
 )
 
+var (
+	syncFilesOnly bool
+	syncDirsOnly  bool
+	syncUpdate    bool
+)
+
 var syncCmd = &cobra.Command{
 	Use:   "sync <query>",
 	Short: "Sync a project with its template",
 	Long: `Compares a project against its template and creates any missing
-directories. Uses the project index to find the template that was used.`,
+directories and files. Uses the project index to find the template that
+was used. Names and file contents are resolved with the variables the
+project was created with; conditional directories whose condition is not
+met and optional directories skipped at creation are left out.
+
+Existing files are never overwritten. With --update-unmodified, a file is
+replaced by the current template version if it still has exactly the
+content it was generated with. Use --dirs-only or --files to sync only
+directories or only files.`,
 	Args: cobra.ExactArgs(1),
 	RunE: runSync,
 }
 
+func init() {
+	syncCmd.Flags().BoolVar(&syncFilesOnly, "files", false, "only create missing files")
+	syncCmd.Flags().BoolVar(&syncDirsOnly, "dirs-only", false, "only create missing directories")
+	syncCmd.Flags().BoolVar(&syncUpdate, "update-unmodified", false, "update files that are unchanged since they were generated")
+	syncCmd.MarkFlagsMutuallyExclusive("files", "dirs-only")
+}
+
 func runSync(cmd *cobra.Command, args []string) error {
 	cfg, err := loadConfig()
 	if err != nil {
@@ -53,46 +77,30 @@ func runSync(cmd *cobra.Command, args []string) error {
 		return &ExitError{Code: ExitTemplateNotFound, Message: fmt.Sprintf("template %q not found in config", entry.TemplateID)}
 	}
 
-	// Get template dirs
-	templateDirs := make(map[string]bool)
-	for _, p := range project.Flatten(tmpl.Directories, "") {
-		templateDirs[filepath.ToSlash(p)] = true
+	templateDirs, templateFiles := templateLayout(tmpl, entry)
+	if syncFilesOnly {
+		templateDirs = nil
 	}
-
-	// Get actual dirs
-	actualDirs := make(map[string]bool)
-	_ = filepath.WalkDir(projectPath, func(path string, d os.DirEntry, err error) error {
-		if err != nil || !d.IsDir() {
-			return nil
-		}
-		rel, relErr := filepath.Rel(projectPath, path)
-		if relErr != nil || rel == "." {
-			return nil
-		}
-		actualDirs[filepath.ToSlash(rel)] = true
-		return nil
-	})
-
-	// Find missing dirs
-	var missing []string
-	for p := range templateDirs {
-		if !actualDirs[p] {
-			missing = append(missing, p)
-		}
+	if syncDirsOnly {
+		templateFiles = nil
 	}
-	sort.Strings(missing)
 
-	if len(missing) == 0 {
-		fmt.Println("Project is in sync with template — no missing directories.")
-		return nil
+	rec := journal.Record{
+		Operation: journal.OpSync,
+		Details:   map[string]string{"path": projectPath, "template": tmpl.ID},
 	}
+	hashes := make(map[string]string)
 
-	created := 0
-	for _, rel := range missing {
+	// Create missing dirs; parents come before their children
+	dirsCreated := 0
+	for _, rel := range templateDirs {
 		fullPath := filepath.Join(projectPath, filepath.FromSlash(rel))
+		if _, err := os.Stat(fullPath); err == nil {
+			continue
+		}
 		if dryRun {
 			fmt.Printf("  [DRY-RUN] mkdir %s\n", fullPath)
-			created++
+			dirsCreated++
 			continue
 		}
 		if err := os.MkdirAll(fullPath, 0755); err != nil {
@@ -102,13 +110,171 @@ func runSync(cmd *cobra.Command, args []string) error {
 		if verbose {
 			fmt.Printf("  mkdir %s\n", fullPath)
 		}
-		created++
+		rec.Created = append(rec.Created, fullPath)
+		dirsCreated++
+	}
+
+	// Create missing files and update unmodified ones
+	filesCreated, filesUpdated := 0, 0
+	for _, f := range templateFiles {
+		fullPath := filepath.Join(projectPath, filepath.FromSlash(f.Path))
+		content := []byte(f.Content)
+		current, err := os.ReadFile(fullPath)
+		exists := err == nil
+		switch {
+		case os.IsNotExist(err):
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "  Warning: cannot read %s: %v\n", f.Path, err)
+			continue
+		case bytes.Equal(current, content):
+			continue
+		case !syncUpdate:
+			if verbose {
+				fmt.Printf("  skip %s (exists)\n", fullPath)
+			}
+			continue
+		case entry.FileHashes[f.Path] != project.HashContent(current):
+			if verbose {
+				fmt.Printf("  skip %s (modified)\n", fullPath)
+			}
+			continue
+		}
+
+		action := "create"
+		if exists {
+			action = "update"
+		}
+		if dryRun {
+			fmt.Printf("  [DRY-RUN] %s %s\n", action, fullPath)
+		} else {
+			dirs, err := writeSyncedFile(fullPath, content)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "  Warning: cannot write %s: %v\n", f.Path, err)
+				continue
+			}
+			if verbose {
+				fmt.Printf("  %s %s\n", action, fullPath)
+			}
+			rec.Created = append(rec.Created, dirs...)
+			rec.Created = append(rec.Created, fullPath)
+			rec.Details["file."+f.Path] = f.Content
+			if exists {
+				rec.Details["old."+f.Path] = string(current)
+			}
+			hashes[f.Path] = project.HashContent(content)
+		}
+		if exists {
+			filesUpdated++
+		} else {
+			filesCreated++
+		}
+	}
+
+	if len(hashes) > 0 {
+		setFileHashes(idxPath, projectPath, hashes)
+	}
+	if len(rec.Created) > 0 {
+		recordJournal(rec)
 	}
 
+	if dirsCreated+filesCreated+filesUpdated == 0 {
+		fmt.Println("Project is in sync with template — nothing missing.")
+		return nil
+	}
+	summary := fmt.Sprintf("%d missing directory(ies), %d file(s)", dirsCreated, filesCreated)
+	if filesUpdated > 0 {
+		summary += fmt.Sprintf(", %d updated file(s)", filesUpdated)
+	}
 	if dryRun {
-		fmt.Printf("\nDry run — would create %d missing directory(ies)\n", created)
+		fmt.Printf("\nDry run — would create %s\n", summary)
 	} else {
-		fmt.Printf("Synced %d missing directory(ies)\n", created)
+		fmt.Printf("Synced %s\n", summary)
 	}
 	return nil
 }
+
+// writeSyncedFile writes content to path, creating missing parent
+// directories, and returns the directories it created.
+func writeSyncedFile(path string, content []byte) ([]string, error) {
+	var missing []string
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(dir); err == nil || dir == filepath.Dir(dir) {
+			break
+		}
+		missing = append([]string{dir}, missing...)
+	}
+	if len(missing) > 0 {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, err
+		}
+	}
+	return missing, os.WriteFile(path, content, 0644)
+}
+
+// setFileHashes records the generated content hashes of files in the
+// project's index entry. Empty hashes remove the file's entry.
+func setFileHashes(idxPath, projectPath string, hashes map[string]string) {
+	_ = index.Update(idxPath, projectPath, func(e *index.Entry) {
+		if e.FileHashes == nil {
+			e.FileHashes = make(map[string]string, len(hashes))
+		}
+		for rel, h := range hashes {
+			if h == "" {
+				delete(e.FileHashes, rel)
+			} else {
+				e.FileHashes[rel] = h
+			}
+		}
+	})
+}
+
+// templateLayout resolves the directories and files tmpl produces for entry,
+// as slash-separated paths relative to the project root, with the variables
+// and skipped optional directories recorded at creation.
+func templateLayout(tmpl *config.Template, entry index.Entry) (dirs []string, files []project.File) {
+	skip := make(map[string]bool, len(entry.SkippedOptional))
+	for _, d := range entry.SkippedOptional {
+		skip[d] = true
+	}
+	return project.Layout(tmpl.Directories, project.CreateOptions{
+		Variables:    creationVars(tmpl, entry),
+		SkipOptional: skip,
+	})
+}
+
+// creationVars returns the variables entry was created with. Projects
+// indexed before variables were recorded, or added by reindex and watch,
+// get the built-in variables for their name and creation date, the
+// template defaults and persisted variables from their metadata.
+func creationVars(tmpl *config.Template, entry index.Entry) map[string]string {
+	vars := tmplpkg.BuiltinVars(entry.Name, entry.CreatedAt)
+	for _, v := range tmpl.Variables {
+		vars[v.Name] = v.Default
+		if val, ok := entry.Metadata[v.Name]; ok && v.Persist {
+			vars[v.Name] = val
+		}
+	}
+	for k, v := range entry.Variables {
+		vars[k] = v
+	}
+	vars["path"] = entry.Path
+	return vars
+}
+
+// projectDirs returns the slash-separated paths of all directories below
+// root.
+func projectDirs(root string) map[string]bool {
+	dirs := make(map[string]bool)
+	_ = filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		rel, relErr := filepath.Rel(root, path)
+		if relErr != nil || rel == "." {
+			return nil
+		}
+		dirs[filepath.ToSlash(rel)] = true
+		return nil
+	})
+	return dirs
+}
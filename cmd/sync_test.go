@@ -91,3 +91,212 @@ func TestRunSyncNoMatch(t *testing.T) {
 		t.Fatal("expected ExitError")
 	}
 }
+
+// writeVarsConfig writes a template with a templated, a conditional and an
+// optional directory.
+func writeVarsConfig(t *testing.T, dir, base string) string {
+	t.Helper()
+	content := `templates:
+  - id: shoot
+    name: Shoot
+    base_path: ` + base + `
+    variables:
+      - name: client
+        default: acme
+      - name: drone
+        default: "no"
+    directories:
+      - name: "{client}_Footage"
+        children:
+          - name: "{year}"
+      - name: Drone
+        when: drone == yes
+      - name: Extras
+        optional: true
+      - name: Edit
+`
+	cfgPath := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(cfgPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return cfgPath
+}
+
+func TestRunSyncUsesCreationVariables(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "projects")
+	setConfigPath(t, writeVarsConfig(t, dir, base))
+	oldSkip := createSkip
+	createSkip = []string{"Extras"}
+	t.Cleanup(func() { createSkip = oldSkip })
+
+	if err := runRoot(&cobra.Command{}, []string{"shoot", "Spot"}); err != nil {
+		t.Fatalf("runRoot() error: %v", err)
+	}
+	projectDir := filepath.Join(base, "Spot")
+	entry, ok := findEntry(filepath.Join(dir, "projects.json"), projectDir)
+	if !ok || entry.Variables["client"] != "acme" || len(entry.SkippedOptional) != 1 {
+		t.Fatalf("entry = %+v, want stored variables and skipped optional", entry)
+	}
+
+	footage := filepath.Join(projectDir, "acme_Footage")
+	if err := os.RemoveAll(footage); err != nil {
+		t.Fatal(err)
+	}
+	if err := runSync(&cobra.Command{}, []string{"Spot"}); err != nil {
+		t.Fatalf("runSync() error: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(footage, time.Now().Format("2006"))); err != nil {
+		t.Errorf("templated directory not recreated: %v", err)
+	}
+	for _, name := range []string{"{client}_Footage", "Drone", "Extras"} {
+		if _, err := os.Stat(filepath.Join(projectDir, name)); !os.IsNotExist(err) {
+			t.Errorf("sync created %s", name)
+		}
+	}
+
+	buf := setOutputFormat(t, OutputJSON)
+	if err := runDiff(&cobra.Command{}, []string{"shoot", projectDir}); err != nil {
+		t.Fatalf("runDiff() error: %v", err)
+	}
+	var env struct {
+		Data diffView `json:"data"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &env); err != nil {
+		t.Fatal(err)
+	}
+	if env.Data.MissingCount != 0 || env.Data.ExtraCount != 0 {
+		t.Errorf("diff = %+v, want no missing or extra directories", env.Data)
+	}
+}
+
+func TestRunSkipOptionalUnknown(t *testing.T) {
+	dir := t.TempDir()
+	setConfigPath(t, writeVarsConfig(t, dir, filepath.Join(dir, "projects")))
+	oldSkip := createSkip
+	createSkip = []string{"Edit"}
+	t.Cleanup(func() { createSkip = oldSkip })
+
+	if err := runRoot(&cobra.Command{}, []string{"shoot", "Spot"}); err == nil {
+		t.Error("expected error for a directory that is not optional")
+	}
+}
+
+// writeFilesConfig writes a template with the given files in its Docs
+// directory.
+func writeFilesConfig(t *testing.T, cfgPath, base, files string) {
+	t.Helper()
+	content := `templates:
+  - id: docs
+    name: Docs
+    base_path: ` + base + `
+    directories:
+      - name: Docs
+        files:
+` + files
+	if err := os.WriteFile(cfgPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func setSyncFlags(t *testing.T, filesOnly, dirsOnly, update bool) {
+	t.Helper()
+	oldFiles, oldDirs, oldUpdate := syncFilesOnly, syncDirsOnly, syncUpdate
+	syncFilesOnly, syncDirsOnly, syncUpdate = filesOnly, dirsOnly, update
+	t.Cleanup(func() { syncFilesOnly, syncDirsOnly, syncUpdate = oldFiles, oldDirs, oldUpdate })
+}
+
+func TestRunSyncFiles(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "projects")
+	cfgPath := filepath.Join(dir, "config.yaml")
+	writeFilesConfig(t, cfgPath, base, `          - name: README.md
+            content: "# {name} v1"
+          - name: NOTES.md
+            content: "notes v1"
+`)
+	setConfigPath(t, cfgPath)
+	setSyncFlags(t, false, false, false)
+
+	if err := runRoot(&cobra.Command{}, []string{"docs", "Spot"}); err != nil {
+		t.Fatalf("runRoot() error: %v", err)
+	}
+	docs := filepath.Join(base, "Spot", "Docs")
+	entry, _ := findEntry(filepath.Join(dir, "projects.json"), filepath.Join(base, "Spot"))
+	if len(entry.FileHashes) != 2 {
+		t.Fatalf("FileHashes = %v, want 2 generated files", entry.FileHashes)
+	}
+
+	// The template changes; NOTES.md is edited locally
+	writeFilesConfig(t, cfgPath, base, `          - name: README.md
+            content: "# {name} v2"
+          - name: NOTES.md
+            content: "notes v2"
+          - name: DELIVERY_SPEC.md
+            content: "spec for {name}"
+`)
+	if err := os.WriteFile(filepath.Join(docs, "NOTES.md"), []byte("my notes"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	readFile := func(name string) string {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(docs, name))
+		if err != nil {
+			return "<missing>"
+		}
+		return string(data)
+	}
+
+	setSyncFlags(t, false, true, false)
+	if err := runSync(&cobra.Command{}, []string{"Spot"}); err != nil {
+		t.Fatalf("runSync(--dirs-only) error: %v", err)
+	}
+	if got := readFile("DELIVERY_SPEC.md"); got != "<missing>" {
+		t.Error("--dirs-only created a file")
+	}
+
+	setSyncFlags(t, false, false, false)
+	if err := runSync(&cobra.Command{}, []string{"Spot"}); err != nil {
+		t.Fatalf("runSync() error: %v", err)
+	}
+	if got := readFile("DELIVERY_SPEC.md"); got != "spec for Spot" {
+		t.Errorf("DELIVERY_SPEC.md = %q, want rendered content", got)
+	}
+	if got := readFile("README.md"); got != "# Spot v1" {
+		t.Errorf("README.md overwritten without --update-unmodified: %q", got)
+	}
+
+	setSyncFlags(t, true, false, true)
+	if err := runSync(&cobra.Command{}, []string{"Spot"}); err != nil {
+		t.Fatalf("runSync(--update-unmodified) error: %v", err)
+	}
+	if got := readFile("README.md"); got != "# Spot v2" {
+		t.Errorf("unmodified README.md = %q, want updated", got)
+	}
+	if got := readFile("NOTES.md"); got != "my notes" {
+		t.Errorf("locally modified NOTES.md = %q, want kept", got)
+	}
+
+	// Undo restores the previous README, then the first sync's new file goes
+	if err := runUndo(&cobra.Command{}, nil); err != nil {
+		t.Fatalf("runUndo() error: %v", err)
+	}
+	if got := readFile("README.md"); got != "# Spot v1" {
+		t.Errorf("after undo README.md = %q, want v1", got)
+	}
+	if err := runUndo(&cobra.Command{}, nil); err != nil {
+		t.Fatalf("runUndo() error: %v", err)
+	}
+	if got := readFile("DELIVERY_SPEC.md"); got != "<missing>" {
+		t.Errorf("after undo DELIVERY_SPEC.md = %q, want removed", got)
+	}
+
+	if err := runRedo(&cobra.Command{}, nil); err != nil {
+		t.Fatalf("runRedo() error: %v", err)
+	}
+	if got := readFile("DELIVERY_SPEC.md"); got != "spec for Spot" {
+		t.Errorf("after redo DELIVERY_SPEC.md = %q", got)
+	}
+}
@@ -0,0 +1,225 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fwartner/prjct/internal/index"
+	"github.com/fwartner/prjct/internal/journal"
+	"github.com/spf13/cobra"
+)
+
+var tagCmd = &cobra.Command{
+	Use:   "tag",
+	Short: "Manage project tags",
+	Long: `Tags label individual projects, e.g. rush, client-x or pitch.
+Tags are case-insensitive and stored in lowercase. Filter projects by tag
+with "prjct search --tag".`,
+}
+
+var tagAddCmd = &cobra.Command{
+	Use:               "add <query> <tag>...",
+	Short:             "Add tags to a project",
+	Args:              cobra.MinimumNArgs(2),
+	RunE:              runTagAdd,
+	ValidArgsFunction: completeTagArgs,
+}
+
+var tagRmCmd = &cobra.Command{
+	Use:               "rm <query> <tag>...",
+	Short:             "Remove tags from a project",
+	Args:              cobra.MinimumNArgs(2),
+	RunE:              runTagRm,
+	ValidArgsFunction: completeTagArgs,
+}
+
+var tagLsCmd = &cobra.Command{
+	Use:   "ls [query]",
+	Short: "List tags of a project, or all tags with counts",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  runTagLs,
+}
+
+// tagCountView holds the number of projects carrying one tag.
+type tagCountView struct {
+	Tag   string `json:"tag" yaml:"tag"`
+	Count int    `json:"count" yaml:"count"`
+}
+
+func init() {
+	tagCmd.AddCommand(tagAddCmd)
+	tagCmd.AddCommand(tagRmCmd)
+	tagCmd.AddCommand(tagLsCmd)
+}
+
+func runTagAdd(cmd *cobra.Command, args []string) error {
+	tags, err := parseTags(args[1:])
+	if err != nil {
+		return err
+	}
+	idxPath, entry, err := findProject(args[0])
+	if err != nil {
+		return err
+	}
+	return updateTags(idxPath, entry, tags, nil)
+}
+
+func runTagRm(cmd *cobra.Command, args []string) error {
+	tags, err := parseTags(args[1:])
+	if err != nil {
+		return err
+	}
+	idxPath, entry, err := findProject(args[0])
+	if err != nil {
+		return err
+	}
+	return updateTags(idxPath, entry, nil, tags)
+}
+
+func runTagLs(cmd *cobra.Command, args []string) error {
+	if len(args) == 1 {
+		_, entry, err := findProject(args[0])
+		if err != nil {
+			return err
+		}
+		if isStructuredOutput() {
+			return writeOutput("tag", nonNil(entry.Tags))
+		}
+		if len(entry.Tags) == 0 {
+			fmt.Printf("%s has no tags\n", entry.Name)
+			return nil
+		}
+		fmt.Println(strings.Join(entry.Tags, "\n"))
+		return nil
+	}
+
+	idxPath, err := resolveIndexPath()
+	if err != nil {
+		return err
+	}
+	idx, err := index.Load(idxPath)
+	if err != nil {
+		return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("cannot load index: %v", err)}
+	}
+
+	counts := tagCounts(idx.Projects)
+	if isStructuredOutput() {
+		return writeOutput("tag", counts)
+	}
+	if len(counts) == 0 {
+		fmt.Println("No tags in use.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintf(w, "  TAG\tCOUNT\n")
+	fmt.Fprintf(w, "  ---\t-----\n")
+	for _, c := range counts {
+		fmt.Fprintf(w, "  %s\t%d\n", c.Tag, c.Count)
+	}
+	w.Flush()
+	return nil
+}
+
+// updateTags adds and removes tags on the entry and journals the change.
+func updateTags(idxPath string, entry index.Entry, add, remove []string) error {
+	// Compute the effective change on a copy so dry-run and the journal
+	// only report tags that actually change.
+	preview := entry
+	preview.Tags = append([]string(nil), entry.Tags...)
+	added := preview.AddTags(add...)
+	removed := preview.RemoveTags(remove...)
+
+	if len(added) == 0 && len(removed) == 0 {
+		fmt.Printf("Tags unchanged for %s\n", entry.Name)
+		return nil
+	}
+
+	if dryRun {
+		for _, t := range added {
+			fmt.Printf("  [DRY-RUN] add tag %s\n", t)
+		}
+		for _, t := range removed {
+			fmt.Printf("  [DRY-RUN] remove tag %s\n", t)
+		}
+		return nil
+	}
+
+	err := index.Update(idxPath, entry.Path, func(e *index.Entry) {
+		e.AddTags(add...)
+		e.RemoveTags(remove...)
+	})
+	if err != nil {
+		return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("failed to save tags: %v", err)}
+	}
+
+	recordJournal(journal.Record{
+		Operation: journal.OpTag,
+		Details: map[string]string{
+			"path":    entry.Path,
+			"added":   strings.Join(added, ","),
+			"removed": strings.Join(removed, ","),
+		},
+	})
+
+	fmt.Printf("Tags updated for %s: %s\n", entry.Name, strings.Join(preview.Tags, ", "))
+	return nil
+}
+
+// parseTags normalizes tag arguments. Tags may not contain whitespace or
+// commas so they stay usable on the command line and in journal records.
+func parseTags(args []string) ([]string, error) {
+	tags := make([]string, 0, len(args))
+	for _, a := range args {
+		t := index.NormalizeTag(a)
+		if t == "" || strings.ContainsAny(t, ", \t") {
+			return nil, &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("invalid tag %q: must be non-empty without spaces or commas", a)}
+		}
+		tags = append(tags, t)
+	}
+	return tags, nil
+}
+
+// tagCounts returns per-tag project counts sorted by tag.
+func tagCounts(entries []index.Entry) []tagCountView {
+	counts := index.TagCounts(entries)
+	views := make([]tagCountView, 0, len(counts))
+	for tag, count := range counts {
+		views = append(views, tagCountView{Tag: tag, Count: count})
+	}
+	sort.Slice(views, func(i, j int) bool {
+		return views[i].Tag < views[j].Tag
+	})
+	return views
+}
+
+// completeTags offers the tags already used in the index.
+func completeTags(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	idxPath, err := resolveIndexPath()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	idx, err := index.Load(idxPath)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var tags []string
+	for _, c := range tagCounts(idx.Projects) {
+		if strings.HasPrefix(c.Tag, strings.ToLower(toComplete)) {
+			tags = append(tags, c.Tag)
+		}
+	}
+	return tags, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeTagArgs completes tags for "tag add" and "tag rm" once the
+// project query has been given.
+func completeTagArgs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) == 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return completeTags(cmd, args, toComplete)
+}
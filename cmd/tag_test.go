@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fwartner/prjct/internal/index"
+	"github.com/fwartner/prjct/internal/journal"
+	"github.com/spf13/cobra"
+)
+
+func setSearchTags(t *testing.T, tags []string) {
+	t.Helper()
+	old := searchTags
+	searchTags = tags
+	t.Cleanup(func() { searchTags = old })
+}
+
+func TestRunTagAddAndRm(t *testing.T) {
+	base := t.TempDir()
+	cfgPath := writeTestConfig(t, base)
+	setConfigPath(t, cfgPath)
+	idxPath := writeTestIndex(t, filepath.Dir(cfgPath), []index.Entry{
+		{Name: "Proj", TemplateID: "test", Path: filepath.Join(base, "Proj"), CreatedAt: time.Now()},
+	})
+
+	if err := runTagAdd(&cobra.Command{}, []string{"Proj", "Rush", "pitch"}); err != nil {
+		t.Fatalf("runTagAdd() error: %v", err)
+	}
+	idx, _ := index.Load(idxPath)
+	if got := idx.Projects[0].Tags; len(got) != 2 || got[0] != "pitch" || got[1] != "rush" {
+		t.Errorf("Tags = %v, want [pitch rush]", got)
+	}
+
+	rec, _ := journal.Last(filepath.Join(filepath.Dir(cfgPath), "journal.json"))
+	if rec == nil || rec.Operation != journal.OpTag || rec.Details["added"] != "rush,pitch" {
+		t.Errorf("journal record = %+v, want tag addition", rec)
+	}
+
+	if err := runTagRm(&cobra.Command{}, []string{"Proj", "rush"}); err != nil {
+		t.Fatalf("runTagRm() error: %v", err)
+	}
+	idx, _ = index.Load(idxPath)
+	if got := idx.Projects[0].Tags; len(got) != 1 || got[0] != "pitch" {
+		t.Errorf("Tags = %v, want [pitch]", got)
+	}
+}
+
+func TestParseTagsInvalid(t *testing.T) {
+	for _, tag := range []string{"", "two words", "a,b"} {
+		if _, err := parseTags([]string{tag}); err == nil {
+			t.Errorf("parseTags(%q) expected error", tag)
+		}
+	}
+}
+
+func TestRunRootWithTags(t *testing.T) {
+	base := t.TempDir()
+	cfgPath := writeTestConfig(t, base)
+	setConfigPath(t, cfgPath)
+	old := createTags
+	createTags = []string{"rush"}
+	t.Cleanup(func() { createTags = old })
+
+	if err := runRoot(&cobra.Command{}, []string{"test", "Tagged"}); err != nil {
+		t.Fatalf("runRoot() error: %v", err)
+	}
+	idx, _ := index.Load(filepath.Join(filepath.Dir(cfgPath), "projects.json"))
+	if len(idx.Projects) != 1 || !idx.Projects[0].HasTag("rush") {
+		t.Errorf("projects = %+v, want one tagged rush", idx.Projects)
+	}
+}
+
+func TestRunSearchByTag(t *testing.T) {
+	dir := t.TempDir()
+	writeTestIndex(t, dir, []index.Entry{
+		{Name: "alpha", TemplateID: "dev", Path: "/a", CreatedAt: time.Now(), Tags: []string{"rush"}},
+		{Name: "beta", TemplateID: "dev", Path: "/b", CreatedAt: time.Now()},
+	})
+	setConfigPath(t, filepath.Join(dir, "config.yaml"))
+	setSearchTemplate(t, "")
+	setSearchTags(t, []string{"rush"})
+	buf := setOutputFormat(t, OutputNDJSON)
+
+	if err := runSearch(&cobra.Command{}, nil); err != nil {
+		t.Fatalf("runSearch() error: %v", err)
+	}
+	if n := strings.Count(buf.String(), "\n"); n != 1 {
+		t.Errorf("got %d results, want 1:\n%s", n, buf.String())
+	}
+}
+
+func TestCompleteTags(t *testing.T) {
+	dir := t.TempDir()
+	writeTestIndex(t, dir, []index.Entry{
+		{Name: "a", Path: "/a", Tags: []string{"pitch", "rush"}},
+		{Name: "b", Path: "/b", Tags: []string{"rush"}},
+	})
+	setConfigPath(t, filepath.Join(dir, "config.yaml"))
+
+	got, _ := completeTags(&cobra.Command{}, nil, "r")
+	if len(got) != 1 || got[0] != "rush" {
+		t.Errorf("completeTags(r) = %v, want [rush]", got)
+	}
+}
+
+func TestBuildStatsTags(t *testing.T) {
+	stats := buildStats([]index.Entry{
+		{Name: "a", TemplateID: "dev", Tags: []string{"rush"}},
+		{Name: "b", TemplateID: "dev", Tags: []string{"pitch", "rush"}},
+	})
+	if len(stats.Tags) != 2 || stats.Tags[1].Tag != "rush" || stats.Tags[1].Count != 2 {
+		t.Errorf("Tags = %+v", stats.Tags)
+	}
+}
@@ -0,0 +1,239 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/fwartner/prjct/internal/config"
+	"github.com/fwartner/prjct/internal/index"
+	"github.com/fwartner/prjct/internal/journal"
+	tmplpkg "github.com/fwartner/prjct/internal/template"
+	"github.com/spf13/cobra"
+)
+
+var tidyCmd = &cobra.Command{
+	Use:   "tidy <query>",
+	Short: "Move stray directories out of a project's template structure",
+	Long: `Moves directories that are not part of the template, such as
+"New Folder (3)" created by an editor, into an _Unsorted directory of the
+first matching project. Rules in the template can send them to template
+directories instead:
+
+  tidy:
+    unsorted: _Unsorted
+    rules:
+      - match: "*render*"
+        to: 04_Export
+
+Only directories at the project root or next to template subdirectories
+are considered; the contents of template directories, hidden directories
+and directories that look like a renamed template directory (see
+"prjct audit") stay where they are. Nothing is deleted or overwritten: a
+name that is taken at the destination gets a " (2)" suffix. Use --dry-run
+to see the plan; "prjct undo" moves everything back.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runTidy,
+}
+
+// tidyMoveView is one directory tidy moves.
+type tidyMoveView struct {
+	From string `json:"from" yaml:"from"`
+	To   string `json:"to" yaml:"to"`
+	Rule string `json:"rule,omitempty" yaml:"rule,omitempty"`
+}
+
+// tidyView is the machine-readable result of the tidy command.
+type tidyView struct {
+	Name    string         `json:"name" yaml:"name"`
+	Path    string         `json:"path" yaml:"path"`
+	Moves   []tidyMoveView `json:"moves" yaml:"moves"`
+	Skipped []string       `json:"skipped" yaml:"skipped"`
+	Applied bool           `json:"applied" yaml:"applied"`
+}
+
+func runTidy(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	_, entry, err := findProject(args[0])
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(entry.Path); err != nil {
+		return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("project directory not found: %s", entry.Path)}
+	}
+	tmpl, err := cfg.ResolveTemplate(entry.TemplateID)
+	if err != nil {
+		return &ExitError{Code: ExitTemplateNotFound, Message: fmt.Sprintf("template %q not found in config", entry.TemplateID)}
+	}
+
+	moves, actions, skipped := planTidy(tmpl, entry)
+	view := tidyView{Name: entry.Name, Path: entry.Path, Moves: moves, Skipped: nonNil(skipped)}
+
+	if len(actions) > 0 && !dryRun {
+		if err := runMigrateActions(entry.Path, actions, false); err != nil {
+			return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("tidy failed, nothing moved: %v", err)}
+		}
+		rec := journal.Record{
+			Operation: journal.OpTidy,
+			Details:   map[string]string{"path": entry.Path, "template": tmpl.ID},
+		}
+		recordActions(&rec, entry.Path, actions)
+		recordJournal(rec)
+		view.Applied = true
+	}
+
+	if isStructuredOutput() {
+		return writeOutput("tidy", view)
+	}
+
+	for _, sk := range skipped {
+		fmt.Printf("  skip: %s\n", sk)
+	}
+	if len(moves) == 0 {
+		fmt.Println("Nothing to tidy — no stray directories.")
+		return nil
+	}
+	prefix := "  "
+	if dryRun {
+		prefix = "  [DRY-RUN] "
+	}
+	for _, m := range moves {
+		fmt.Printf("%smove %s → %s\n", prefix, m.From, m.To)
+	}
+	if dryRun {
+		fmt.Printf("\nDry run — would move %d directory(ies)\n", len(moves))
+	} else {
+		fmt.Printf("Moved %d directory(ies)\n", len(moves))
+	}
+	return nil
+}
+
+// planTidy returns the directories of entry's project to move, the actions
+// that move them, creating missing destinations first, and the
+// destinations that had to be skipped.
+func planTidy(tmpl *config.Template, entry index.Entry) ([]tidyMoveView, []migrateAction, []string) {
+	layout, _ := templateLayout(tmpl, entry)
+	// Strays are looked for where the template has directories of its own
+	parents := map[string]bool{".": true}
+	for _, d := range layout {
+		parents[path.Dir(d)] = true
+	}
+
+	vars := creationVars(tmpl, entry)
+	var skipped []string
+	// Variables may resolve to paths the config check could not see
+	unsorted := cleanRel(tmplpkg.Resolve(tmpl.Tidy.UnsortedDir(), vars))
+	if leavesRoot(unsorted) {
+		skipped = append(skipped, fmt.Sprintf("unsorted %s: leaves the project, unmatched directories stay put", unsorted))
+		unsorted = ""
+	}
+	var rules []config.TidyRule
+	destinations := []string{unsorted}
+	if tmpl.Tidy != nil {
+		for _, r := range tmpl.Tidy.Rules {
+			r.To = cleanRel(tmplpkg.Resolve(r.To, vars))
+			if leavesRoot(r.To) {
+				skipped = append(skipped, fmt.Sprintf("rule %s → %s: leaves the project", r.Match, r.To))
+				continue
+			}
+			rules = append(rules, r)
+			destinations = append(destinations, r.To)
+		}
+	}
+
+	d := compareDirs(tmpl, entry)
+	_, extra, _ := pairRenames(d.missing, d.extra)
+
+	moves := []tidyMoveView{}
+	var actions []migrateAction
+	taken := make(map[string]bool)
+	created := make(map[string]bool)
+	for _, dir := range extra {
+		name := path.Base(dir)
+		if !parents[path.Dir(dir)] || strings.HasPrefix(name, ".") || isTidyDestination(dir, destinations) {
+			continue
+		}
+
+		to, rule := unsorted, ""
+		for _, r := range rules {
+			if ok, _ := path.Match(strings.ToLower(r.Match), strings.ToLower(name)); ok {
+				to, rule = r.To, r.Match
+				break
+			}
+		}
+		if to == "" || to == path.Dir(dir) {
+			continue
+		}
+
+		for _, a := range missingAncestors(entry.Path, to, created) {
+			actions = append(actions, migrateAction{Op: actionMkdir, From: a})
+		}
+		target := freeTidyPath(entry.Path, path.Join(to, name), taken)
+		taken[target] = true
+		actions = append(actions, migrateAction{Op: actionMove, From: dir, To: target})
+		moves = append(moves, tidyMoveView{From: dir, To: target, Rule: rule})
+	}
+	return moves, actions, skipped
+}
+
+// isTidyDestination reports whether dir is, or is inside, a directory tidy
+// moves strays to.
+func isTidyDestination(dir string, destinations []string) bool {
+	for _, d := range destinations {
+		if dir == d || strings.HasPrefix(dir, d+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// missingAncestors returns dir and those of its parents that do not exist
+// below root and have not been created yet, outermost first, and marks them
+// as created.
+func missingAncestors(root, dir string, created map[string]bool) []string {
+	var missing []string
+	for d := dir; d != "." && !created[d]; d = path.Dir(d) {
+		if _, err := os.Stat(filepath.Join(root, filepath.FromSlash(d))); err == nil {
+			break
+		}
+		missing = append([]string{d}, missing...)
+	}
+	for _, d := range missing {
+		created[d] = true
+	}
+	return missing
+}
+
+// freeTidyPath returns target, or target with a " (n)" suffix if that name
+// already exists below root or is taken by an earlier move.
+func freeTidyPath(root, target string, taken map[string]bool) string {
+	candidate := target
+	for n := 2; ; n++ {
+		if _, err := os.Lstat(filepath.Join(root, filepath.FromSlash(candidate))); os.IsNotExist(err) && !taken[candidate] {
+			return candidate
+		}
+		candidate = fmt.Sprintf("%s (%d)", target, n)
+	}
+}
+
+// replayTidy moves the directories of a tidy record back, or again.
+func replayTidy(rec *journal.Record, reverse bool) error {
+	root := rec.Details["path"]
+	actions := migrationActions(rec)
+	if err := runMigrateActions(root, actions, reverse); err != nil {
+		return &ExitError{Code: ExitGeneral, Message: err.Error()}
+	}
+	moved := 0
+	for _, a := range actions {
+		if a.Op == actionMove {
+			moved++
+		}
+	}
+	fmt.Printf("Moved %d directory(ies) in %s\n", moved, root)
+	return nil
+}
@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fwartner/prjct/internal/config"
+	"github.com/fwartner/prjct/internal/index"
+	"github.com/spf13/cobra"
+)
+
+// tidyTestSetup creates a project with stray directories at the root and
+// next to a template subdirectory.
+func tidyTestSetup(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	base := filepath.Join(dir, "projects")
+	project := filepath.Join(base, "spot")
+	for _, d := range []string{
+		"01_Footage/Day1", "04_Export", "Edits/Graphics", "Edits/Old Stuff",
+		"New Folder (3)", "Final Render/v1", ".cache", "_Unsorted/New Folder (3)",
+	} {
+		if err := os.MkdirAll(filepath.Join(project, d), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfgPath := filepath.Join(dir, "config.yaml")
+	cfg := "templates:\n  - id: video\n    name: Video\n    base_path: " + base + `
+    tidy:
+      rules:
+        - match: "*render*"
+          to: 04_Export
+    directories:
+      - name: 01_Footage
+      - name: 04_Export
+      - name: Edits
+        children:
+          - name: Graphics
+`
+	if err := os.WriteFile(cfgPath, []byte(cfg), 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeTestIndex(t, dir, []index.Entry{{Name: "spot", TemplateID: "video", Path: project}})
+	setConfigPath(t, cfgPath)
+	return project
+}
+
+func TestRunTidyDryRun(t *testing.T) {
+	project := tidyTestSetup(t)
+	setDryRun(t, true)
+	buf := setOutputFormat(t, OutputJSON)
+
+	if err := runTidy(&cobra.Command{}, []string{"spot"}); err != nil {
+		t.Fatalf("runTidy() error: %v", err)
+	}
+	var env struct {
+		Data tidyView `json:"data"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &env); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, buf.String())
+	}
+	want := map[string]string{
+		"Edits/Old Stuff": "_Unsorted/Old Stuff",
+		"Final Render":    "04_Export/Final Render",
+		"New Folder (3)":  "_Unsorted/New Folder (3) (2)",
+	}
+	if len(env.Data.Moves) != len(want) {
+		t.Fatalf("moves = %+v, want %v", env.Data.Moves, want)
+	}
+	for _, m := range env.Data.Moves {
+		if want[m.From] != m.To {
+			t.Errorf("move %s → %s, want → %s", m.From, m.To, want[m.From])
+		}
+	}
+	if _, err := os.Stat(filepath.Join(project, "New Folder (3)")); err != nil {
+		t.Errorf("dry run moved a directory: %v", err)
+	}
+}
+
+func TestRunTidyAndUndo(t *testing.T) {
+	project := tidyTestSetup(t)
+	setDryRun(t, false)
+	setOutputFormat(t, OutputJSON)
+
+	if err := runTidy(&cobra.Command{}, []string{"spot"}); err != nil {
+		t.Fatalf("runTidy() error: %v", err)
+	}
+	for _, p := range []string{"04_Export/Final Render/v1", "_Unsorted/Old Stuff", "_Unsorted/New Folder (3) (2)", "01_Footage/Day1", ".cache"} {
+		if _, err := os.Stat(filepath.Join(project, p)); err != nil {
+			t.Errorf("%s missing after tidy: %v", p, err)
+		}
+	}
+
+	if err := runUndo(&cobra.Command{}, nil); err != nil {
+		t.Fatalf("runUndo() error: %v", err)
+	}
+	for _, p := range []string{"Final Render/v1", "Edits/Old Stuff", "New Folder (3)", "_Unsorted/New Folder (3)"} {
+		if _, err := os.Stat(filepath.Join(project, p)); err != nil {
+			t.Errorf("%s missing after undo: %v", p, err)
+		}
+	}
+
+	if err := runRedo(&cobra.Command{}, nil); err != nil {
+		t.Fatalf("runRedo() error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(project, "04_Export", "Final Render")); err != nil {
+		t.Errorf("redo did not move Final Render: %v", err)
+	}
+}
+
+func TestPlanTidySkipsDestinationsOutsideProject(t *testing.T) {
+	project := filepath.Join(t.TempDir(), "spot")
+	for _, d := range []string{"01_Footage", "Final Render", "Old Stuff", "Misc"} {
+		if err := os.MkdirAll(filepath.Join(project, d), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tmpl := &config.Template{
+		ID:          "video",
+		Directories: []config.Directory{{Name: "01_Footage"}},
+		Tidy: &config.TidySettings{
+			Unsorted: "{dest}",
+			Rules: []config.TidyRule{
+				{Match: "*render*", To: "{dest}/Export"},
+				{Match: "old*", To: "Archive"},
+			},
+		},
+	}
+	entry := index.Entry{Name: "spot", TemplateID: "video", Path: project, Variables: map[string]string{"dest": "../.."}}
+
+	moves, actions, skipped := planTidy(tmpl, entry)
+	if len(moves) != 1 || moves[0].From != "Old Stuff" || moves[0].To != "Archive/Old Stuff" {
+		t.Errorf("moves = %+v, want only Old Stuff → Archive", moves)
+	}
+	for _, a := range actions {
+		if leavesRoot(a.From) || leavesRoot(a.To) {
+			t.Errorf("action %s leaves the project", a)
+		}
+	}
+	if len(skipped) != 2 {
+		t.Errorf("skipped = %q, want the unsorted directory and the render rule", skipped)
+	}
+}
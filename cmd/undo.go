@@ -1,93 +1,695 @@
 package cmd
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/fwartner/prjct/internal/config"
 	"github.com/fwartner/prjct/internal/index"
 	"github.com/fwartner/prjct/internal/journal"
+	"github.com/fwartner/prjct/internal/project"
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
 )
 
 var undoCmd = &cobra.Command{
-	Use:   "undo",
-	Short: "Undo the last recorded operation",
-	Long: `Reverses the most recent journaled operation such as create, rename,
-or clone. Not all operations can be fully undone.`,
-	Args: cobra.NoArgs,
+	Use:   "undo [id | --group id]",
+	Short: "Undo a recorded operation",
+	Long: `Reverses a journaled operation. Without an ID the most recent
+operation that has not been undone is reverted; use "prjct log" to find
+IDs. An operation cannot be undone while later operations on the same
+project or template are still in effect — undo those first.
+
+Supported operations: create, clone and restore (remove the project unless
+something in it changed since; --force removes it anyway), rename, note, sync (removes created
+directories that are still empty and reverts unchanged synced files),
+migrate and tidy (move directories back), clean (recreates removed directories),
+import (removes the added templates), reindex (drops the added index
+entries), archive (removes the archive file while the project still
+exists), status, meta and tag.
+With --group, every operation of one invocation, as shown by
+"prjct log --group", is undone, newest first.
+Undone operations can be re-applied with "prjct redo".`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: runUndo,
 }
 
+var (
+	undoForce bool
+	undoGroup string
+)
+
+func init() {
+	undoCmd.Flags().BoolVar(&undoForce, "force", false, "remove created or restored projects even if they changed since")
+	undoCmd.Flags().StringVar(&undoGroup, "group", "", "undo all operations of one invocation")
+}
+
+// errNotUndoable is returned for operations that cannot be reverted.
+var errNotUndoable = errors.New("operation cannot be undone automatically")
+
 func runUndo(cmd *cobra.Command, args []string) error {
 	jPath, err := resolveJournalPath()
 	if err != nil {
 		return &ExitError{Code: ExitGeneral, Message: err.Error()}
 	}
 
-	rec, err := journal.Last(jPath)
+	j, err := journal.Load(jPath)
 	if err != nil {
 		return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("cannot read journal: %v", err)}
 	}
+
+	if undoGroup != "" {
+		if len(args) > 0 {
+			return &ExitError{Code: ExitGeneral, Message: "give an operation ID or --group, not both"}
+		}
+		return undoGroupRecords(jPath, j, undoGroup)
+	}
+
+	rec, err := selectRecord(j, args, j.LastActive)
+	if err != nil {
+		return err
+	}
 	if rec == nil {
 		fmt.Println("Nothing to undo.")
 		return nil
 	}
-
-	fmt.Printf("Last operation: %s at %s\n", rec.Operation, rec.Timestamp.Format("2006-01-02 15:04:05"))
-	for k, v := range rec.Details {
-		fmt.Printf("  %s: %s\n", k, v)
+	if rec.Undone != nil {
+		return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("operation #%d is already undone; use \"prjct redo %d\"", rec.ID, rec.ID)}
+	}
+	if deps := j.Dependents(rec); len(deps) > 0 {
+		return &ExitError{Code: ExitGeneral, Message: dependencyMessage("undo", rec, deps, "undo them first")}
 	}
 
+	fmt.Printf("Operation #%d: %s at %s\n", rec.ID, rec.Operation, rec.Timestamp.Format("2006-01-02 15:04:05"))
+	printDetails(rec)
+
 	if dryRun {
 		fmt.Println("Dry run — would attempt to undo the above operation.")
 		return nil
 	}
 
+	if undone, err := applyUndo(jPath, rec); !undone {
+		return err
+	}
+	fmt.Println("Undo complete.")
+	return nil
+}
+
+// undoGroupRecords undoes the active records of one invocation, newest
+// first. Records of other invocations that depend on them must be undone
+// first; nothing is reverted while any exist.
+func undoGroupRecords(jPath string, j *journal.Journal, group string) error {
+	var recs []*journal.Record
+	for i := len(j.Records) - 1; i >= 0; i-- {
+		if r := &j.Records[i]; r.Group == group && r.Undone == nil {
+			recs = append(recs, r)
+		}
+	}
+	if len(recs) == 0 {
+		return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("no operations of group %s left to undo", group)}
+	}
+	for _, rec := range recs {
+		var outside []journal.Record
+		for _, d := range j.Dependents(rec) {
+			if d.Group != group {
+				outside = append(outside, d)
+			}
+		}
+		if len(outside) > 0 {
+			return &ExitError{Code: ExitGeneral, Message: dependencyMessage("undo", rec, outside, "undo them first")}
+		}
+	}
+
+	for _, rec := range recs {
+		fmt.Printf("Operation #%d: %s at %s\n", rec.ID, rec.Operation, rec.Timestamp.Format("2006-01-02 15:04:05"))
+		printDetails(rec)
+		if dryRun {
+			continue
+		}
+		// An operation skipped as not undoable keeps earlier ones in place
+		if deps := j.Dependents(rec); len(deps) > 0 {
+			return &ExitError{Code: ExitGeneral, Message: dependencyMessage("undo", rec, deps, "stopping")}
+		}
+		if _, err := applyUndo(jPath, rec); err != nil {
+			return err
+		}
+	}
+	if dryRun {
+		fmt.Printf("Dry run — would attempt to undo %d operation(s) of group %s.\n", len(recs), group)
+		return nil
+	}
+	fmt.Println("Undo complete.")
+	return nil
+}
+
+// applyUndo reverts rec and marks it as undone in the journal at jPath and
+// in rec itself, reporting whether it did. Operations that cannot be undone
+// are reported and left active.
+func applyUndo(jPath string, rec *journal.Record) (bool, error) {
+	state, err := undoRecord(rec)
+	if errors.Is(err, errNotUndoable) {
+		fmt.Printf("Cannot undo operation type %q automatically.\n", rec.Operation)
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	undone := &journal.Undo{At: time.Now(), State: state}
+	if err := journal.Mark(jPath, rec.ID, func(r *journal.Record) {
+		r.Undone = undone
+	}); err != nil {
+		return false, &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("cannot update journal: %v", err)}
+	}
+	rec.Undone = undone
+	return true, nil
+}
+
+// undoRecord reverts a single operation. The returned state is stored with
+// the undo marker and handed back to redoRecord.
+func undoRecord(rec *journal.Record) (map[string]string, error) {
+	idxPath, _ := resolveIndexPath()
+	path := rec.Details["path"]
+
 	switch rec.Operation {
 	case journal.OpCreate, journal.OpClone:
-		path := rec.Details["path"]
 		if path == "" {
-			return &ExitError{Code: ExitGeneral, Message: "journal record missing path"}
+			return nil, &ExitError{Code: ExitGeneral, Message: "journal record missing path"}
 		}
-		if err := os.RemoveAll(path); err != nil {
-			return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("cannot remove %s: %v", path, err)}
+		if err := checkUnchanged(rec, path); err != nil {
+			return nil, err
 		}
-		// Remove from index
-		if idxPath, idxErr := resolveIndexPath(); idxErr == nil {
-			_ = index.Remove(idxPath, path)
+		state := map[string]string{}
+		if entry, ok := findEntry(idxPath, path); ok {
+			if data, err := json.Marshal(entry); err == nil {
+				state["entry"] = string(data)
+			}
 		}
+		if err := os.RemoveAll(path); err != nil {
+			return nil, &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("cannot remove %s: %v", path, err)}
+		}
+		_ = index.Remove(idxPath, path)
 		fmt.Printf("Removed: %s\n", path)
+		return state, nil
 
 	case journal.OpRename:
-		oldPath := rec.Details["old_path"]
-		newPath := rec.Details["new_path"]
+		oldPath, newPath := rec.Details["old_path"], rec.Details["new_path"]
 		if oldPath == "" || newPath == "" {
-			return &ExitError{Code: ExitGeneral, Message: "journal record missing paths"}
+			return nil, &ExitError{Code: ExitGeneral, Message: "journal record missing paths"}
 		}
-		if err := os.Rename(newPath, oldPath); err != nil {
-			return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("cannot rename back: %v", err)}
+		if err := renameProject(idxPath, newPath, oldPath, rec.Details["old_name"]); err != nil {
+			return nil, err
+		}
+		fmt.Printf("Reverted: %s -> %s\n", newPath, oldPath)
+		return nil, nil
+
+	case journal.OpNote:
+		note := rec.Details["note"]
+		err := updateEntry(idxPath, path, func(e *index.Entry) {
+			for i := len(e.Notes) - 1; i >= 0; i-- {
+				if e.Notes[i] == note {
+					e.Notes = append(e.Notes[:i], e.Notes[i+1:]...)
+					break
+				}
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+		fmt.Printf("Removed note from %s\n", path)
+		return nil, nil
+
+	case journal.OpSync:
+		dirs, files := syncedPaths(rec)
+		reverted, modified := revertSyncedFiles(rec, files)
+		removed, kept := removeEmptyDirs(dirs)
+		if len(files) > 0 {
+			fmt.Printf("Reverted %d synced file(s)\n", reverted)
+		}
+		fmt.Printf("Removed %d synced directory(ies)\n", len(removed))
+		for _, f := range modified {
+			fmt.Printf("  kept %s (modified)\n", f)
+		}
+		for _, dir := range kept {
+			fmt.Printf("  kept %s (not empty)\n", dir)
+		}
+		return nil, nil
+
+	case journal.OpClean:
+		created, err := makeDirs(rec.Removed)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Printf("Recreated %d directory(ies)\n", created)
+		return nil, nil
+
+	case journal.OpImport:
+		return undoImport(rec)
+
+	case journal.OpMigrate:
+		return nil, replayMigration(rec, true)
+
+	case journal.OpTidy:
+		return nil, replayTidy(rec, true)
+
+	case journal.OpArchive:
+		if len(rec.Removed) > 0 {
+			return nil, errNotUndoable
+		}
+		files := rec.Created
+		if len(files) == 0 {
+			files = []string{rec.Details["archive"]}
+		}
+		for _, f := range files {
+			if err := os.Remove(f); err != nil && !os.IsNotExist(err) {
+				return nil, &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("cannot remove %s: %v", f, err)}
+			}
+		}
+		fmt.Printf("Removed archive: %s\n", rec.Details["archive"])
+		return nil, nil
+
+	case journal.OpRestore:
+		if err := checkUnchanged(rec, path); err != nil {
+			return nil, err
+		}
+		state := map[string]string{}
+		if entry, ok := findEntry(idxPath, path); ok && len(rec.Indexed) > 0 {
+			if data, err := json.Marshal(entry); err == nil {
+				state["entry"] = string(data)
+			}
+		}
+		if err := os.RemoveAll(path); err != nil {
+			return nil, &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("cannot remove %s: %v", path, err)}
+		}
+		if len(rec.Indexed) > 0 {
+			_ = index.Remove(idxPath, path)
 		}
-		// Update index
-		if idxPath, idxErr := resolveIndexPath(); idxErr == nil {
-			oldName := rec.Details["old_name"]
-			_ = index.Update(idxPath, newPath, func(e *index.Entry) {
-				e.Name = oldName
+		if oldPath := rec.Details["old_path"]; oldPath != "" {
+			_ = index.Update(idxPath, path, func(e *index.Entry) {
 				e.Path = oldPath
 			})
 		}
-		fmt.Printf("Reverted: %s -> %s\n", newPath, oldPath)
+		fmt.Printf("Removed restored project: %s\n", path)
+		return state, nil
 
-	default:
-		fmt.Printf("Cannot undo operation type %q automatically.\n", rec.Operation)
-		return nil
+	case journal.OpReindex:
+		var entries []index.Entry
+		for _, p := range rec.Indexed {
+			if e, ok := findEntry(idxPath, p); ok {
+				entries = append(entries, e)
+				_ = index.Remove(idxPath, p)
+			}
+		}
+		data, err := json.Marshal(entries)
+		if err != nil {
+			return nil, &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("cannot save index entries: %v", err)}
+		}
+		fmt.Printf("Removed %d project(s) from the index\n", len(entries))
+		return map[string]string{"entries": string(data)}, nil
+
+	case journal.OpStatus:
+		if err := updateEntry(idxPath, path, func(e *index.Entry) {
+			e.SetStatus(rec.Details["from"], time.Now())
+		}); err != nil {
+			return nil, err
+		}
+		fmt.Printf("Status of %s set back to %s\n", path, statusLabel(rec.Details["from"]))
+		return nil, nil
+
+	case journal.OpMeta:
+		if err := updateEntry(idxPath, path, func(e *index.Entry) {
+			applyMetaDetails(e, rec.Details, "old.")
+		}); err != nil {
+			return nil, err
+		}
+		fmt.Printf("Metadata of %s restored\n", path)
+		return nil, nil
+
+	case journal.OpTag:
+		if err := updateEntry(idxPath, path, func(e *index.Entry) {
+			e.RemoveTags(splitList(rec.Details["added"])...)
+			e.AddTags(splitList(rec.Details["removed"])...)
+		}); err != nil {
+			return nil, err
+		}
+		fmt.Printf("Tags of %s restored\n", path)
+		return nil, nil
 	}
+	return nil, errNotUndoable
+}
 
-	// Remove the record from journal
-	_ = journal.RemoveLast(jPath)
-	fmt.Println("Undo complete.")
+// undoImport removes the templates added by an import from the config and
+// keeps their definitions for redo.
+func undoImport(rec *journal.Record) (map[string]string, error) {
+	cfgPath := rec.Details["config"]
+	cfg, err := config.Load(cfgPath)
+	if err != nil {
+		return nil, &ExitError{Code: ExitConfigInvalid, Message: fmt.Sprintf("loading config: %v", err)}
+	}
+
+	ids := make(map[string]bool)
+	for _, id := range splitList(rec.Details["templates"]) {
+		ids[id] = true
+	}
+
+	var kept, removed []config.Template
+	for _, t := range cfg.Templates {
+		if ids[t.ID] {
+			removed = append(removed, t)
+		} else {
+			kept = append(kept, t)
+		}
+	}
+	cfg.Templates = kept
+
+	data, err := yaml.Marshal(removed)
+	if err != nil {
+		return nil, &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("cannot save removed templates: %v", err)}
+	}
+	if err := cfg.Save(cfgPath); err != nil {
+		return nil, &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("saving config: %v", err)}
+	}
+	for _, t := range removed {
+		fmt.Printf("  remove: %q\n", t.ID)
+	}
+	return map[string]string{"templates": string(data)}, nil
+}
+
+// selectRecord returns the record named by args[0], or the one picked by
+// fallback when no ID is given.
+func selectRecord(j *journal.Journal, args []string, fallback func() *journal.Record) (*journal.Record, error) {
+	if len(args) == 0 {
+		return fallback(), nil
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(args[0], "#"))
+	if err != nil {
+		return nil, &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("invalid operation ID %q", args[0])}
+	}
+	rec := j.Find(id)
+	if rec == nil {
+		return nil, &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("no operation with ID %d in the active journal", id)}
+	}
+	return rec, nil
+}
+
+// dependencyMessage explains which records block an undo or redo.
+func dependencyMessage(action string, rec *journal.Record, deps []journal.Record, hint string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "cannot %s #%d (%s): later operations depend on it; %s:", action, rec.ID, rec.Operation, hint)
+	for _, d := range deps {
+		fmt.Fprintf(&b, "\n  #%d %s %s", d.ID, d.Operation, recordSummary(&d))
+	}
+	return b.String()
+}
+
+func printDetails(rec *journal.Record) {
+	keys := make([]string, 0, len(rec.Details))
+	for k := range rec.Details {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := rec.Details[k]
+		// File contents recorded by sync are summarized
+		if strings.Contains(v, "\n") || len(v) > 200 {
+			v = fmt.Sprintf("(%d bytes)", len(v))
+		}
+		fmt.Printf("  %s: %s\n", k, v)
+	}
+	for _, p := range rec.Created {
+		fmt.Printf("  created: %s\n", p)
+	}
+	for _, p := range rec.Removed {
+		fmt.Printf("  removed: %s\n", p)
+	}
+	for _, p := range rec.Indexed {
+		fmt.Printf("  indexed: %s\n", p)
+	}
+}
+
+// renameProject moves a project directory and updates its index entry.
+func renameProject(idxPath, from, to, name string) error {
+	if err := os.Rename(from, to); err != nil {
+		return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("cannot rename %s: %v", from, err)}
+	}
+	_ = index.Update(idxPath, from, func(e *index.Entry) {
+		e.Name = name
+		e.Path = to
+	})
+	return nil
+}
+
+// updateEntry applies fn to the index entry at projectPath.
+func updateEntry(idxPath, projectPath string, fn func(*index.Entry)) error {
+	if _, ok := findEntry(idxPath, projectPath); !ok {
+		return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("project %s is no longer indexed", projectPath)}
+	}
+	if err := index.Update(idxPath, projectPath, fn); err != nil {
+		return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("cannot update index: %v", err)}
+	}
 	return nil
 }
 
+// findEntry returns the index entry with the given path.
+func findEntry(idxPath, projectPath string) (index.Entry, bool) {
+	idx, err := index.Load(idxPath)
+	if err != nil {
+		return index.Entry{}, false
+	}
+	for _, e := range idx.Projects {
+		if e.Path == projectPath {
+			return e, true
+		}
+	}
+	return index.Entry{}, false
+}
+
+// applyMetaDetails sets each metadata key recorded in details under prefix
+// ("meta." or "old."); empty values remove the key.
+func applyMetaDetails(e *index.Entry, details map[string]string, prefix string) {
+	for k, v := range details {
+		key, ok := strings.CutPrefix(k, prefix)
+		if !ok {
+			continue
+		}
+		if v == "" {
+			delete(e.Metadata, key)
+			continue
+		}
+		if e.Metadata == nil {
+			e.Metadata = make(map[string]string)
+		}
+		e.Metadata[key] = v
+	}
+}
+
+// removeEmptyDirs removes the given directories deepest first, skipping
+// any that are missing or no longer empty.
+func removeEmptyDirs(dirs []string) (removed, kept []string) {
+	sorted := append([]string(nil), dirs...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return len(sorted[i]) > len(sorted[j])
+	})
+	for _, dir := range sorted {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		if len(entries) > 0 {
+			kept = append(kept, dir)
+			continue
+		}
+		if err := os.Remove(dir); err == nil {
+			removed = append(removed, dir)
+		}
+	}
+	return removed, kept
+}
+
+// checkUnchanged refuses to remove the project an operation created when
+// anything in it was added or modified after the operation, unless --force
+// is given. The journal only knows about journaled changes, so footage or
+// edits made since would otherwise be deleted silently.
+func checkUnchanged(rec *journal.Record, path string) error {
+	if undoForce {
+		return nil
+	}
+	changed, err := changedSince(path, rec.Timestamp)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("cannot check %s: %v", path, err)}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "cannot undo #%d (%s): %s changed since; these would be lost (use --force to remove it anyway):", rec.ID, rec.Operation, path)
+	for _, rel := range changed {
+		fmt.Fprintf(&b, "\n  %s", rel)
+	}
+	return &ExitError{Code: ExitGeneral, Message: b.String()}
+}
+
+// changedSince returns the paths below root, relative to it, that were
+// added or modified after t. Adding, removing or renaming an entry also
+// updates the modification time of its directory.
+func changedSince(root string, t time.Time) ([]string, error) {
+	var changed []string
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == root {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.ModTime().After(t) {
+			rel, _ := filepath.Rel(root, path)
+			changed = append(changed, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	return changed, err
+}
+
+// syncedPaths splits the paths created by a sync into directories and the
+// relative paths of the files it wrote.
+func syncedPaths(rec *journal.Record) (dirs, files []string) {
+	written := make(map[string]bool)
+	for k := range rec.Details {
+		if rel, ok := strings.CutPrefix(k, "file."); ok {
+			files = append(files, rel)
+			written[filepath.Join(rec.Details["path"], filepath.FromSlash(rel))] = true
+		}
+	}
+	sort.Strings(files)
+	for _, p := range rec.Created {
+		if !written[p] {
+			dirs = append(dirs, p)
+		}
+	}
+	return dirs, files
+}
+
+// revertSyncedFiles removes files a sync created and restores files it
+// updated, as long as they still have the synced content. It returns how
+// many were reverted and the paths that were modified since.
+func revertSyncedFiles(rec *journal.Record, files []string) (int, []string) {
+	projectPath := rec.Details["path"]
+	hashes := make(map[string]string)
+	var modified []string
+	for _, rel := range files {
+		path := filepath.Join(projectPath, filepath.FromSlash(rel))
+		current, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if string(current) != rec.Details["file."+rel] {
+			modified = append(modified, path)
+			continue
+		}
+		if old, ok := rec.Details["old."+rel]; ok {
+			if os.WriteFile(path, []byte(old), 0644) == nil {
+				hashes[rel] = project.HashContent([]byte(old))
+			}
+		} else if os.Remove(path) == nil {
+			hashes[rel] = ""
+		}
+	}
+	if idxPath, err := resolveIndexPath(); err == nil && len(hashes) > 0 {
+		setFileHashes(idxPath, projectPath, hashes)
+	}
+	return len(hashes), modified
+}
+
+// makeDirs creates the given directories, returning how many were missing.
+func makeDirs(dirs []string) (int, error) {
+	created := 0
+	for _, dir := range dirs {
+		if _, err := os.Stat(dir); err == nil {
+			continue
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return created, &ExitError{Code: ExitGeneral, Message: fmt.Sprintf("cannot create %s: %v", dir, err)}
+		}
+		created++
+	}
+	return created, nil
+}
+
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+// invocationID groups the journal records written by one command run.
+var invocationID string
+
+// invocationRetention caches the journal retention limits for the current
+// invocation, so that the config is not loaded again for every record.
+var (
+	retentionMu         sync.Mutex
+	invocationRetention *journal.Retention
+)
+
+// beginInvocation starts a new journal group for the command about to run.
+func beginInvocation() {
+	invocationID = journal.NewGroupID()
+	retentionMu.Lock()
+	invocationRetention = nil
+	retentionMu.Unlock()
+}
+
+// recordJournal appends rec to the journal, best-effort, tagging it with the
+// current invocation's group.
+func recordJournal(rec journal.Record) {
+	if rec.Timestamp.IsZero() {
+		rec.Timestamp = time.Now()
+	}
+	if invocationID == "" {
+		beginInvocation()
+	}
+	rec.Group = invocationID
+	if jPath, err := resolveJournalPath(); err == nil {
+		_ = journal.AppendWithRetention(jPath, rec, journalRetention())
+	}
+}
+
+// journalRetention returns the retention limits from the config, or the
+// defaults when the config cannot be loaded. The config is read once per
+// invocation.
+func journalRetention() journal.Retention {
+	retentionMu.Lock()
+	defer retentionMu.Unlock()
+	if invocationRetention != nil {
+		return *invocationRetention
+	}
+	var r journal.Retention
+	if cfg, err := loadConfig(); err == nil && cfg.Journal != nil {
+		r = journal.Retention{
+			MaxRecords: cfg.Journal.MaxRecords,
+			MaxAge:     time.Duration(cfg.Journal.MaxAgeDays) * 24 * time.Hour,
+		}
+	}
+	invocationRetention = &r
+	return r
+}
+
 func resolveJournalPath() (string, error) {
 	if configPath != "" {
 		return configPath[:len(configPath)-len("config.yaml")] + "journal.json", nil
@@ -1,11 +1,16 @@
 package cmd
 
 import (
+	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/fwartner/prjct/internal/config"
+	"github.com/fwartner/prjct/internal/index"
 	"github.com/fwartner/prjct/internal/journal"
 	"github.com/spf13/cobra"
 )
@@ -55,6 +60,48 @@ func TestRunUndoCreate(t *testing.T) {
 	}
 }
 
+func TestRunUndoCreateChangedSince(t *testing.T) {
+	base := t.TempDir()
+	cfgPath := writeTestConfig(t, base)
+	setConfigPath(t, cfgPath)
+
+	projectDir := filepath.Join(base, "Shoot")
+	_ = os.MkdirAll(filepath.Join(projectDir, "Footage"), 0755)
+	created := time.Now().Add(-time.Hour)
+	_ = os.Chtimes(filepath.Join(projectDir, "Footage"), created, created)
+
+	jPath := filepath.Join(filepath.Dir(cfgPath), "journal.json")
+	_ = journal.Append(jPath, journal.Record{
+		Timestamp: created.Add(time.Minute),
+		Operation: journal.OpCreate,
+		Details:   map[string]string{"path": projectDir, "name": "Shoot"},
+	})
+	if err := os.WriteFile(filepath.Join(projectDir, "Footage", "A001.mov"), []byte("clip"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := runUndo(&cobra.Command{}, []string{"1"})
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatalf("runUndo() = %v, want refusal", err)
+	}
+	if !strings.Contains(exitErr.Message, "Footage/A001.mov") {
+		t.Errorf("message %q does not list the added file", exitErr.Message)
+	}
+	if _, err := os.Stat(projectDir); err != nil {
+		t.Fatalf("project removed despite changes: %v", err)
+	}
+
+	undoForce = true
+	t.Cleanup(func() { undoForce = false })
+	if err := runUndo(&cobra.Command{}, []string{"1"}); err != nil {
+		t.Fatalf("runUndo() --force: %v", err)
+	}
+	if _, err := os.Stat(projectDir); !os.IsNotExist(err) {
+		t.Error("expected --force to remove the project")
+	}
+}
+
 func TestRunUndoRename(t *testing.T) {
 	base := t.TempDir()
 	cfgPath := writeTestConfig(t, base)
@@ -107,3 +154,274 @@ func TestResolveJournalPath(t *testing.T) {
 		t.Errorf("got %q, want %q", got, want)
 	}
 }
+
+func TestRunUndoByIDWithDependents(t *testing.T) {
+	base := t.TempDir()
+	cfgPath := writeTestConfig(t, base)
+	setConfigPath(t, cfgPath)
+
+	projectDir := filepath.Join(base, "Proj")
+	_ = os.MkdirAll(projectDir, 0755)
+	writeTestIndex(t, filepath.Dir(cfgPath), []index.Entry{
+		{Name: "Proj", TemplateID: "test", Path: projectDir, CreatedAt: time.Now(), Notes: []string{"hello"}},
+	})
+
+	jPath := filepath.Join(filepath.Dir(cfgPath), "journal.json")
+	_ = journal.Append(jPath, journal.Record{Timestamp: time.Now(), Operation: journal.OpCreate,
+		Details: map[string]string{"path": projectDir, "template": "test", "name": "Proj"}})
+	_ = journal.Append(jPath, journal.Record{Timestamp: time.Now(), Operation: journal.OpNote,
+		Details: map[string]string{"path": projectDir, "note": "hello"}})
+
+	err := runUndo(&cobra.Command{}, []string{"1"})
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || !strings.Contains(exitErr.Message, "#2") {
+		t.Fatalf("expected dependency error naming #2, got %v", err)
+	}
+	if _, statErr := os.Stat(projectDir); statErr != nil {
+		t.Error("project must not be removed when undo is refused")
+	}
+
+	if err := runUndo(&cobra.Command{}, []string{"2"}); err != nil {
+		t.Fatalf("runUndo(2) error: %v", err)
+	}
+	if e, _ := findEntry(filepath.Join(filepath.Dir(cfgPath), "projects.json"), projectDir); len(e.Notes) != 0 {
+		t.Errorf("Notes = %v, want note removed", e.Notes)
+	}
+	if err := runUndo(&cobra.Command{}, []string{"1"}); err != nil {
+		t.Fatalf("runUndo(1) error: %v", err)
+	}
+}
+
+func TestRunUndoRedoNote(t *testing.T) {
+	base := t.TempDir()
+	cfgPath := writeTestConfig(t, base)
+	setConfigPath(t, cfgPath)
+	idxPath := writeTestIndex(t, filepath.Dir(cfgPath), []index.Entry{
+		{Name: "Proj", TemplateID: "test", Path: filepath.Join(base, "Proj"), CreatedAt: time.Now()},
+	})
+
+	if err := runNote(&cobra.Command{}, []string{"Proj", "first"}); err != nil {
+		t.Fatalf("runNote() error: %v", err)
+	}
+	if err := runUndo(&cobra.Command{}, nil); err != nil {
+		t.Fatalf("runUndo() error: %v", err)
+	}
+	idx, _ := index.Load(idxPath)
+	if len(idx.Projects[0].Notes) != 0 {
+		t.Fatalf("Notes = %v, want none after undo", idx.Projects[0].Notes)
+	}
+
+	jPath := filepath.Join(filepath.Dir(cfgPath), "journal.json")
+	if j, _ := journal.Load(jPath); len(j.Records) != 1 || j.Records[0].Undone == nil {
+		t.Fatal("undo should mark the record instead of removing it")
+	}
+
+	if err := runRedo(&cobra.Command{}, nil); err != nil {
+		t.Fatalf("runRedo() error: %v", err)
+	}
+	idx, _ = index.Load(idxPath)
+	if len(idx.Projects[0].Notes) != 1 || idx.Projects[0].Notes[0] != "first" {
+		t.Errorf("Notes = %v, want [first] after redo", idx.Projects[0].Notes)
+	}
+	if j, _ := journal.Load(jPath); j.Records[0].Undone != nil {
+		t.Error("redo should clear the undone marker")
+	}
+}
+
+func TestRunUndoRedoGroup(t *testing.T) {
+	base := t.TempDir()
+	cfgPath := writeTestConfig(t, base)
+	setConfigPath(t, cfgPath)
+	idxPath := writeTestIndex(t, filepath.Dir(cfgPath), []index.Entry{
+		{Name: "Alpha", TemplateID: "test", Path: filepath.Join(base, "Alpha"), CreatedAt: time.Now()},
+		{Name: "Beta", TemplateID: "test", Path: filepath.Join(base, "Beta"), CreatedAt: time.Now()},
+	})
+	t.Cleanup(func() { undoGroup, redoGroup = "", "" })
+
+	beginInvocation()
+	group := invocationID
+	for _, name := range []string{"Alpha", "Beta"} {
+		if err := runNote(&cobra.Command{}, []string{name, "sent"}); err != nil {
+			t.Fatalf("runNote(%s) error: %v", name, err)
+		}
+	}
+	beginInvocation()
+	if err := runNote(&cobra.Command{}, []string{"Alpha", "later"}); err != nil {
+		t.Fatalf("runNote() error: %v", err)
+	}
+
+	undoGroup = group
+	if err := runUndo(&cobra.Command{}, nil); err == nil {
+		t.Fatal("expected the later note of another invocation to block the group")
+	}
+	undoGroup = ""
+	if err := runUndo(&cobra.Command{}, nil); err != nil {
+		t.Fatalf("runUndo() error: %v", err)
+	}
+
+	undoGroup = group
+	if err := runUndo(&cobra.Command{}, nil); err != nil {
+		t.Fatalf("runUndo(--group) error: %v", err)
+	}
+	idx, _ := index.Load(idxPath)
+	for _, e := range idx.Projects {
+		if len(e.Notes) != 0 {
+			t.Errorf("%s notes = %v, want none after undo --group", e.Name, e.Notes)
+		}
+	}
+
+	redoGroup = group
+	if err := runRedo(&cobra.Command{}, nil); err != nil {
+		t.Fatalf("runRedo(--group) error: %v", err)
+	}
+	idx, _ = index.Load(idxPath)
+	for _, e := range idx.Projects {
+		if len(e.Notes) != 1 || e.Notes[0] != "sent" {
+			t.Errorf("%s notes = %v, want [sent] after redo --group", e.Name, e.Notes)
+		}
+	}
+}
+
+func TestRunUndoSyncAndClean(t *testing.T) {
+	base := t.TempDir()
+	cfgPath := writeTestConfig(t, base)
+	setConfigPath(t, cfgPath)
+
+	projectDir := filepath.Join(base, "Proj")
+	_ = os.MkdirAll(filepath.Join(projectDir, "src"), 0755)
+	writeTestIndex(t, filepath.Dir(cfgPath), []index.Entry{
+		{Name: "Proj", TemplateID: "test", Path: projectDir, CreatedAt: time.Now()},
+	})
+
+	if err := runSync(&cobra.Command{}, []string{"Proj"}); err != nil {
+		t.Fatalf("runSync() error: %v", err)
+	}
+	docs := filepath.Join(projectDir, "docs")
+	if _, err := os.Stat(docs); err != nil {
+		t.Fatalf("sync should create docs: %v", err)
+	}
+	if err := runUndo(&cobra.Command{}, nil); err != nil {
+		t.Fatalf("runUndo(sync) error: %v", err)
+	}
+	if _, err := os.Stat(docs); !os.IsNotExist(err) {
+		t.Error("undo of sync should remove the empty created directory")
+	}
+
+	scratch := filepath.Join(projectDir, "scratch")
+	_ = os.MkdirAll(scratch, 0755)
+	if err := runClean(&cobra.Command{}, []string{"Proj"}); err != nil {
+		t.Fatalf("runClean() error: %v", err)
+	}
+	if _, err := os.Stat(scratch); !os.IsNotExist(err) {
+		t.Fatal("clean should remove empty scratch")
+	}
+	if err := runUndo(&cobra.Command{}, nil); err != nil {
+		t.Fatalf("runUndo(clean) error: %v", err)
+	}
+	if _, err := os.Stat(scratch); err != nil {
+		t.Error("undo of clean should recreate scratch")
+	}
+}
+
+func TestRunUndoRedoImport(t *testing.T) {
+	base := t.TempDir()
+	cfgPath := writeTestConfig(t, base)
+	setConfigPath(t, cfgPath)
+
+	importFile := filepath.Join(t.TempDir(), "import.yaml")
+	_ = os.WriteFile(importFile, []byte(`templates:
+  - id: extra
+    name: Extra
+    base_path: `+base+`
+    directories:
+      - name: a
+`), 0644)
+
+	if err := runImport(&cobra.Command{}, []string{importFile}); err != nil {
+		t.Fatalf("runImport() error: %v", err)
+	}
+	if err := runUndo(&cobra.Command{}, nil); err != nil {
+		t.Fatalf("runUndo(import) error: %v", err)
+	}
+	cfg, _ := config.Load(cfgPath)
+	if cfg.FindTemplate("extra") != nil {
+		t.Error("undo of import should remove the template")
+	}
+
+	if err := runRedo(&cobra.Command{}, nil); err != nil {
+		t.Fatalf("runRedo(import) error: %v", err)
+	}
+	cfg, _ = config.Load(cfgPath)
+	if cfg.FindTemplate("extra") == nil {
+		t.Error("redo of import should add the template back")
+	}
+}
+
+func TestRunLogJSON(t *testing.T) {
+	base := t.TempDir()
+	cfgPath := writeTestConfig(t, base)
+	setConfigPath(t, cfgPath)
+	buf := setOutputFormat(t, OutputJSON)
+
+	jPath := filepath.Join(filepath.Dir(cfgPath), "journal.json")
+	_ = journal.Append(jPath, journal.Record{Timestamp: time.Now(), Operation: journal.OpCreate, Details: map[string]string{"path": "/a"}})
+	_ = journal.Append(jPath, journal.Record{Timestamp: time.Now(), Operation: journal.OpNote, Details: map[string]string{"path": "/a"}})
+
+	if err := runLog(&cobra.Command{}, nil); err != nil {
+		t.Fatalf("runLog() error: %v", err)
+	}
+	var env struct {
+		Data []logRecordView `json:"data"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &env); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(env.Data) != 2 || env.Data[0].ID != 2 {
+		t.Errorf("data = %+v, want newest first", env.Data)
+	}
+}
+
+func TestParseLogTime(t *testing.T) {
+	got, err := parseLogTime("2026-03-01", true)
+	if err != nil {
+		t.Fatalf("parseLogTime() error: %v", err)
+	}
+	if got.Day() != 1 || got.Hour() != 23 {
+		t.Errorf("end-of-day date = %v, want the end of March 1", got)
+	}
+	if got, _ := parseLogTime("7d", false); time.Since(got) < 6*24*time.Hour {
+		t.Errorf("7d = %v, want about a week ago", got)
+	}
+	if _, err := parseLogTime("yesterday", false); err == nil {
+		t.Error("expected error for unsupported time")
+	}
+}
+
+func TestRunLogSearchesRotated(t *testing.T) {
+	base := t.TempDir()
+	cfgPath := writeTestConfig(t, base)
+	setConfigPath(t, cfgPath)
+	buf := setOutputFormat(t, OutputJSON)
+
+	jPath := filepath.Join(filepath.Dir(cfgPath), "journal.json")
+	old := time.Now().AddDate(0, -3, 0)
+	_ = journal.AppendWithRetention(jPath, journal.Record{Timestamp: old, Operation: journal.OpCreate, Details: map[string]string{"path": "/a"}}, journal.Retention{})
+	_ = journal.AppendWithRetention(jPath, journal.Record{Timestamp: time.Now(), Operation: journal.OpNote, Details: map[string]string{"path": "/a"}}, journal.Retention{MaxRecords: 1})
+
+	oldOp := logOp
+	logOp = "create"
+	t.Cleanup(func() { logOp = oldOp })
+
+	if err := runLog(&cobra.Command{}, nil); err != nil {
+		t.Fatalf("runLog() error: %v", err)
+	}
+	var env struct {
+		Data []logRecordView `json:"data"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &env); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(env.Data) != 1 || env.Data[0].Operation != "create" {
+		t.Errorf("data = %+v, want the rotated create", env.Data)
+	}
+}
@@ -11,19 +11,25 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var watchInterval int
+var (
+	watchInterval  int
+	watchRetention bool
+)
 
 var watchCmd = &cobra.Command{
 	Use:   "watch",
 	Short: "Watch base paths and auto-index new projects",
 	Long: `Periodically scans all template base paths for new directories and
-adds them to the project index. Press Ctrl+C to stop.`,
+adds them to the project index. With --retention, the template retention
+policies are applied after every scan (see "prjct retention run").
+Press Ctrl+C to stop.`,
 	Args: cobra.NoArgs,
 	RunE: runWatch,
 }
 
 func init() {
 	watchCmd.Flags().IntVar(&watchInterval, "interval", 30, "scan interval in seconds")
+	watchCmd.Flags().BoolVar(&watchRetention, "retention", false, "apply retention policies after each scan")
 }
 
 func runWatch(cmd *cobra.Command, args []string) error {
@@ -45,12 +51,18 @@ func runWatch(cmd *cobra.Command, args []string) error {
 
 	// Run once immediately, then on interval
 	scanAndIndex(cfg, idxPath)
+	if watchRetention {
+		retainInactive(cfg, idxPath)
+	}
 
 	ticker := time.NewTicker(time.Duration(watchInterval) * time.Second)
 	defer ticker.Stop()
 
 	for range ticker.C {
 		scanAndIndex(cfg, idxPath)
+		if watchRetention {
+			retainInactive(cfg, idxPath)
+		}
 	}
 
 	return nil
@@ -115,3 +127,20 @@ func scanAndIndex(cfg *config.Config, idxPath string) {
 		fmt.Printf("[%s] Indexed %d new project(s)\n", time.Now().Format("15:04:05"), added)
 	}
 }
+
+// retainInactive applies the retention policies and logs each action.
+func retainInactive(cfg *config.Config, idxPath string) {
+	actions, err := applyRetention(cfg, idxPath, "", !dryRun, false)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: retention: %v\n", err)
+		return
+	}
+	stamp := time.Now().Format("15:04:05")
+	for _, a := range actions {
+		if a.Error != "" {
+			fmt.Fprintf(os.Stderr, "[%s] Retention %s %s failed: %s\n", stamp, a.Action, a.Name, a.Error)
+			continue
+		}
+		fmt.Printf("[%s] Retention %s %s → %s (%s)\n", stamp, a.Action, a.Name, a.Target, a.Result)
+	}
+}
@@ -0,0 +1,546 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrUnsafePath is returned when an archive entry would be written outside
+// the extraction directory.
+var ErrUnsafePath = errors.New("unsafe path in archive")
+
+// Options controls how Create writes an archive.
+type Options struct {
+	// Format defaults to DefaultFormat.
+	Format Format
+	// Level is the compression level from 1 (fastest) to 9 (smallest);
+	// 0 selects the format's default. It must be 0 for FormatTar.
+	Level int
+	// Exclude lists gitignore-style patterns of paths to leave out. If Only
+	// is set, just the paths it matches (and their parent directories) are
+	// archived.
+	Exclude []string
+	Only    []string
+	// Split, if positive, writes numbered volumes of at most this many
+	// bytes (see VolumePath) instead of a single file.
+	Split int64
+	// Name and Template are recorded in the manifest.
+	Name     string
+	Template string
+	// Progress, if set, is called as files are written, after the source
+	// has been measured with Measure.
+	Progress func(Progress)
+}
+
+// Create writes sourcePath as an archive to outputPath. Entries are stored
+// under the base name of sourcePath, followed by a manifest with the
+// SHA-256 of every file, which is also returned. An existing file at
+// outputPath is never overwritten. A partially written archive is removed
+// on failure.
+func Create(outputPath, sourcePath string, opts Options) (*Manifest, error) {
+	if opts.Format == "" {
+		opts.Format = DefaultFormat
+	}
+	if opts.Split > 0 {
+		return createSplit(outputPath, sourcePath, opts)
+	}
+	outFile, err := os.OpenFile(outputPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := writeArchive(outFile, sourcePath, opts)
+	if closeErr := outFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(outputPath)
+		return nil, err
+	}
+	return m, nil
+}
+
+// createSplit writes the archive as numbered volumes of at most opts.Split
+// bytes and stores the manifest, including the volume checksums, next to
+// them. Like the volumes, the manifest must not exist yet.
+func createSplit(outputPath, sourcePath string, opts Options) (*Manifest, error) {
+	sidecar := SidecarPath(outputPath)
+	if _, err := os.Lstat(sidecar); err == nil {
+		return nil, &os.PathError{Op: "open", Path: sidecar, Err: os.ErrExist}
+	}
+	vw := &volumeWriter{base: outputPath, limit: opts.Split}
+	m, err := writeArchive(vw, sourcePath, opts)
+	if closeErr := vw.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		m.Volumes = vw.volumes
+		err = writeSidecar(outputPath, m)
+	}
+	if err != nil {
+		vw.remove()
+		os.Remove(sidecar)
+		return nil, err
+	}
+
+	// Drop volumes left over from an earlier, larger archive whose first
+	// volume and manifest are gone.
+	for n := len(vw.volumes) + 1; ; n++ {
+		if err := os.Remove(VolumePath(outputPath, n)); err != nil {
+			break
+		}
+	}
+	return m, nil
+}
+
+func writeArchive(w io.Writer, sourcePath string, opts Options) (*Manifest, error) {
+	absSource, err := filepath.Abs(sourcePath)
+	if err != nil {
+		return nil, err
+	}
+	exclude, err := CompilePatterns(opts.Exclude)
+	if err != nil {
+		return nil, err
+	}
+	only, err := CompilePatterns(opts.Only)
+	if err != nil {
+		return nil, err
+	}
+
+	var track *tracker
+	if opts.Progress != nil {
+		totals, err := Measure(absSource, opts)
+		if err != nil {
+			return nil, err
+		}
+		track = &tracker{state: Progress{Total: totals}, fn: opts.Progress}
+		track.report()
+	}
+
+	sourceFiles, err := CountFiles(absSource)
+	if err != nil {
+		return nil, err
+	}
+
+	baseName := filepath.Base(absSource)
+	m := &Manifest{
+		Version:     ManifestVersion,
+		CreatedAt:   time.Now(),
+		Source:      absSource,
+		Root:        baseName,
+		Name:        opts.Name,
+		Template:    opts.Template,
+		Format:      opts.Format,
+		Exclude:     opts.Exclude,
+		Only:        opts.Only,
+		SourceFiles: sourceFiles,
+		Files:       []ManifestFile{},
+	}
+
+	ew, err := newEntryWriter(w, opts.Format, opts.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	// Directories are written on demand so that --only keeps the parents of
+	// included files but not unrelated empty directories.
+	written := make(map[string]bool)
+	var writeDir func(rel string) error
+	writeDir = func(rel string) error {
+		if written[rel] {
+			return nil
+		}
+		if rel != "." {
+			if err := writeDir(path.Dir(rel)); err != nil {
+				return err
+			}
+		}
+		written[rel] = true
+		return addEntry(ew, m, absSource, rel, nil)
+	}
+
+	err = walkSource(absSource, exclude, only, func(rel string, d os.DirEntry) error {
+		if d.IsDir() {
+			return writeDir(rel)
+		}
+		if err := writeDir(path.Dir(rel)); err != nil {
+			return err
+		}
+		return addEntry(ew, m, absSource, rel, track)
+	}, func(rel string) {
+		m.Omitted = append(m.Omitted, rel)
+	})
+	if err != nil {
+		ew.Close()
+		return nil, err
+	}
+
+	if err := writeManifest(ew, m); err != nil {
+		ew.Close()
+		return nil, err
+	}
+	if err := ew.Close(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// CountFiles returns the number of regular files below root, regardless of
+// any exclude or only patterns. Symlinks are not followed.
+func CountFiles(root string) (int, error) {
+	n := 0
+	err := filepath.WalkDir(root, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
+
+// walkSource calls visit for root (as ".") and every path below it selected
+// by the exclude and only patterns, in lexical order. omit is called for
+// each path matched by exclude; excluded directories are not descended
+// into. With only patterns, a file may be visited without its directory.
+func walkSource(root string, exclude, only *Patterns, visit func(rel string, d os.DirEntry) error, omit func(rel string)) error {
+	return filepath.WalkDir(root, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == "." {
+			return visit(rel, d)
+		}
+
+		if exclude.Match(rel, d.IsDir()) {
+			if omit != nil {
+				omit(rel)
+			}
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !only.Empty() && !only.MatchPath(rel, d.IsDir()) {
+			return nil
+		}
+		return visit(rel, d)
+	})
+}
+
+// addEntry writes the file, directory or symlink at rel below root and
+// records regular files in the manifest.
+func addEntry(ew entryWriter, m *Manifest, root, rel string, track *tracker) error {
+	p := filepath.Join(root, filepath.FromSlash(rel))
+	info, err := os.Lstat(p)
+	if err != nil {
+		return err
+	}
+
+	var link string
+	if info.Mode()&os.ModeSymlink != 0 {
+		if link, err = os.Readlink(p); err != nil {
+			return err
+		}
+	}
+	header, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return err
+	}
+
+	// Use portable relative path
+	if rel == "." {
+		header.Name = m.Root + "/"
+	} else {
+		header.Name = m.Root + "/" + rel
+		if info.IsDir() {
+			header.Name += "/"
+		}
+	}
+
+	if !info.Mode().IsRegular() {
+		return ew.WriteEntry(header, nil)
+	}
+
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	var body io.Reader = io.TeeReader(f, h)
+	if track != nil {
+		body = &progressReader{r: body, track: track}
+	}
+	if err := ew.WriteEntry(header, body); err != nil {
+		return err
+	}
+	m.Files = append(m.Files, ManifestFile{
+		Path:   rel,
+		Size:   info.Size(),
+		SHA256: hex.EncodeToString(h.Sum(nil)),
+	})
+	if track != nil {
+		track.state.Files++
+		track.report()
+	}
+	return nil
+}
+
+// writeManifest appends m as the ManifestName entry.
+func writeManifest(ew entryWriter, m *Manifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	header := &tar.Header{
+		Name:     ManifestName,
+		Typeflag: tar.TypeReg,
+		Mode:     0644,
+		Size:     int64(len(data)),
+		ModTime:  m.CreatedAt,
+	}
+	return ew.WriteEntry(header, bytes.NewReader(data))
+}
+
+// Extracted describes the result of Extract.
+type Extracted struct {
+	// Root is the name of the archive's top-level directory.
+	Root string
+	// Manifest is the manifest embedded in the archive, if any.
+	Manifest *Manifest
+	Files    int
+}
+
+// Extract unpacks an archive into destDir, which must exist. The format is
+// detected from the file contents. All entries must share one top-level
+// directory. Entries with absolute paths, ".." components or links
+// pointing outside destDir are rejected with ErrUnsafePath.
+func Extract(archivePath, destDir string) (*Extracted, error) {
+	res := &Extracted{}
+	// Symlinks are created last, so no entry is ever written through one
+	type symlink struct{ name, link string }
+	var links []symlink
+	err := readEntries(archivePath, func(e *entry) error {
+		if e.Name == ManifestName {
+			m, err := decodeManifest(e.Body)
+			if err != nil {
+				return err
+			}
+			res.Manifest = m
+			return nil
+		}
+
+		name, err := safeName(e.Name)
+		if err != nil {
+			return err
+		}
+		root, _, _ := strings.Cut(name, "/")
+		if res.Root == "" {
+			res.Root = root
+		} else if root != res.Root {
+			return fmt.Errorf("archive has more than one top-level directory (%s, %s)", res.Root, root)
+		}
+		if err := noSymlinks(destDir, name); err != nil {
+			return err
+		}
+
+		target := filepath.Join(destDir, filepath.FromSlash(name))
+
+		switch e.Typeflag {
+		case tar.TypeDir:
+			return os.MkdirAll(target, e.Mode|0700)
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			if err := writeFile(target, e.Body, e.Mode); err != nil {
+				return err
+			}
+			res.Files++
+			return nil
+		case tar.TypeSymlink:
+			link := e.Linkname
+			if filepath.IsAbs(link) || !within(path.Join(path.Dir(name), link), res.Root) {
+				return fmt.Errorf("%w: symlink %s -> %s", ErrUnsafePath, e.Name, link)
+			}
+			links = append(links, symlink{name, link})
+			return nil
+		}
+		// Hard links, devices and FIFOs are never written by Create.
+		return fmt.Errorf("unsupported entry type %q for %s", e.Typeflag, e.Name)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if res.Root == "" {
+		return nil, errors.New("archive is empty")
+	}
+
+	rootDir, err := resolvePath(filepath.Join(destDir, res.Root))
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range links {
+		if err := noSymlinks(destDir, l.name); err != nil {
+			return nil, err
+		}
+		target := filepath.Join(destDir, filepath.FromSlash(l.name))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return nil, err
+		}
+		if _, err := os.Lstat(target); err == nil {
+			return nil, fmt.Errorf("%w: symlink %s replaces another entry", ErrUnsafePath, l.name)
+		}
+		if err := os.Symlink(l.link, target); err != nil {
+			return nil, err
+		}
+		// The textual check above cannot see through other symlinks
+		resolved, err := resolvePath(target)
+		if err != nil {
+			return nil, err
+		}
+		if resolved != rootDir && !strings.HasPrefix(resolved, rootDir+string(filepath.Separator)) {
+			return nil, fmt.Errorf("%w: symlink %s -> %s leaves the archive root", ErrUnsafePath, l.name, l.link)
+		}
+	}
+	return res, nil
+}
+
+// noSymlinks returns an error if any existing component of the slash path
+// name below destDir is a symlink.
+func noSymlinks(destDir, name string) error {
+	p := destDir
+	for _, part := range strings.Split(name, "/") {
+		p = filepath.Join(p, part)
+		info, err := os.Lstat(p)
+		if err != nil {
+			return nil // nothing below a missing component exists either
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("%w: %s is written through a symlink", ErrUnsafePath, name)
+		}
+	}
+	return nil
+}
+
+// maxLinks limits how many symlinks resolvePath follows.
+const maxLinks = 255
+
+// resolvePath returns the absolute path p refers to with every symlink in
+// it followed, like filepath.EvalSymlinks, except that components that do
+// not exist are kept as they are.
+func resolvePath(p string) (string, error) {
+	p, err := filepath.Abs(p)
+	if err != nil {
+		return "", err
+	}
+	vol := filepath.VolumeName(p)
+	cur := vol + string(filepath.Separator)
+	rest := strings.Split(filepath.ToSlash(p[len(vol):]), "/")
+	hops := 0
+	for len(rest) > 0 {
+		part := rest[0]
+		rest = rest[1:]
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			cur = filepath.Dir(cur)
+			continue
+		}
+		next := filepath.Join(cur, part)
+		info, err := os.Lstat(next)
+		if err != nil || info.Mode()&os.ModeSymlink == 0 {
+			cur = next
+			continue
+		}
+		if hops++; hops > maxLinks {
+			return "", fmt.Errorf("too many levels of symlinks in %s", p)
+		}
+		link, err := os.Readlink(next)
+		if err != nil {
+			return "", err
+		}
+		if filepath.IsAbs(link) {
+			v := filepath.VolumeName(link)
+			cur, link = v+string(filepath.Separator), link[len(v):]
+		}
+		rest = append(strings.Split(filepath.ToSlash(link), "/"), rest...)
+	}
+	return cur, nil
+}
+
+// safeName cleans an entry name and rejects absolute paths and entries
+// that climb out of the archive root.
+func safeName(name string) (string, error) {
+	clean := path.Clean(strings.TrimSuffix(name, "/"))
+	if path.IsAbs(clean) || clean == "." || clean == ".." || strings.HasPrefix(clean, "../") ||
+		filepath.IsAbs(filepath.FromSlash(clean)) || strings.Contains(clean, `\`) {
+		return "", fmt.Errorf("%w: %s", ErrUnsafePath, name)
+	}
+	return clean, nil
+}
+
+// within reports whether the cleaned slash path p stays inside root.
+func within(p, root string) bool {
+	p = path.Clean(p)
+	return p == root || strings.HasPrefix(p, root+"/")
+}
+
+func writeFile(target string, r io.Reader, mode os.FileMode) error {
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, mode|0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// RootName returns the top-level directory of an archive by reading its
+// first entry.
+func RootName(archivePath string) (string, error) {
+	var root string
+	err := readEntries(archivePath, func(e *entry) error {
+		if e.Name == ManifestName {
+			return nil
+		}
+		name, err := safeName(e.Name)
+		if err != nil {
+			return err
+		}
+		root, _, _ = strings.Cut(name, "/")
+		return errStop
+	})
+	if err != nil {
+		return "", err
+	}
+	if root == "" {
+		return "", errors.New("archive is empty")
+	}
+	return root, nil
+}
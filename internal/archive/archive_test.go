@@ -0,0 +1,499 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTestArchive(t *testing.T, path string, headers []*tar.Header) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	for _, h := range headers {
+		if err := tw.WriteHeader(h); err != nil {
+			t.Fatal(err)
+		}
+		if h.Typeflag == tar.TypeReg {
+			if _, err := tw.Write(make([]byte, h.Size)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCreateExtractRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "proj")
+	if err := os.MkdirAll(filepath.Join(src, "src", "empty"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "src", "main.go"), []byte("package main"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("src/main.go", filepath.Join(src, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	archivePath := filepath.Join(dir, "proj.tar.gz")
+	m, err := Create(archivePath, src, Options{Name: "proj", Template: "dev"})
+	if err != nil {
+		t.Fatalf("Create() error: %v", err)
+	}
+	if len(m.Files) != 1 || m.Files[0].Path != "src/main.go" || m.Template != "dev" {
+		t.Errorf("manifest = %+v", m)
+	}
+
+	root, err := RootName(archivePath)
+	if err != nil || root != "proj" {
+		t.Fatalf("RootName() = %q, %v; want proj", root, err)
+	}
+
+	dest := filepath.Join(dir, "out")
+	res, err := Extract(archivePath, dest)
+	if err != nil {
+		t.Fatalf("Extract() error: %v", err)
+	}
+	if res.Root != "proj" || res.Files != 1 {
+		t.Errorf("Extract() = %+v, want root proj with 1 file", res)
+	}
+	if res.Manifest == nil || len(res.Manifest.Files) != 1 {
+		t.Errorf("embedded manifest = %+v", res.Manifest)
+	}
+	if _, err := os.Stat(filepath.Join(dest, ManifestName)); !os.IsNotExist(err) {
+		t.Error("manifest should not be extracted")
+	}
+	data, err := os.ReadFile(filepath.Join(dest, "proj", "src", "main.go"))
+	if err != nil || string(data) != "package main" {
+		t.Errorf("extracted file = %q, %v", data, err)
+	}
+	if info, err := os.Stat(filepath.Join(dest, "proj", "src", "empty")); err != nil || !info.IsDir() {
+		t.Error("empty directory not restored")
+	}
+	if target, err := os.Readlink(filepath.Join(dest, "proj", "link")); err != nil || target != "src/main.go" {
+		t.Errorf("symlink = %q, %v", target, err)
+	}
+}
+
+func TestExtractRejectsUnsafeEntries(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers []*tar.Header
+		unsafe  bool
+	}{
+		{"parent traversal", []*tar.Header{
+			{Name: "proj/", Typeflag: tar.TypeDir, Mode: 0755},
+			{Name: "proj/../../evil.txt", Typeflag: tar.TypeReg, Mode: 0644, Size: 1},
+		}, true},
+		{"absolute path", []*tar.Header{
+			{Name: "/tmp/evil.txt", Typeflag: tar.TypeReg, Mode: 0644, Size: 1},
+		}, true},
+		{"escaping symlink", []*tar.Header{
+			{Name: "proj/", Typeflag: tar.TypeDir, Mode: 0755},
+			{Name: "proj/link", Typeflag: tar.TypeSymlink, Linkname: "../../etc/passwd"},
+		}, true},
+		{"several roots", []*tar.Header{
+			{Name: "a/", Typeflag: tar.TypeDir, Mode: 0755},
+			{Name: "b/", Typeflag: tar.TypeDir, Mode: 0755},
+		}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			archivePath := filepath.Join(dir, "bad.tar.gz")
+			writeTestArchive(t, archivePath, tt.headers)
+
+			dest := filepath.Join(dir, "out")
+			_, err := Extract(archivePath, dest)
+			if err == nil {
+				t.Fatal("Extract() succeeded, want error")
+			}
+			if tt.unsafe && !errors.Is(err, ErrUnsafePath) {
+				t.Errorf("Extract() error = %v, want ErrUnsafePath", err)
+			}
+			if _, err := os.Stat(filepath.Join(dir, "evil.txt")); !os.IsNotExist(err) {
+				t.Error("file written outside destination")
+			}
+		})
+	}
+}
+
+// writeTestZip writes a zip with the entries of headers.
+func writeTestZip(t *testing.T, path string, headers []*tar.Header) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for _, h := range headers {
+		fh := &zip.FileHeader{Name: h.Name}
+		switch h.Typeflag {
+		case tar.TypeDir:
+			fh.SetMode(os.ModeDir | 0755)
+		case tar.TypeSymlink:
+			fh.SetMode(os.ModeSymlink | 0777)
+		default:
+			fh.SetMode(0644)
+		}
+		w, err := zw.CreateHeader(fh)
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch h.Typeflag {
+		case tar.TypeSymlink:
+			_, err = w.Write([]byte(h.Linkname))
+		case tar.TypeReg:
+			_, err = w.Write(make([]byte, h.Size))
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExtractRejectsWritesThroughSymlinks(t *testing.T) {
+	chain := []*tar.Header{
+		{Name: "root/", Typeflag: tar.TypeDir, Mode: 0755},
+		{Name: "root/p", Typeflag: tar.TypeSymlink, Linkname: "."},
+		{Name: "root/p/p/p/q", Typeflag: tar.TypeSymlink, Linkname: "../../.."},
+		{Name: "root/p/p/p/q/PWNED", Typeflag: tar.TypeReg, Mode: 0644, Size: 1},
+	}
+	tests := []struct {
+		name    string
+		headers []*tar.Header
+	}{
+		{"symlink chain", chain},
+		{"symlink chain without file", chain[:3]},
+		{"symlink through symlink", []*tar.Header{
+			{Name: "root/", Typeflag: tar.TypeDir, Mode: 0755},
+			{Name: "root/a", Typeflag: tar.TypeSymlink, Linkname: "."},
+			{Name: "root/b", Typeflag: tar.TypeSymlink, Linkname: "a/.."},
+		}},
+	}
+
+	for _, tt := range tests {
+		for _, format := range []string{"tar.gz", "zip"} {
+			t.Run(tt.name+" "+format, func(t *testing.T) {
+				base := t.TempDir()
+				archivePath := filepath.Join(base, "bad."+format)
+				if format == "zip" {
+					writeTestZip(t, archivePath, tt.headers)
+				} else {
+					writeTestArchive(t, archivePath, tt.headers)
+				}
+
+				dest := filepath.Join(base, "a", "b", "dest")
+				_, err := Extract(archivePath, dest)
+				if !errors.Is(err, ErrUnsafePath) {
+					t.Errorf("Extract() error = %v, want ErrUnsafePath", err)
+				}
+				_ = filepath.WalkDir(base, func(p string, d os.DirEntry, err error) error {
+					if err == nil && d.Name() == "PWNED" {
+						if rel, _ := filepath.Rel(dest, p); rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+							t.Errorf("file written outside destination: %s", p)
+						}
+					}
+					return nil
+				})
+			})
+		}
+	}
+}
+
+func TestManifestVerify(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sum, size, err := hashFile(filepath.Join(dir, "a.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := &Manifest{Files: []ManifestFile{
+		{Path: "a.txt", Size: size, SHA256: sum},
+		{Path: "missing.txt", Size: 1, SHA256: sum},
+	}}
+	mismatches, err := m.Verify(dir)
+	if err != nil {
+		t.Fatalf("Verify() error: %v", err)
+	}
+	if len(mismatches) != 1 || mismatches[0].Path != "missing.txt" {
+		t.Fatalf("Verify() = %+v, want missing.txt only", mismatches)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("HELLO"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mismatches, _ = m.Verify(dir)
+	if len(mismatches) != 2 {
+		t.Errorf("Verify() after modification = %+v, want 2 mismatches", mismatches)
+	}
+}
+
+func TestVerifyArchive(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "proj")
+	if err := os.MkdirAll(src, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "a.txt"), []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	archivePath := filepath.Join(dir, "proj.tar.gz")
+	if _, err := Create(archivePath, src, Options{}); err != nil {
+		t.Fatal(err)
+	}
+
+	m, mismatches, err := VerifyArchive(archivePath, nil)
+	if err != nil || len(mismatches) != 0 || len(m.Files) != 1 {
+		t.Fatalf("VerifyArchive() = %+v, %v, %v", m, mismatches, err)
+	}
+
+	tampered := &Manifest{Files: []ManifestFile{
+		{Path: "a.txt", Size: 5, SHA256: "0000"},
+		{Path: "b.txt", Size: 1, SHA256: "0000"},
+	}}
+	_, mismatches, err = VerifyArchive(archivePath, tampered)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mismatches) != 2 || mismatches[0].Reason != "checksum mismatch" || mismatches[1].Reason != "missing from archive" {
+		t.Errorf("VerifyArchive() mismatches = %+v", mismatches)
+	}
+
+	legacy := filepath.Join(dir, "legacy.tar.gz")
+	writeTestArchive(t, legacy, []*tar.Header{{Name: "proj/", Typeflag: tar.TypeDir, Mode: 0755}})
+	if _, _, err := VerifyArchive(legacy, nil); !errors.Is(err, ErrNoManifest) {
+		t.Errorf("VerifyArchive(legacy) error = %v, want ErrNoManifest", err)
+	}
+}
+
+func TestVerifyArchiveTruncated(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "proj")
+	if err := os.MkdirAll(src, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "a.bin"), make([]byte, 1<<16), 0644); err != nil {
+		t.Fatal(err)
+	}
+	archivePath := filepath.Join(dir, "proj.tar.gz")
+	m, err := Create(archivePath, src, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(archivePath, data[:len(data)/2], 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := VerifyArchive(archivePath, m); err == nil {
+		t.Error("VerifyArchive() should fail on a truncated archive")
+	}
+}
+
+func TestCreateFormats(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "proj")
+	if err := os.MkdirAll(filepath.Join(src, "edit"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "edit", "cut.txt"), []byte("v1"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("edit/cut.txt", filepath.Join(src, "latest")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, format := range Formats {
+		t.Run(string(format), func(t *testing.T) {
+			archivePath := filepath.Join(dir, "proj"+format.Ext())
+			level := 0
+			if format.Compressed() {
+				level = 1
+			}
+			if _, err := Create(archivePath, src, Options{Format: format, Level: level}); err != nil {
+				t.Fatalf("Create() error: %v", err)
+			}
+
+			detected, err := Detect(archivePath)
+			if err != nil || detected != format {
+				t.Fatalf("Detect() = %q, %v; want %q", detected, err, format)
+			}
+			if _, mismatches, err := VerifyArchive(archivePath, nil); err != nil || len(mismatches) != 0 {
+				t.Fatalf("VerifyArchive() = %v, %v", mismatches, err)
+			}
+
+			dest := filepath.Join(dir, "out-"+string(format))
+			res, err := Extract(archivePath, dest)
+			if err != nil {
+				t.Fatalf("Extract() error: %v", err)
+			}
+			if res.Manifest == nil || res.Manifest.Format != format {
+				t.Errorf("manifest = %+v, want format %s", res.Manifest, format)
+			}
+			info, err := os.Stat(filepath.Join(dest, "proj", "edit", "cut.txt"))
+			if err != nil || info.Mode().Perm() != 0640 {
+				t.Errorf("extracted file mode = %v, %v; want 0640", info, err)
+			}
+			if target, err := os.Readlink(filepath.Join(dest, "proj", "latest")); err != nil || target != "edit/cut.txt" {
+				t.Errorf("symlink = %q, %v", target, err)
+			}
+		})
+	}
+}
+
+func TestCreateKeepsExistingOutput(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "proj")
+	if err := os.MkdirAll(src, 0755); err != nil {
+		t.Fatal(err)
+	}
+	existing := filepath.Join(dir, "proj.tar.gz")
+	if err := os.WriteFile(existing, []byte("other archive"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Create(existing, src, Options{}); !errors.Is(err, os.ErrExist) {
+		t.Fatalf("Create() error = %v, want ErrExist", err)
+	}
+	if data, _ := os.ReadFile(existing); string(data) != "other archive" {
+		t.Errorf("existing archive changed to %q", data)
+	}
+
+	if err := os.WriteFile(SidecarPath(existing), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Create(existing, src, Options{Split: 1 << 20}); !errors.Is(err, os.ErrExist) {
+		t.Fatalf("Create() split error = %v, want ErrExist", err)
+	}
+	if data, _ := os.ReadFile(SidecarPath(existing)); string(data) != "{}" {
+		t.Errorf("existing manifest changed to %q", data)
+	}
+}
+
+func TestCreateRejectsLevelForTar(t *testing.T) {
+	dir := t.TempDir()
+	archivePath := filepath.Join(dir, "proj.tar")
+	if _, err := Create(archivePath, dir, Options{Format: FormatTar, Level: 5}); err == nil {
+		t.Fatal("Create() should reject a compression level for tar")
+	}
+	if _, err := os.Stat(archivePath); !os.IsNotExist(err) {
+		t.Error("failed archive should be removed")
+	}
+}
+
+func TestCreateExcludeAndOnly(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "proj")
+	for _, f := range []string{"04_Export/Final/film.mov", "04_Export/Proxies/p1.mov", "01_Edit/cut.prproj", "01_Edit/render.cache"} {
+		p := filepath.Join(src, filepath.FromSlash(f))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(f), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(src, "02_Audio"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	files := func(m *Manifest) []string {
+		var paths []string
+		for _, f := range m.Files {
+			paths = append(paths, f.Path)
+		}
+		return paths
+	}
+
+	m, err := Create(filepath.Join(dir, "exclude.tar.gz"), src, Options{Exclude: []string{"04_Export/Proxies/", "*.cache"}})
+	if err != nil {
+		t.Fatalf("Create(exclude) error: %v", err)
+	}
+	if got := files(m); len(got) != 2 {
+		t.Errorf("files = %v, want film.mov and cut.prproj", got)
+	}
+	if len(m.Omitted) != 2 || m.Omitted[0] != "01_Edit/render.cache" || m.Omitted[1] != "04_Export/Proxies" {
+		t.Errorf("Omitted = %v", m.Omitted)
+	}
+	if m.SourceFiles != 4 {
+		t.Errorf("SourceFiles = %d, want 4 including excluded files", m.SourceFiles)
+	}
+
+	archivePath := filepath.Join(dir, "only.tar.gz")
+	m, err = Create(archivePath, src, Options{Only: []string{"04_Export/Final/**"}})
+	if err != nil {
+		t.Fatalf("Create(only) error: %v", err)
+	}
+	if got := files(m); len(got) != 1 || got[0] != "04_Export/Final/film.mov" {
+		t.Errorf("files = %v, want only film.mov", got)
+	}
+	dest := filepath.Join(dir, "out")
+	if _, err := Extract(archivePath, dest); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "proj", "02_Audio")); !os.IsNotExist(err) {
+		t.Error("--only should not archive unrelated directories")
+	}
+	if _, err := os.Stat(filepath.Join(dest, "proj", "04_Export", "Final", "film.mov")); err != nil {
+		t.Errorf("included file missing: %v", err)
+	}
+}
+
+func TestCreateProgress(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "proj")
+	if err := os.MkdirAll(filepath.Join(src, "cache"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, size := range map[string]int{"a.mov": 3000, "b.mov": 5000, "cache/x.cache": 100} {
+		if err := os.WriteFile(filepath.Join(src, filepath.FromSlash(name)), make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	opts := Options{Exclude: []string{"cache/"}}
+
+	totals, err := Measure(src, opts)
+	if err != nil || totals.Files != 2 || totals.Bytes != 8000 {
+		t.Fatalf("Measure() = %+v, %v; want 2 files, 8000 bytes", totals, err)
+	}
+
+	var last Progress
+	calls := 0
+	opts.Progress = func(p Progress) {
+		calls++
+		last = p
+	}
+	if _, err := Create(filepath.Join(dir, "proj.tar.gz"), src, opts); err != nil {
+		t.Fatal(err)
+	}
+	if calls == 0 || last.Files != 2 || last.Bytes != 8000 || last.Total != totals {
+		t.Errorf("last progress = %+v after %d call(s)", last, calls)
+	}
+}
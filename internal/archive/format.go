@@ -0,0 +1,315 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Format is an archive container format.
+type Format string
+
+// Supported archive formats.
+const (
+	FormatTar   Format = "tar"
+	FormatTarGz Format = "tar.gz"
+	FormatZip   Format = "zip"
+)
+
+// DefaultFormat is used when neither a flag nor the template selects one.
+const DefaultFormat = FormatTarGz
+
+// Formats lists the supported formats.
+var Formats = []Format{FormatTar, FormatTarGz, FormatZip}
+
+// ParseFormat validates a format name. "tgz" is accepted for tar.gz.
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(s) {
+	case "tar":
+		return FormatTar, nil
+	case "tar.gz", "tgz":
+		return FormatTarGz, nil
+	case "zip":
+		return FormatZip, nil
+	}
+	return "", fmt.Errorf("unknown archive format %q: use tar, tar.gz, or zip", s)
+}
+
+// Ext returns the file extension for the format, including the dot.
+func (f Format) Ext() string {
+	return "." + string(f)
+}
+
+// Compressed reports whether the format supports a compression level.
+func (f Format) Compressed() bool {
+	return f != FormatTar
+}
+
+// Detect determines the format of an archive from its leading bytes. Split
+// archives are detected from their first volume.
+func Detect(archivePath string) (Format, error) {
+	src, err := openSource(archivePath)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+	return detect(src)
+}
+
+func detect(src *source) (Format, error) {
+	head := make([]byte, 512)
+	n, err := src.ReadAt(head, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("reading archive: %w", err)
+	}
+	head = head[:n]
+
+	switch {
+	case bytes.HasPrefix(head, []byte{0x1f, 0x8b}):
+		return FormatTarGz, nil
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")), bytes.HasPrefix(head, []byte("PK\x05\x06")):
+		return FormatZip, nil
+	case len(head) >= 262 && string(head[257:262]) == "ustar":
+		return FormatTar, nil
+	}
+	return "", errors.New("unrecognized archive format")
+}
+
+// entry is one member of an archive, independent of its container format.
+// Typeflag uses the tar constants for directories, regular files and
+// symlinks.
+type entry struct {
+	Name     string
+	Typeflag byte
+	Mode     os.FileMode
+	Linkname string
+	Body     io.Reader
+}
+
+// errStop ends readEntries early without an error.
+var errStop = errors.New("stop")
+
+// readEntries calls fn for every member of the archive in order, detecting
+// the format automatically. Split archives are read across all volumes.
+func readEntries(archivePath string, fn func(*entry) error) error {
+	src, err := openSource(archivePath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	format, err := detect(src)
+	if err != nil {
+		return fmt.Errorf("%s: %w", archivePath, err)
+	}
+	if format == FormatZip {
+		err = readZip(src, fn)
+	} else {
+		err = readTar(src, format, fn)
+	}
+	if errors.Is(err, errStop) {
+		return nil
+	}
+	return err
+}
+
+func readTar(src *source, format Format, fn func(*entry) error) error {
+	var r io.Reader = bufio.NewReader(src.Reader())
+	if format == FormatTarGz {
+		gr, err := gzip.NewReader(r)
+		if err != nil {
+			return fmt.Errorf("reading archive: %w", err)
+		}
+		defer gr.Close()
+		r = gr
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading archive: %w", err)
+		}
+		e := &entry{
+			Name:     header.Name,
+			Typeflag: header.Typeflag,
+			Mode:     os.FileMode(header.Mode).Perm(),
+			Linkname: header.Linkname,
+			Body:     tr,
+		}
+		if e.Typeflag == tar.TypeRegA {
+			e.Typeflag = tar.TypeReg
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+}
+
+func readZip(src *source, fn func(*entry) error) error {
+	zr, err := zip.NewReader(src, src.size)
+	if err != nil {
+		return fmt.Errorf("reading archive: %w", err)
+	}
+
+	for _, zf := range zr.File {
+		mode := zf.Mode()
+		e := &entry{Name: zf.Name, Mode: mode.Perm()}
+		switch {
+		case mode.IsDir() || strings.HasSuffix(zf.Name, "/"):
+			e.Typeflag = tar.TypeDir
+		case mode&os.ModeSymlink != 0:
+			e.Typeflag = tar.TypeSymlink
+		case mode.IsRegular():
+			e.Typeflag = tar.TypeReg
+		default:
+			e.Typeflag = tar.TypeChar
+		}
+
+		if e.Typeflag == tar.TypeDir {
+			if err := fn(e); err != nil {
+				return err
+			}
+			continue
+		}
+
+		rc, err := zf.Open()
+		if err != nil {
+			return fmt.Errorf("reading archive: %w", err)
+		}
+		if e.Typeflag == tar.TypeSymlink {
+			link, err := io.ReadAll(io.LimitReader(rc, 4096))
+			rc.Close()
+			if err != nil {
+				return fmt.Errorf("reading archive: %w", err)
+			}
+			e.Linkname = string(link)
+		} else {
+			e.Body = rc
+		}
+		err = fn(e)
+		if rc != nil && e.Body != nil {
+			if closeErr := rc.Close(); err == nil && closeErr != nil {
+				err = fmt.Errorf("reading archive: %w", closeErr)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// entryWriter writes members described by tar headers to an archive.
+type entryWriter interface {
+	WriteEntry(header *tar.Header, body io.Reader) error
+	Close() error
+}
+
+// newEntryWriter returns a writer for format on top of w. level is a
+// compression level from 1 (fastest) to 9 (smallest), or 0 for the default.
+func newEntryWriter(w io.Writer, format Format, level int) (entryWriter, error) {
+	if level < 0 || level > 9 {
+		return nil, fmt.Errorf("compression level %d out of range 1-9", level)
+	}
+	if level != 0 && !format.Compressed() {
+		return nil, fmt.Errorf("format %s does not support a compression level", format)
+	}
+	if level == 0 {
+		level = flate.DefaultCompression
+	}
+
+	switch format {
+	case FormatTar:
+		return &tarWriter{tw: tar.NewWriter(w)}, nil
+	case FormatTarGz:
+		gw, err := newParallelGzipWriter(w, level)
+		if err != nil {
+			return nil, err
+		}
+		return &tarWriter{tw: tar.NewWriter(gw), gw: gw}, nil
+	case FormatZip:
+		zw := zip.NewWriter(w)
+		zw.RegisterCompressor(zip.Deflate, func(out io.Writer) (io.WriteCloser, error) {
+			return flate.NewWriter(out, level)
+		})
+		return &zipWriter{zw: zw}, nil
+	}
+	return nil, fmt.Errorf("unknown archive format %q", format)
+}
+
+type tarWriter struct {
+	tw *tar.Writer
+	gw io.WriteCloser
+}
+
+func (t *tarWriter) WriteEntry(header *tar.Header, body io.Reader) error {
+	if err := t.tw.WriteHeader(header); err != nil {
+		return err
+	}
+	if body == nil {
+		return nil
+	}
+	_, err := io.Copy(t.tw, body)
+	return err
+}
+
+func (t *tarWriter) Close() error {
+	if err := t.tw.Close(); err != nil {
+		return err
+	}
+	if t.gw != nil {
+		return t.gw.Close()
+	}
+	return nil
+}
+
+type zipWriter struct {
+	zw *zip.Writer
+}
+
+func (z *zipWriter) WriteEntry(header *tar.Header, body io.Reader) error {
+	zh := &zip.FileHeader{Name: header.Name, Modified: header.ModTime}
+	zh.SetMode(header.FileInfo().Mode())
+
+	switch header.Typeflag {
+	case tar.TypeDir:
+		zh.Method = zip.Store
+		_, err := z.zw.CreateHeader(zh)
+		return err
+	case tar.TypeSymlink:
+		zh.Method = zip.Store
+		w, err := z.zw.CreateHeader(zh)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, header.Linkname)
+		return err
+	}
+
+	zh.Method = zip.Deflate
+	w, err := z.zw.CreateHeader(zh)
+	if err != nil {
+		return err
+	}
+	if body == nil {
+		return nil
+	}
+	_, err = io.Copy(w, body)
+	return err
+}
+
+func (z *zipWriter) Close() error {
+	return z.zw.Close()
+}
@@ -0,0 +1,228 @@
+package archive
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ManifestName is the name of the manifest entry embedded at the top level
+// of an archive, next to the project directory.
+const ManifestName = ".prjct-manifest.json"
+
+// ManifestVersion is the current manifest schema version.
+const ManifestVersion = 1
+
+// Manifest lists the files of an archived project with their checksums.
+type Manifest struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	Source    string    `json:"source"`
+	Root      string    `json:"root"`
+	Name      string    `json:"name,omitempty"`
+	Template  string    `json:"template,omitempty"`
+	Format    Format    `json:"format,omitempty"`
+	// Exclude and Only are the patterns the archive was created with.
+	// Omitted lists the paths left out by Exclude; a directory is listed
+	// once, without its contents.
+	Exclude []string `json:"exclude,omitempty"`
+	Only    []string `json:"only,omitempty"`
+	Omitted []string `json:"omitted,omitempty"`
+	// SourceFiles is the number of regular files in the project when it
+	// was archived, before Exclude and Only were applied.
+	SourceFiles int `json:"source_files,omitempty"`
+	// Volumes lists the parts of a split archive. It is only present in
+	// the sidecar manifest, since the embedded one is written before the
+	// volumes are complete.
+	Volumes []Volume       `json:"volumes,omitempty"`
+	Files   []ManifestFile `json:"files"`
+}
+
+// ManifestFile is a single file entry. Path is relative to the project
+// root and uses forward slashes.
+type ManifestFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Mismatch describes a file that failed verification.
+type Mismatch struct {
+	Path   string
+	Reason string
+}
+
+// ErrNoManifest is returned by VerifyArchive when an archive has neither an
+// embedded nor a sidecar manifest.
+var ErrNoManifest = errors.New("archive has no manifest")
+
+// SidecarPath returns the path of the manifest stored next to an archive.
+func SidecarPath(archivePath string) string {
+	return archivePath + ".manifest.json"
+}
+
+// ReadSidecar loads the manifest stored next to archivePath. It returns
+// nil without error if there is none.
+func ReadSidecar(archivePath string) (*Manifest, error) {
+	f, err := os.Open(SidecarPath(BasePath(archivePath)))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	return decodeManifest(f)
+}
+
+// writeSidecar stores m next to archivePath.
+func writeSidecar(archivePath string, m *Manifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(SidecarPath(archivePath), append(data, '\n'), 0644)
+}
+
+func decodeManifest(r io.Reader) (*Manifest, error) {
+	var m Manifest
+	if err := json.NewDecoder(r).Decode(&m); err != nil {
+		return nil, fmt.Errorf("corrupt manifest: %w", err)
+	}
+	return &m, nil
+}
+
+// Verify checks the files of the project directory dir against the
+// manifest and returns every missing or changed file.
+func (m *Manifest) Verify(dir string) ([]Mismatch, error) {
+	var mismatches []Mismatch
+	for _, f := range m.Files {
+		p := filepath.Join(dir, filepath.FromSlash(f.Path))
+		sum, size, err := hashFile(p)
+		if errors.Is(err, os.ErrNotExist) {
+			mismatches = append(mismatches, Mismatch{Path: f.Path, Reason: "missing"})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if size != f.Size || sum != f.SHA256 {
+			mismatches = append(mismatches, Mismatch{Path: f.Path, Reason: "checksum mismatch"})
+		}
+	}
+	return mismatches, nil
+}
+
+// VerifyArchive re-reads archivePath, hashing every file it contains, and
+// compares the result against m. If m is nil, the manifest embedded in the
+// archive or stored next to it is used. For split archives, the volume
+// checksums are checked first. It returns the manifest checked against and
+// every missing, changed or unexpected file or volume.
+func VerifyArchive(archivePath string, m *Manifest) (*Manifest, []Mismatch, error) {
+	archivePath = BasePath(archivePath)
+	sidecar, err := ReadSidecar(archivePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	if sidecar != nil && len(sidecar.Volumes) > 0 {
+		mismatches, err := verifyVolumes(archivePath, sidecar.Volumes)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(mismatches) > 0 {
+			if m == nil {
+				m = sidecar
+			}
+			return m, mismatches, nil
+		}
+	}
+
+	files, embedded, err := scan(archivePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	if m == nil {
+		m = embedded
+	}
+	if m == nil {
+		m = sidecar
+	}
+	if m == nil {
+		return nil, nil, ErrNoManifest
+	}
+
+	var mismatches []Mismatch
+	for _, want := range m.Files {
+		got, ok := files[want.Path]
+		switch {
+		case !ok:
+			mismatches = append(mismatches, Mismatch{Path: want.Path, Reason: "missing from archive"})
+		case got.Size != want.Size || got.SHA256 != want.SHA256:
+			mismatches = append(mismatches, Mismatch{Path: want.Path, Reason: "checksum mismatch"})
+		}
+		delete(files, want.Path)
+	}
+	var extra []string
+	for p := range files {
+		extra = append(extra, p)
+	}
+	sort.Strings(extra)
+	for _, p := range extra {
+		mismatches = append(mismatches, Mismatch{Path: p, Reason: "not in manifest"})
+	}
+	return m, mismatches, nil
+}
+
+// scan hashes the regular files of an archive, keyed by their path below
+// the archive root, and returns the embedded manifest if there is one.
+func scan(archivePath string) (map[string]ManifestFile, *Manifest, error) {
+	files := make(map[string]ManifestFile)
+	var m *Manifest
+	err := readEntries(archivePath, func(e *entry) error {
+		if e.Name == ManifestName {
+			var err error
+			m, err = decodeManifest(e.Body)
+			return err
+		}
+		if e.Typeflag != tar.TypeReg {
+			return nil
+		}
+		h := sha256.New()
+		n, err := io.Copy(h, e.Body)
+		if err != nil {
+			return fmt.Errorf("reading archive: %w", err)
+		}
+		_, rel, _ := strings.Cut(path.Clean(e.Name), "/")
+		files[rel] = ManifestFile{Path: rel, Size: n, SHA256: hex.EncodeToString(h.Sum(nil))}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return files, m, nil
+}
+
+// hashFile returns the hex SHA-256 and size of the file at p.
+func hashFile(p string) (string, int64, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
@@ -0,0 +1,159 @@
+package archive
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Patterns matches slash-separated paths relative to a project root against
+// gitignore-style patterns:
+//
+//   - "*" and "?" match within one path segment, "**" across segments
+//   - a pattern without a slash matches the name at any depth ("*.cache")
+//   - a pattern with a slash is anchored at the root ("04_Export/Proxies/**");
+//     a leading slash only anchors
+//   - a trailing slash matches directories only ("tmp/")
+//   - a leading "!" re-includes paths matched by an earlier pattern
+//   - blank lines and lines starting with "#" are ignored
+//
+// The last matching pattern decides.
+type Patterns struct {
+	rules []patternRule
+}
+
+type patternRule struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// CompilePatterns parses gitignore-style patterns.
+func CompilePatterns(patterns []string) (*Patterns, error) {
+	p := &Patterns{}
+	for _, raw := range patterns {
+		line := strings.TrimSpace(raw)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule := patternRule{}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		anchored := strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+		if line == "" {
+			return nil, fmt.Errorf("invalid pattern %q", raw)
+		}
+
+		expr := globToRegexp(line)
+		if anchored {
+			expr = "^" + expr + "$"
+		} else {
+			expr = "^(?:.*/)?" + expr + "$"
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", raw, err)
+		}
+		rule.re = re
+		p.rules = append(p.rules, rule)
+	}
+	return p, nil
+}
+
+// Empty reports whether there are no patterns.
+func (p *Patterns) Empty() bool {
+	return p == nil || len(p.rules) == 0
+}
+
+// Match reports whether rel, a slash-separated path relative to the root,
+// is matched. isDir tells whether rel is a directory.
+func (p *Patterns) Match(rel string, isDir bool) bool {
+	if p == nil {
+		return false
+	}
+	matched := false
+	for _, r := range p.rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		if r.re.MatchString(rel) {
+			matched = !r.negate
+		}
+	}
+	return matched
+}
+
+// MatchPath reports whether rel or any of its parent directories is
+// matched.
+func (p *Patterns) MatchPath(rel string, isDir bool) bool {
+	if p.Match(rel, isDir) {
+		return true
+	}
+	for dir := parentDir(rel); dir != ""; dir = parentDir(dir) {
+		if p.Match(dir, true) {
+			return true
+		}
+	}
+	return false
+}
+
+func parentDir(rel string) string {
+	i := strings.LastIndex(rel, "/")
+	if i < 0 {
+		return ""
+	}
+	return rel[:i]
+}
+
+// globToRegexp translates a glob into a regular expression body.
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				i++
+				switch {
+				case i+1 < len(glob) && glob[i+1] == '/':
+					// "**/" matches zero or more directories
+					i++
+					b.WriteString("(?:.*/)?")
+				default:
+					b.WriteString(".*")
+				}
+				continue
+			}
+			b.WriteString("[^/]*")
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+				b.WriteString(regexp.QuoteMeta(string(glob[i])))
+			}
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
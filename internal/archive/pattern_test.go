@@ -0,0 +1,69 @@
+package archive
+
+import "testing"
+
+func TestPatternsMatch(t *testing.T) {
+	p, err := CompilePatterns([]string{
+		"# render caches",
+		"*.cache",
+		"04_Export/Proxies/**",
+		"/tmp/",
+		".DS_Store",
+		"Renders/**/*.exr",
+		"!keep.cache",
+	})
+	if err != nil {
+		t.Fatalf("CompilePatterns() error: %v", err)
+	}
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"a.cache", false, true},
+		{"deep/dir/b.cache", false, true},
+		{"keep.cache", false, false},
+		{"04_Export/Proxies/clip.mov", false, true},
+		{"04_Export/Proxies/sub/clip.mov", false, true},
+		{"04_Export/Proxies", true, false},
+		{"04_Export/Final/clip.mov", false, false},
+		{"tmp", true, true},
+		{"tmp", false, false},
+		{"src/tmp", true, false},
+		{"Edit/.DS_Store", false, true},
+		{"Renders/frame.exr", false, true},
+		{"Renders/a/b/frame.exr", false, true},
+		{"Renders/frame.png", false, false},
+	}
+	for _, tt := range tests {
+		if got := p.Match(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("Match(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestPatternsMatchPath(t *testing.T) {
+	p, err := CompilePatterns([]string{"04_Export/Final"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.MatchPath("04_Export/Final/a/clip.mov", false) {
+		t.Error("MatchPath should match files below a matched directory")
+	}
+	if p.MatchPath("04_Export/Drafts/clip.mov", false) {
+		t.Error("MatchPath matched an unrelated path")
+	}
+}
+
+func TestCompilePatternsInvalid(t *testing.T) {
+	for _, pattern := range []string{"/", "!/"} {
+		if _, err := CompilePatterns([]string{pattern}); err == nil {
+			t.Errorf("CompilePatterns(%q) should fail", pattern)
+		}
+	}
+	p, err := CompilePatterns(nil)
+	if err != nil || !p.Empty() {
+		t.Errorf("CompilePatterns(nil) = %v, %v; want empty", p, err)
+	}
+}
@@ -0,0 +1,177 @@
+package archive
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"hash/crc32"
+	"io"
+	"runtime"
+	"sync/atomic"
+)
+
+const (
+	// gzipBlockSize is the amount of input compressed per goroutine.
+	gzipBlockSize = 1 << 20
+	// gzipDictSize is the deflate window carried over between blocks.
+	gzipDictSize = 32 << 10
+)
+
+// parallelGzipWriter compresses blocks of input concurrently and writes a
+// single standard gzip member, like pigz. Each block is deflated with the
+// tail of the previous block as its dictionary and ends with a sync flush,
+// so the concatenated blocks form one deflate stream.
+type parallelGzipWriter struct {
+	w     io.Writer
+	level int
+
+	buf  []byte
+	dict []byte
+	crc  hash.Hash32
+	size uint32
+
+	queue  chan chan gzipBlock
+	done   chan error
+	failed atomic.Bool
+	err    error
+}
+
+var errGzipClosed = errors.New("gzip writer is closed")
+
+type gzipBlock struct {
+	data []byte
+	err  error
+}
+
+// newParallelGzipWriter returns a writer compressing at level with up to
+// GOMAXPROCS blocks in flight.
+func newParallelGzipWriter(w io.Writer, level int) (*parallelGzipWriter, error) {
+	// Validate the level up front; blocks are compressed later.
+	if _, err := flate.NewWriter(io.Discard, level); err != nil {
+		return nil, err
+	}
+	z := &parallelGzipWriter{
+		w:     w,
+		level: level,
+		buf:   make([]byte, 0, gzipBlockSize),
+		crc:   crc32.NewIEEE(),
+		queue: make(chan chan gzipBlock, runtime.GOMAXPROCS(0)),
+		done:  make(chan error, 1),
+	}
+
+	// Header: magic, deflate, no flags, no mtime, no extra flags, unknown OS.
+	header := []byte{0x1f, 0x8b, 8, 0, 0, 0, 0, 0, 0, 255}
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+
+	go z.drain()
+	return z, nil
+}
+
+// drain writes compressed blocks in order.
+func (z *parallelGzipWriter) drain() {
+	var err error
+	for ch := range z.queue {
+		block := <-ch
+		if err != nil {
+			continue
+		}
+		if block.err != nil {
+			err = block.err
+			z.failed.Store(true)
+			continue
+		}
+		_, err = z.w.Write(block.data)
+		if err != nil {
+			z.failed.Store(true)
+		}
+	}
+	z.done <- err
+}
+
+func (z *parallelGzipWriter) Write(p []byte) (int, error) {
+	if z.err != nil {
+		return 0, z.err
+	}
+	if z.failed.Load() {
+		// Stop early; Close reports the underlying error.
+		return 0, z.Close()
+	}
+	z.crc.Write(p)
+	z.size += uint32(len(p))
+
+	n := len(p)
+	for len(p) > 0 {
+		room := gzipBlockSize - len(z.buf)
+		if room > len(p) {
+			room = len(p)
+		}
+		z.buf = append(z.buf, p[:room]...)
+		p = p[room:]
+		if len(z.buf) == gzipBlockSize {
+			z.dispatch(false)
+		}
+	}
+	return n, nil
+}
+
+// dispatch hands the buffered block to a new goroutine. It blocks while
+// GOMAXPROCS blocks are already in flight.
+func (z *parallelGzipWriter) dispatch(final bool) {
+	block := z.buf
+	dict := z.dict
+	ch := make(chan gzipBlock, 1)
+	z.queue <- ch
+
+	go func() {
+		var out bytes.Buffer
+		fw, err := flate.NewWriterDict(&out, z.level, dict)
+		if err == nil {
+			_, err = fw.Write(block)
+		}
+		if err == nil {
+			if final {
+				err = fw.Close()
+			} else {
+				err = fw.Flush()
+			}
+		}
+		ch <- gzipBlock{data: out.Bytes(), err: err}
+	}()
+
+	if len(block) >= gzipDictSize {
+		z.dict = block[len(block)-gzipDictSize:]
+	} else {
+		z.dict = append(append([]byte{}, z.dict...), block...)
+		if len(z.dict) > gzipDictSize {
+			z.dict = z.dict[len(z.dict)-gzipDictSize:]
+		}
+	}
+	z.buf = make([]byte, 0, gzipBlockSize)
+}
+
+// Close compresses the remaining input and writes the gzip trailer.
+func (z *parallelGzipWriter) Close() error {
+	if z.err != nil {
+		return z.err
+	}
+	z.dispatch(true)
+	close(z.queue)
+	if err := <-z.done; err != nil {
+		z.err = err
+		return err
+	}
+
+	trailer := make([]byte, 8)
+	binary.LittleEndian.PutUint32(trailer[0:4], z.crc.Sum32())
+	binary.LittleEndian.PutUint32(trailer[4:8], z.size)
+	if _, err := z.w.Write(trailer); err != nil {
+		z.err = err
+		return err
+	}
+	z.err = errGzipClosed
+	return nil
+}
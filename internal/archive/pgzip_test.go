@@ -0,0 +1,82 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"math/rand"
+	"testing"
+)
+
+func TestParallelGzipWriter(t *testing.T) {
+	// Mix compressible and random data across several blocks, with a
+	// partial last block.
+	rng := rand.New(rand.NewSource(1))
+	var input bytes.Buffer
+	for input.Len() < 3*gzipBlockSize+12345 {
+		if rng.Intn(2) == 0 {
+			input.Write(bytes.Repeat([]byte("prjct archive "), 500))
+		} else {
+			chunk := make([]byte, 4096)
+			rng.Read(chunk)
+			input.Write(chunk)
+		}
+	}
+
+	for _, level := range []int{-1, 1, 9} {
+		var out bytes.Buffer
+		z, err := newParallelGzipWriter(&out, level)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Odd write sizes exercise block boundaries.
+		data := input.Bytes()
+		for len(data) > 0 {
+			n := 70001
+			if n > len(data) {
+				n = len(data)
+			}
+			if _, err := z.Write(data[:n]); err != nil {
+				t.Fatal(err)
+			}
+			data = data[n:]
+		}
+		if err := z.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		gr, err := gzip.NewReader(&out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gr.Multistream(false) // must be a single gzip member
+		got, err := io.ReadAll(gr)
+		if err != nil {
+			t.Fatalf("level %d: reading output: %v", level, err)
+		}
+		if !bytes.Equal(got, input.Bytes()) {
+			t.Fatalf("level %d: round trip mismatch (%d vs %d bytes)", level, len(got), input.Len())
+		}
+		if rest, _ := io.ReadAll(&out); len(rest) != 0 {
+			t.Errorf("level %d: %d trailing bytes after the gzip member", level, len(rest))
+		}
+	}
+}
+
+func TestParallelGzipWriterEmpty(t *testing.T) {
+	var out bytes.Buffer
+	z, err := newParallelGzipWriter(&out, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+	gr, err := gzip.NewReader(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := io.ReadAll(gr); err != nil || len(got) != 0 {
+		t.Errorf("ReadAll() = %d bytes, %v", len(got), err)
+	}
+}
@@ -0,0 +1,77 @@
+package archive
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Totals are the number of files and bytes an archive will contain.
+type Totals struct {
+	Files int
+	Bytes int64
+}
+
+// Progress reports how much of an archive has been written so far.
+type Progress struct {
+	Files int
+	Bytes int64
+	Total Totals
+}
+
+// Measure walks sourcePath with the exclude and only patterns of opts and
+// returns the totals Create will write.
+func Measure(sourcePath string, opts Options) (Totals, error) {
+	var t Totals
+	exclude, err := CompilePatterns(opts.Exclude)
+	if err != nil {
+		return t, err
+	}
+	only, err := CompilePatterns(opts.Only)
+	if err != nil {
+		return t, err
+	}
+	root, err := filepath.Abs(sourcePath)
+	if err != nil {
+		return t, err
+	}
+
+	err = walkSource(root, exclude, only, func(rel string, d os.DirEntry) error {
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		t.Files++
+		t.Bytes += info.Size()
+		return nil
+	}, nil)
+	return t, err
+}
+
+// tracker accumulates progress and passes it to the caller's callback.
+type tracker struct {
+	state Progress
+	fn    func(Progress)
+}
+
+func (t *tracker) report() {
+	t.fn(t.state)
+}
+
+// progressReader counts the bytes read through it.
+type progressReader struct {
+	r     io.Reader
+	track *tracker
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		p.track.state.Bytes += int64(n)
+		p.track.report()
+	}
+	return n, err
+}
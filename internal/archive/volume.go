@@ -0,0 +1,261 @@
+package archive
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Volume is one numbered part of a split archive. Name is the file name,
+// relative to the directory of the archive.
+type Volume struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// VolumePath returns the path of volume n (starting at 1) of a split
+// archive, e.g. project.tar.gz.001.
+func VolumePath(archivePath string, n int) string {
+	return fmt.Sprintf("%s.%03d", archivePath, n)
+}
+
+// BasePath returns the archive path for the first volume of a split
+// archive (project.tar.gz.001 → project.tar.gz) and any other path
+// unchanged.
+func BasePath(archivePath string) string {
+	if strings.HasSuffix(archivePath, ".001") {
+		if _, err := os.Stat(archivePath); err == nil {
+			return strings.TrimSuffix(archivePath, ".001")
+		}
+	}
+	return archivePath
+}
+
+// Exists reports whether archivePath is an archive file or the base path of
+// a split archive.
+func Exists(archivePath string) bool {
+	_, err := Files(archivePath)
+	return err == nil
+}
+
+// Files returns the files making up an archive: the file itself, or its
+// numbered volumes in order, as listed in the sidecar manifest if there is
+// one.
+func Files(archivePath string) ([]string, error) {
+	archivePath = BasePath(archivePath)
+	if info, err := os.Stat(archivePath); err == nil && !info.IsDir() {
+		return []string{archivePath}, nil
+	}
+	if m, err := ReadSidecar(archivePath); err == nil && m != nil && len(m.Volumes) > 0 {
+		dir := filepath.Dir(archivePath)
+		paths := make([]string, len(m.Volumes))
+		for i, v := range m.Volumes {
+			paths[i] = filepath.Join(dir, v.Name)
+		}
+		return paths, nil
+	}
+	var paths []string
+	for n := 1; ; n++ {
+		p := VolumePath(archivePath, n)
+		if _, err := os.Stat(p); err != nil {
+			break
+		}
+		paths = append(paths, p)
+	}
+	if len(paths) == 0 {
+		return nil, &os.PathError{Op: "open", Path: archivePath, Err: os.ErrNotExist}
+	}
+	return paths, nil
+}
+
+// VerifyVolumes checks the volumes of a split archive against the
+// checksums in its sidecar manifest. It returns nil for archives that are
+// not split.
+func VerifyVolumes(archivePath string) ([]Mismatch, error) {
+	archivePath = BasePath(archivePath)
+	m, err := ReadSidecar(archivePath)
+	if err != nil || m == nil {
+		return nil, err
+	}
+	return verifyVolumes(archivePath, m.Volumes)
+}
+
+func verifyVolumes(archivePath string, volumes []Volume) ([]Mismatch, error) {
+	var mismatches []Mismatch
+	dir := filepath.Dir(archivePath)
+	for _, v := range volumes {
+		sum, size, err := hashFile(filepath.Join(dir, v.Name))
+		if errors.Is(err, os.ErrNotExist) {
+			mismatches = append(mismatches, Mismatch{Path: v.Name, Reason: "volume missing"})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if size != v.Size || sum != v.SHA256 {
+			mismatches = append(mismatches, Mismatch{Path: v.Name, Reason: "volume checksum mismatch"})
+		}
+	}
+	return mismatches, nil
+}
+
+// volumeWriter writes a byte stream into numbered files of at most limit
+// bytes each, hashing every volume.
+type volumeWriter struct {
+	base  string
+	limit int64
+
+	f       *os.File
+	h       hash.Hash
+	n       int64
+	paths   []string
+	volumes []Volume
+}
+
+func (v *volumeWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		if v.f == nil {
+			path := VolumePath(v.base, len(v.paths)+1)
+			f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+			if err != nil {
+				return written, err
+			}
+			v.f, v.h, v.n = f, sha256.New(), 0
+			v.paths = append(v.paths, path)
+		}
+
+		chunk := p
+		if room := v.limit - v.n; int64(len(chunk)) > room {
+			chunk = chunk[:room]
+		}
+		n, err := v.f.Write(chunk)
+		v.h.Write(chunk[:n])
+		v.n += int64(n)
+		written += n
+		p = p[n:]
+		if err != nil {
+			return written, err
+		}
+		if v.n == v.limit {
+			if err := v.finish(); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+// finish closes the current volume and records its checksum.
+func (v *volumeWriter) finish() error {
+	err := v.f.Close()
+	v.volumes = append(v.volumes, Volume{
+		Name:   filepath.Base(v.paths[len(v.paths)-1]),
+		Size:   v.n,
+		SHA256: hex.EncodeToString(v.h.Sum(nil)),
+	})
+	v.f = nil
+	return err
+}
+
+func (v *volumeWriter) Close() error {
+	if v.f == nil {
+		return nil
+	}
+	return v.finish()
+}
+
+// remove deletes every volume written so far.
+func (v *volumeWriter) remove() {
+	if v.f != nil {
+		v.f.Close()
+	}
+	for _, p := range v.paths {
+		os.Remove(p)
+	}
+}
+
+// source is a read-only view of an archive that may span several volumes.
+type source struct {
+	parts []sourcePart
+	size  int64
+}
+
+type sourcePart struct {
+	f    *os.File
+	off  int64
+	size int64
+}
+
+// openSource opens an archive or all volumes of a split archive as one
+// contiguous stream.
+func openSource(archivePath string) (*source, error) {
+	paths, err := Files(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	s := &source{}
+	for _, p := range paths {
+		f, err := os.Open(p)
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+		info, err := f.Stat()
+		if err != nil {
+			f.Close()
+			s.Close()
+			return nil, err
+		}
+		s.parts = append(s.parts, sourcePart{f: f, off: s.size, size: info.Size()})
+		s.size += info.Size()
+	}
+	return s, nil
+}
+
+// ReadAt reads across volume boundaries.
+func (s *source) ReadAt(p []byte, off int64) (int, error) {
+	if off >= s.size {
+		return 0, io.EOF
+	}
+	i := sort.Search(len(s.parts), func(i int) bool {
+		return s.parts[i].off+s.parts[i].size > off
+	})
+	read := 0
+	for ; i < len(s.parts) && read < len(p); i++ {
+		part := s.parts[i]
+		n, err := part.f.ReadAt(p[read:min(len(p), read+int(part.off+part.size-off))], off-part.off)
+		read += n
+		off += int64(n)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return read, err
+		}
+	}
+	if read < len(p) {
+		return read, io.EOF
+	}
+	return read, nil
+}
+
+// Reader returns a sequential reader over the whole archive.
+func (s *source) Reader() io.Reader {
+	return io.NewSectionReader(s, 0, s.size)
+}
+
+func (s *source) Close() error {
+	var first error
+	for _, part := range s.parts {
+		if err := part.f.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
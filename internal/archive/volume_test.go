@@ -0,0 +1,75 @@
+package archive
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCreateSplit(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "proj")
+	if err := os.MkdirAll(src, 0755); err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 200<<10)
+	rand.New(rand.NewSource(1)).Read(data)
+	if err := os.WriteFile(filepath.Join(src, "clip.mov"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, format := range Formats {
+		t.Run(string(format), func(t *testing.T) {
+			archivePath := filepath.Join(dir, "proj"+format.Ext())
+			// A stale volume from an earlier, larger run must not be stitched in.
+			if err := os.WriteFile(VolumePath(archivePath, 5), []byte("stale"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			m, err := Create(archivePath, src, Options{Format: format, Split: 64 << 10})
+			if err != nil {
+				t.Fatalf("Create() error: %v", err)
+			}
+			if len(m.Volumes) != 4 {
+				t.Fatalf("got %d volumes, want 4", len(m.Volumes))
+			}
+			if _, err := os.Stat(archivePath); !os.IsNotExist(err) {
+				t.Error("split archive should not write the unsplit file")
+			}
+			if _, err := os.Stat(VolumePath(archivePath, 5)); !os.IsNotExist(err) {
+				t.Error("stale volume should be removed")
+			}
+
+			for _, p := range []string{archivePath, VolumePath(archivePath, 1)} {
+				if !Exists(p) {
+					t.Errorf("Exists(%q) = false", p)
+				}
+				if _, mismatches, err := VerifyArchive(p, nil); err != nil || len(mismatches) != 0 {
+					t.Errorf("VerifyArchive(%q) = %v, %v", p, mismatches, err)
+				}
+			}
+
+			dest := filepath.Join(dir, "out-"+string(format))
+			if _, err := Extract(archivePath, dest); err != nil {
+				t.Fatalf("Extract() error: %v", err)
+			}
+			got, err := os.ReadFile(filepath.Join(dest, "proj", "clip.mov"))
+			if err != nil || len(got) != len(data) || string(got) != string(data) {
+				t.Errorf("stitched file differs (%d bytes, %v)", len(got), err)
+			}
+
+			// Damage the second volume.
+			if err := os.WriteFile(VolumePath(archivePath, 2), []byte("x"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			mismatches, err := VerifyVolumes(archivePath)
+			if err != nil || len(mismatches) != 1 || mismatches[0].Path != filepath.Base(VolumePath(archivePath, 2)) {
+				t.Errorf("VerifyVolumes() = %v, %v", mismatches, err)
+			}
+			if _, mismatches, err := VerifyArchive(archivePath, nil); err != nil || len(mismatches) != 1 {
+				t.Errorf("VerifyArchive() after damage = %v, %v", mismatches, err)
+			}
+		})
+	}
+}
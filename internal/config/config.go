@@ -2,10 +2,15 @@ package config
 
 import (
 	"fmt"
+	"maps"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -17,10 +22,30 @@ type FileTemplate struct {
 }
 
 // Variable represents a user-prompted variable for template expansion.
+// Persisted variables are also stored as project metadata.
 type Variable struct {
 	Name    string `yaml:"name"`
 	Prompt  string `yaml:"prompt,omitempty"`
 	Default string `yaml:"default,omitempty"`
+	Persist bool   `yaml:"persist,omitempty"`
+}
+
+// Metadata field types.
+const (
+	MetaString = "string"
+	MetaDate   = "date"
+	MetaNumber = "number"
+	MetaBool   = "bool"
+)
+
+// MetaField declares a custom metadata field stored on each project
+// created from the template.
+type MetaField struct {
+	Name     string `yaml:"name"`
+	Type     string `yaml:"type,omitempty"`
+	Prompt   string `yaml:"prompt,omitempty"`
+	Required bool   `yaml:"required,omitempty"`
+	Default  string `yaml:"default,omitempty"`
 }
 
 // Directory represents a single directory node in a template tree.
@@ -34,20 +59,179 @@ type Directory struct {
 
 // Template represents a project template with its directory structure.
 type Template struct {
-	ID          string      `yaml:"id"`
-	Name        string      `yaml:"name"`
-	BasePath    string      `yaml:"base_path"`
-	Directories []Directory `yaml:"directories"`
-	Hooks       []string    `yaml:"hooks,omitempty"`
-	Variables   []Variable  `yaml:"variables,omitempty"`
-	Extends     string      `yaml:"extends,omitempty"`
-	Tags        []string    `yaml:"tags,omitempty"`
+	ID          string           `yaml:"id"`
+	Name        string           `yaml:"name"`
+	BasePath    string           `yaml:"base_path"`
+	Directories []Directory      `yaml:"directories"`
+	Hooks       []string         `yaml:"hooks,omitempty"`
+	Variables   []Variable       `yaml:"variables,omitempty"`
+	Metadata    []MetaField      `yaml:"metadata,omitempty"`
+	Extends     string           `yaml:"extends,omitempty"`
+	Tags        []string         `yaml:"tags,omitempty"`
+	Archive     *ArchiveSettings `yaml:"archive,omitempty"`
+	Retention   []RetentionRule  `yaml:"retention,omitempty"`
+	Version     int              `yaml:"version,omitempty"`
+	Migrations  []Migration      `yaml:"migrations,omitempty"`
+	Tidy        *TidySettings    `yaml:"tidy,omitempty"`
+	CleanIgnore []string         `yaml:"clean_ignore,omitempty"`
+}
+
+// TidySettings control where prjct tidy moves directories that are not part
+// of the template. A directory goes to the To directory of the first rule
+// whose Match glob matches its name (case-insensitive), otherwise into
+// Unsorted (default "_Unsorted"). Paths are relative to the project root.
+type TidySettings struct {
+	Unsorted string     `yaml:"unsorted,omitempty"`
+	Rules    []TidyRule `yaml:"rules,omitempty"`
+}
+
+// TidyRule sends directories whose name matches the Match glob to To.
+type TidyRule struct {
+	Match string `yaml:"match"`
+	To    string `yaml:"to"`
+}
+
+// DefaultUnsortedDir collects directories no tidy rule matches.
+const DefaultUnsortedDir = "_Unsorted"
+
+// UnsortedDir returns the directory for unmatched directories.
+func (t *TidySettings) UnsortedDir() string {
+	if t == nil || t.Unsorted == "" {
+		return DefaultUnsortedDir
+	}
+	return t.Unsorted
+}
+
+// Migration upgrades projects to Version of their template. Steps run in
+// order.
+type Migration struct {
+	Version int             `yaml:"version"`
+	Steps   []MigrationStep `yaml:"steps"`
+}
+
+// MigrationStep is one change to a project's directories. Exactly one of
+// Rename, Move, Merge and DeleteIfEmpty is set; paths are relative to the
+// project root and may contain variables.
+//
+//   - rename: Color, to: 05_Color renames a directory in place
+//   - move: Graphics, to: Edits/Graphics moves it to another parent
+//   - merge: Old_Audio, to: Audio moves its contents into an existing
+//     directory and removes it
+//   - delete_if_empty: Temp removes a directory that contains no files
+type MigrationStep struct {
+	Rename        string `yaml:"rename,omitempty"`
+	Move          string `yaml:"move,omitempty"`
+	Merge         string `yaml:"merge,omitempty"`
+	DeleteIfEmpty string `yaml:"delete_if_empty,omitempty"`
+	To            string `yaml:"to,omitempty"`
+}
+
+// Migration step kinds.
+const (
+	StepRename        = "rename"
+	StepMove          = "move"
+	StepMerge         = "merge"
+	StepDeleteIfEmpty = "delete_if_empty"
+)
+
+// Kind returns the step's kind and source path, or "" if not exactly one
+// kind is set.
+func (s MigrationStep) Kind() (kind, path string) {
+	n := 0
+	for _, k := range []struct{ kind, path string }{
+		{StepRename, s.Rename},
+		{StepMove, s.Move},
+		{StepMerge, s.Merge},
+		{StepDeleteIfEmpty, s.DeleteIfEmpty},
+	} {
+		if k.path != "" {
+			kind, path = k.kind, k.path
+			n++
+		}
+	}
+	if n != 1 {
+		return "", ""
+	}
+	return kind, path
+}
+
+// CurrentVersion returns the template's version. Templates without one are
+// at version 1.
+func (t *Template) CurrentVersion() int {
+	if t.Version < 1 {
+		return 1
+	}
+	return t.Version
+}
+
+// ArchiveSettings are a template's defaults for prjct archive. Format is
+// tar, tar.gz or zip; Level is a compression level from 1 to 9, where 0
+// selects the format's default. Exclude lists gitignore-style patterns of
+// paths to leave out, such as render caches and proxies.
+type ArchiveSettings struct {
+	Format  string   `yaml:"format,omitempty"`
+	Level   int      `yaml:"level,omitempty"`
+	Exclude []string `yaml:"exclude,omitempty"`
+}
+
+// Retention actions.
+const (
+	RetentionArchive = "archive"
+	RetentionFlag    = "flag"
+)
+
+// RetentionRule acts on projects of a template whose files have not been
+// modified for InactiveDays days, optionally only in one status. Action
+// archive (the default) writes an archive into Destination, or next to the
+// project if empty, and removes the original if Delete is set. Delete is
+// refused when the template's archive.exclude leaves files out, unless
+// DeleteFiltered is also set. Action flag adds Tag (default "stale") to the
+// project.
+type RetentionRule struct {
+	Status         string `yaml:"status,omitempty"`
+	InactiveDays   int    `yaml:"inactive_days"`
+	Action         string `yaml:"action,omitempty"`
+	Destination    string `yaml:"destination,omitempty"`
+	Delete         bool   `yaml:"delete,omitempty"`
+	DeleteFiltered bool   `yaml:"delete_filtered,omitempty"`
+	Tag            string `yaml:"tag,omitempty"`
+}
+
+// DefaultRetentionTag is added by flag rules without a tag.
+const DefaultRetentionTag = "stale"
+
+// ArchiveFormats lists the accepted archive format names.
+var ArchiveFormats = []string{"tar", "tar.gz", "zip"}
+
+// Status is a single project lifecycle state.
+type Status struct {
+	Name        string   `yaml:"name"`
+	Transitions []string `yaml:"transitions,omitempty"`
+	Hook        string   `yaml:"hook,omitempty"`
+}
+
+// Workflow defines the project lifecycle statuses and the allowed
+// transitions between them.
+type Workflow struct {
+	Initial  string   `yaml:"initial,omitempty"`
+	Statuses []Status `yaml:"statuses"`
+}
+
+// JournalSettings controls how long operation records stay in the active
+// journal before they are rotated into monthly archive files. Zero values
+// fall back to the defaults (100 records, no age limit).
+type JournalSettings struct {
+	MaxRecords int `yaml:"max_records,omitempty"`
+	MaxAgeDays int `yaml:"max_age_days,omitempty"`
 }
 
 // Config is the root configuration containing all templates.
 type Config struct {
-	Editor    string     `yaml:"editor,omitempty"`
-	Templates []Template `yaml:"templates"`
+	Editor    string            `yaml:"editor,omitempty"`
+	Formats   map[string]string `yaml:"formats,omitempty"`
+	Workflow  *Workflow         `yaml:"workflow,omitempty"`
+	Journal   *JournalSettings  `yaml:"journal,omitempty"`
+	Templates []Template        `yaml:"templates"`
 }
 
 // ValidationError describes a single validation issue.
@@ -92,6 +276,17 @@ var reservedIDs = map[string]bool{
 	"undo":       true,
 	"readme":     true,
 	"watch":      true,
+	"status":     true,
+	"meta":       true,
+	"tag":        true,
+	"log":        true,
+	"redo":       true,
+	"restore":    true,
+	"retention":  true,
+	"migrate":    true,
+	"audit":      true,
+	"each":       true,
+	"tidy":       true,
 }
 
 // Load reads and parses the config file at the given path.
@@ -184,6 +379,72 @@ func (c *Config) Validate() []ValidationError {
 				})
 			}
 		}
+
+		for j, m := range t.Metadata {
+			mp := fmt.Sprintf("%s.metadata[%d]", prefix, j)
+			if m.Name == "" {
+				errs = append(errs, ValidationError{
+					Field:   mp + ".name",
+					Message: "metadata field name is required",
+				})
+			} else if !varNameRe.MatchString(m.Name) {
+				errs = append(errs, ValidationError{
+					Field:   mp + ".name",
+					Message: fmt.Sprintf("metadata field name %q must match [a-zA-Z_][a-zA-Z0-9_]*", m.Name),
+				})
+			}
+			switch m.Type {
+			case "", MetaString, MetaDate, MetaNumber, MetaBool:
+			default:
+				errs = append(errs, ValidationError{
+					Field:   mp + ".type",
+					Message: fmt.Sprintf("unknown type %q: use string, date, number, or bool", m.Type),
+				})
+				continue
+			}
+			if m.Default != "" {
+				if err := m.Check(m.Default); err != nil {
+					errs = append(errs, ValidationError{
+						Field:   mp + ".default",
+						Message: err.Error(),
+					})
+				}
+			}
+		}
+
+		if t.Archive != nil {
+			errs = append(errs, validateArchive(t.Archive, prefix+".archive")...)
+		}
+		for j, r := range t.Retention {
+			errs = append(errs, c.validateRetention(r, fmt.Sprintf("%s.retention[%d]", prefix, j))...)
+		}
+		errs = append(errs, validateMigrations(t, prefix)...)
+		if t.Tidy != nil {
+			errs = append(errs, validateTidy(t.Tidy, prefix+".tidy")...)
+		}
+	}
+
+	// Sorted, so several errors are always reported in the same order
+	for _, name := range slices.Sorted(maps.Keys(c.Formats)) {
+		if strings.TrimSpace(c.Formats[name]) == "" {
+			errs = append(errs, ValidationError{
+				Field:   "formats." + name,
+				Message: "format string is required",
+			})
+		}
+	}
+
+	if c.Workflow != nil {
+		errs = append(errs, validateWorkflow(c.Workflow)...)
+	}
+
+	if c.Journal != nil {
+		if c.Journal.MaxRecords < 0 {
+			errs = append(errs, ValidationError{Field: "journal.max_records", Message: "must not be negative"})
+		}
+		if c.Journal.MaxAgeDays < 0 {
+			errs = append(errs, ValidationError{Field: "journal.max_age_days", Message: "must not be negative"})
+		}
 	}
 
 	// Validate extends references (second pass — all IDs are now known)
@@ -274,6 +535,308 @@ func validateDirs(dirs []Directory, prefix string, depth int) []ValidationError
 	return errs
 }
 
+func validateArchive(a *ArchiveSettings, prefix string) []ValidationError {
+	var errs []ValidationError
+
+	if a.Format != "" && !slices.Contains(ArchiveFormats, a.Format) {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".format",
+			Message: fmt.Sprintf("unknown format %q: use tar, tar.gz, or zip", a.Format),
+		})
+	}
+	if a.Level < 0 || a.Level > 9 {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".level",
+			Message: "must be between 1 and 9",
+		})
+	} else if a.Level != 0 && a.Format == "tar" {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".level",
+			Message: "tar archives are not compressed",
+		})
+	}
+
+	return errs
+}
+
+func (c *Config) validateRetention(r RetentionRule, prefix string) []ValidationError {
+	var errs []ValidationError
+
+	if r.InactiveDays < 1 {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".inactive_days",
+			Message: "must be at least 1",
+		})
+	}
+	switch r.Action {
+	case "", RetentionArchive:
+		if r.Tag != "" {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".tag",
+				Message: "only applies to the flag action",
+			})
+		}
+		if r.DeleteFiltered && !r.Delete {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".delete_filtered",
+				Message: "requires delete",
+			})
+		}
+	case RetentionFlag:
+		if r.Destination != "" || r.Delete || r.DeleteFiltered {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".action",
+				Message: "destination and delete only apply to the archive action",
+			})
+		}
+		if strings.ContainsAny(r.Tag, ", \t") {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".tag",
+				Message: "must not contain spaces or commas",
+			})
+		}
+	default:
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".action",
+			Message: fmt.Sprintf("unknown action %q: use archive or flag", r.Action),
+		})
+	}
+	if r.Status != "" && c.ResolveWorkflow().FindStatus(r.Status) == nil {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".status",
+			Message: fmt.Sprintf("unknown status %q", r.Status),
+		})
+	}
+
+	return errs
+}
+
+func validateMigrations(t Template, prefix string) []ValidationError {
+	var errs []ValidationError
+
+	if t.Version < 0 {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".version",
+			Message: "must be positive",
+		})
+	}
+	last := 1
+	for i, m := range t.Migrations {
+		mp := fmt.Sprintf("%s.migrations[%d]", prefix, i)
+		if m.Version <= last || m.Version > t.CurrentVersion() {
+			errs = append(errs, ValidationError{
+				Field:   mp + ".version",
+				Message: fmt.Sprintf("must be greater than %d and at most the template version %d", last, t.CurrentVersion()),
+			})
+		}
+		if m.Version > last {
+			last = m.Version
+		}
+		if len(m.Steps) == 0 {
+			errs = append(errs, ValidationError{
+				Field:   mp + ".steps",
+				Message: "at least one step is required",
+			})
+		}
+		for j, step := range m.Steps {
+			sp := fmt.Sprintf("%s.steps[%d]", mp, j)
+			kind, path := step.Kind()
+			if kind == "" {
+				errs = append(errs, ValidationError{
+					Field:   sp,
+					Message: "exactly one of rename, move, merge or delete_if_empty is required",
+				})
+				continue
+			}
+			for _, p := range []string{path, step.To} {
+				if p != "" && !isRelativePath(p) {
+					errs = append(errs, ValidationError{
+						Field:   sp,
+						Message: fmt.Sprintf("path %q must be relative to the project and not contain ..", p),
+					})
+				}
+			}
+			switch {
+			case kind == StepDeleteIfEmpty && step.To != "":
+				errs = append(errs, ValidationError{
+					Field:   sp + ".to",
+					Message: "does not apply to delete_if_empty",
+				})
+			case kind != StepDeleteIfEmpty && step.To == "":
+				errs = append(errs, ValidationError{
+					Field:   sp + ".to",
+					Message: "is required",
+				})
+			case kind == StepRename && strings.ContainsAny(step.To, `/\`):
+				errs = append(errs, ValidationError{
+					Field:   sp + ".to",
+					Message: "must be a name; use move to change the parent directory",
+				})
+			}
+		}
+	}
+
+	return errs
+}
+
+func validateTidy(t *TidySettings, prefix string) []ValidationError {
+	var errs []ValidationError
+
+	if t.Unsorted != "" && !isRelativePath(t.Unsorted) {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".unsorted",
+			Message: "must be relative to the project and not contain ..",
+		})
+	}
+	for i, r := range t.Rules {
+		rp := fmt.Sprintf("%s.rules[%d]", prefix, i)
+		if _, err := path.Match(r.Match, ""); r.Match == "" || err != nil {
+			errs = append(errs, ValidationError{
+				Field:   rp + ".match",
+				Message: "must be a valid glob pattern",
+			})
+		}
+		if r.To == "" || !isRelativePath(r.To) {
+			errs = append(errs, ValidationError{
+				Field:   rp + ".to",
+				Message: "must be a path relative to the project and not contain ..",
+			})
+		}
+	}
+	return errs
+}
+
+// isRelativePath reports whether p is a relative path that stays inside the
+// directory it is relative to.
+func isRelativePath(p string) bool {
+	p = filepath.ToSlash(p)
+	if strings.HasPrefix(p, "/") || filepath.IsAbs(p) {
+		return false
+	}
+	for _, part := range strings.Split(p, "/") {
+		if part == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+func validateWorkflow(w *Workflow) []ValidationError {
+	var errs []ValidationError
+
+	if len(w.Statuses) == 0 {
+		errs = append(errs, ValidationError{
+			Field:   "workflow.statuses",
+			Message: "at least one status is required",
+		})
+		return errs
+	}
+
+	known := make(map[string]bool, len(w.Statuses))
+	for i, s := range w.Statuses {
+		sp := fmt.Sprintf("workflow.statuses[%d]", i)
+		if s.Name == "" {
+			errs = append(errs, ValidationError{
+				Field:   sp + ".name",
+				Message: "status name is required",
+			})
+			continue
+		}
+		if known[s.Name] {
+			errs = append(errs, ValidationError{
+				Field:   sp + ".name",
+				Message: fmt.Sprintf("duplicate status %q", s.Name),
+			})
+		}
+		known[s.Name] = true
+	}
+
+	for i, s := range w.Statuses {
+		for j, to := range s.Transitions {
+			if !known[to] {
+				errs = append(errs, ValidationError{
+					Field:   fmt.Sprintf("workflow.statuses[%d].transitions[%d]", i, j),
+					Message: fmt.Sprintf("transition to unknown status %q", to),
+				})
+			}
+		}
+	}
+
+	if w.Initial != "" && !known[w.Initial] {
+		errs = append(errs, ValidationError{
+			Field:   "workflow.initial",
+			Message: fmt.Sprintf("initial status %q is not defined", w.Initial),
+		})
+	}
+
+	return errs
+}
+
+// DefaultWorkflow returns the lifecycle used when the config does not
+// define one: planned → active → review → delivered → archived.
+func DefaultWorkflow() Workflow {
+	return Workflow{
+		Initial: "active",
+		Statuses: []Status{
+			{Name: "planned", Transitions: []string{"active", "archived"}},
+			{Name: "active", Transitions: []string{"review", "delivered", "archived"}},
+			{Name: "review", Transitions: []string{"active", "delivered"}},
+			{Name: "delivered", Transitions: []string{"active", "archived"}},
+			{Name: "archived", Transitions: []string{"active"}},
+		},
+	}
+}
+
+// ResolveWorkflow returns the configured workflow, or the default one.
+func (c *Config) ResolveWorkflow() Workflow {
+	if c.Workflow == nil {
+		return DefaultWorkflow()
+	}
+	return *c.Workflow
+}
+
+// InitialStatus returns the status assigned to newly created projects.
+// It defaults to the first defined status.
+func (w Workflow) InitialStatus() string {
+	if w.Initial != "" {
+		return w.Initial
+	}
+	if len(w.Statuses) > 0 {
+		return w.Statuses[0].Name
+	}
+	return ""
+}
+
+// FindStatus returns the status with the given name, or nil if not found.
+func (w Workflow) FindStatus(name string) *Status {
+	for i := range w.Statuses {
+		if w.Statuses[i].Name == name {
+			return &w.Statuses[i]
+		}
+	}
+	return nil
+}
+
+// CanTransition reports whether a project may move from one status to
+// another. An empty from status is treated as the initial status.
+func (w Workflow) CanTransition(from, to string) bool {
+	if from == "" {
+		from = w.InitialStatus()
+	}
+	s := w.FindStatus(from)
+	if s == nil {
+		// Unknown current status (e.g. set before the workflow changed):
+		// allow moving into any defined status to recover.
+		return w.FindStatus(to) != nil
+	}
+	for _, t := range s.Transitions {
+		if t == to {
+			return true
+		}
+	}
+	return false
+}
+
 // FindTemplate returns the template with the given ID, or nil if not found.
 func (c *Config) FindTemplate(id string) *Template {
 	for i := range c.Templates {
@@ -329,6 +892,32 @@ func (c *Config) ResolveTemplate(id string) (*Template, error) {
 		}
 		merged.Directories = append(merged.Directories, t.Directories...)
 		merged.Hooks = append(merged.Hooks, t.Hooks...)
+		merged.CleanIgnore = append(merged.CleanIgnore, t.CleanIgnore...)
+		if t.Archive != nil {
+			merged.Archive = mergeArchive(merged.Archive, t.Archive)
+		}
+		// Retention rules: a child's rules replace its parent's
+		if len(t.Retention) > 0 {
+			merged.Retention = t.Retention
+		}
+		if t.Tidy != nil {
+			merged.Tidy = t.Tidy
+		}
+
+		// Metadata fields: child overrides parent by name
+		for _, m := range t.Metadata {
+			found := false
+			for j, existing := range merged.Metadata {
+				if existing.Name == m.Name {
+					merged.Metadata[j] = m
+					found = true
+					break
+				}
+			}
+			if !found {
+				merged.Metadata = append(merged.Metadata, m)
+			}
+		}
 
 		// Variables: child overrides parent by name
 		for _, v := range t.Variables {
@@ -346,9 +935,30 @@ func (c *Config) ResolveTemplate(id string) (*Template, error) {
 		}
 	}
 
+	// Versions and migrations describe one template's own history
+	merged.Version = tmpl.Version
+	merged.Migrations = tmpl.Migrations
+
 	return &merged, nil
 }
 
+// mergeArchive overlays the non-zero settings of child onto parent and
+// appends the child's exclude patterns.
+func mergeArchive(parent, child *ArchiveSettings) *ArchiveSettings {
+	merged := ArchiveSettings{}
+	if parent != nil {
+		merged = *parent
+	}
+	if child.Format != "" {
+		merged.Format = child.Format
+	}
+	if child.Level != 0 {
+		merged.Level = child.Level
+	}
+	merged.Exclude = append(append([]string{}, merged.Exclude...), child.Exclude...)
+	return &merged
+}
+
 // Save writes the config to disk as YAML.
 func (c *Config) Save(path string) error {
 	data, err := yaml.Marshal(c)
@@ -364,6 +974,48 @@ func (c *Config) Save(path string) error {
 	return nil
 }
 
+// Format returns the named output format, or false if it is not defined.
+func (c *Config) Format(name string) (string, bool) {
+	f, ok := c.Formats[name]
+	return f, ok
+}
+
+// Check validates a value against the field's type. Empty values are
+// accepted unless the field is required.
+func (m MetaField) Check(value string) error {
+	if value == "" {
+		if m.Required {
+			return fmt.Errorf("%s is required", m.Name)
+		}
+		return nil
+	}
+	switch m.Type {
+	case MetaDate:
+		if _, err := time.Parse("2006-01-02", value); err != nil {
+			return fmt.Errorf("%s must be a date (YYYY-MM-DD), got %q", m.Name, value)
+		}
+	case MetaNumber:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return fmt.Errorf("%s must be a number, got %q", m.Name, value)
+		}
+	case MetaBool:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("%s must be true or false, got %q", m.Name, value)
+		}
+	}
+	return nil
+}
+
+// FindMetaField returns the metadata field with the given name, or nil.
+func (t *Template) FindMetaField(name string) *MetaField {
+	for i := range t.Metadata {
+		if t.Metadata[i].Name == name {
+			return &t.Metadata[i]
+		}
+	}
+	return nil
+}
+
 // MatchesTags returns true if the template has at least one of the given tags.
 // An empty filter matches everything.
 func (t *Template) MatchesTags(tags []string) bool {
@@ -834,3 +834,351 @@ func writeTemp(t *testing.T, content string) string {
 	}
 	return path
 }
+
+func TestLoadFormats(t *testing.T) {
+	content := `formats:
+  short: "{{.Name}}"
+templates:
+  - id: t
+    name: "T"
+    base_path: "/tmp"
+    directories:
+      - name: "src"
+`
+	path := writeTemp(t, content)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	f, ok := cfg.Format("short")
+	if !ok || f != "{{.Name}}" {
+		t.Errorf("Format(short) = %q, %v", f, ok)
+	}
+	if _, ok := cfg.Format("missing"); ok {
+		t.Error("Format(missing) should not be found")
+	}
+}
+
+func TestValidateEmptyFormat(t *testing.T) {
+	cfg := &Config{
+		Formats: map[string]string{"blank": " "},
+		Templates: []Template{
+			{ID: "t", Name: "T", BasePath: "/tmp", Directories: []Directory{{Name: "src"}}},
+		},
+	}
+	errs := cfg.Validate()
+	if len(errs) != 1 || errs[0].Field != "formats.blank" {
+		t.Errorf("expected formats.blank error, got %v", errs)
+	}
+}
+
+func TestValidateFormatsSorted(t *testing.T) {
+	cfg := &Config{
+		Formats: map[string]string{"zeta": "", "alpha": " ", "mid": "", "ok": "{{.Name}}"},
+		Templates: []Template{
+			{ID: "t", Name: "T", BasePath: "/tmp", Directories: []Directory{{Name: "src"}}},
+		},
+	}
+	for i := 0; i < 20; i++ {
+		errs := cfg.Validate()
+		if len(errs) != 3 || errs[0].Field != "formats.alpha" || errs[1].Field != "formats.mid" || errs[2].Field != "formats.zeta" {
+			t.Fatalf("errors = %v, want alpha, mid, zeta in order", errs)
+		}
+	}
+}
+
+// --- Workflow tests ---
+
+func TestDefaultWorkflowTransitions(t *testing.T) {
+	wf := (&Config{}).ResolveWorkflow()
+	if wf.InitialStatus() != "active" {
+		t.Errorf("InitialStatus() = %q, want active", wf.InitialStatus())
+	}
+	if !wf.CanTransition("active", "review") {
+		t.Error("active -> review should be allowed")
+	}
+	if !wf.CanTransition("", "review") {
+		t.Error("empty status should be treated as the initial status")
+	}
+	if wf.CanTransition("planned", "delivered") {
+		t.Error("planned -> delivered should not be allowed")
+	}
+	if !wf.CanTransition("legacy", "active") {
+		t.Error("unknown current status should allow recovery into a defined status")
+	}
+}
+
+func TestWorkflowInitialDefaultsToFirst(t *testing.T) {
+	wf := Workflow{Statuses: []Status{{Name: "todo"}, {Name: "done"}}}
+	if wf.InitialStatus() != "todo" {
+		t.Errorf("InitialStatus() = %q, want todo", wf.InitialStatus())
+	}
+}
+
+func TestValidateWorkflow(t *testing.T) {
+	cfg := &Config{
+		Workflow: &Workflow{
+			Initial: "missing",
+			Statuses: []Status{
+				{Name: "a", Transitions: []string{"b", "nope"}},
+				{Name: "b"},
+				{Name: "b"},
+			},
+		},
+		Templates: []Template{
+			{ID: "t", Name: "T", BasePath: "/tmp", Directories: []Directory{{Name: "src"}}},
+		},
+	}
+	errs := cfg.Validate()
+	fields := make(map[string]bool)
+	for _, e := range errs {
+		fields[e.Field] = true
+	}
+	for _, want := range []string{"workflow.initial", "workflow.statuses[0].transitions[1]", "workflow.statuses[2].name"} {
+		if !fields[want] {
+			t.Errorf("expected error for %s, got %v", want, errs)
+		}
+	}
+}
+
+// --- Metadata field tests ---
+
+func TestMetaFieldCheck(t *testing.T) {
+	tests := []struct {
+		field MetaField
+		value string
+		ok    bool
+	}{
+		{MetaField{Name: "client"}, "Acme", true},
+		{MetaField{Name: "client", Required: true}, "", false},
+		{MetaField{Name: "due", Type: MetaDate}, "2026-04-01", true},
+		{MetaField{Name: "due", Type: MetaDate}, "April 1", false},
+		{MetaField{Name: "budget", Type: MetaNumber}, "1500.50", true},
+		{MetaField{Name: "budget", Type: MetaNumber}, "lots", false},
+		{MetaField{Name: "rush", Type: MetaBool}, "true", true},
+		{MetaField{Name: "rush", Type: MetaBool}, "maybe", false},
+		{MetaField{Name: "due", Type: MetaDate}, "", true},
+	}
+	for _, tt := range tests {
+		err := tt.field.Check(tt.value)
+		if (err == nil) != tt.ok {
+			t.Errorf("Check(%s=%q) error = %v, want ok=%v", tt.field.Name, tt.value, err, tt.ok)
+		}
+	}
+}
+
+func TestValidateMetadataFields(t *testing.T) {
+	cfg := &Config{
+		Templates: []Template{
+			{
+				ID: "t", Name: "T", BasePath: "/tmp", Directories: []Directory{{Name: "src"}},
+				Metadata: []MetaField{
+					{Name: "bad name"},
+					{Name: "kind", Type: "color"},
+					{Name: "due", Type: MetaDate, Default: "soon"},
+				},
+			},
+		},
+	}
+	errs := cfg.Validate()
+	if len(errs) != 3 {
+		t.Errorf("expected 3 errors, got %v", errs)
+	}
+}
+
+func TestResolveTemplateMergesMetadata(t *testing.T) {
+	cfg := &Config{
+		Templates: []Template{
+			{ID: "base", Name: "Base", BasePath: "/tmp", Directories: []Directory{{Name: "docs"}},
+				Metadata: []MetaField{{Name: "client"}, {Name: "due", Type: MetaDate}}},
+			{ID: "child", Name: "Child", Extends: "base",
+				Metadata: []MetaField{{Name: "client", Required: true}}},
+		},
+	}
+	tmpl, err := cfg.ResolveTemplate("child")
+	if err != nil {
+		t.Fatalf("ResolveTemplate: %v", err)
+	}
+	if len(tmpl.Metadata) != 2 {
+		t.Fatalf("expected 2 metadata fields, got %d", len(tmpl.Metadata))
+	}
+	if f := tmpl.FindMetaField("client"); f == nil || !f.Required {
+		t.Error("child should override parent metadata field")
+	}
+}
+
+func TestValidateJournalSettings(t *testing.T) {
+	cfg := &Config{
+		Journal: &JournalSettings{MaxRecords: -1, MaxAgeDays: -5},
+		Templates: []Template{
+			{ID: "t", Name: "T", BasePath: "/tmp", Directories: []Directory{{Name: "src"}}},
+		},
+	}
+	if errs := cfg.Validate(); len(errs) != 2 {
+		t.Errorf("expected 2 errors, got %v", errs)
+	}
+}
+
+func TestValidateArchiveSettings(t *testing.T) {
+	tests := []struct {
+		settings ArchiveSettings
+		errs     int
+	}{
+		{ArchiveSettings{Format: "zip", Level: 9}, 0},
+		{ArchiveSettings{Format: "tar"}, 0},
+		{ArchiveSettings{Format: "rar"}, 1},
+		{ArchiveSettings{Level: 12}, 1},
+		{ArchiveSettings{Format: "tar", Level: 3}, 1},
+	}
+	for _, tt := range tests {
+		s := tt.settings
+		cfg := &Config{
+			Templates: []Template{
+				{ID: "t", Name: "T", BasePath: "/tmp", Directories: []Directory{{Name: "src"}}, Archive: &s},
+			},
+		}
+		if errs := cfg.Validate(); len(errs) != tt.errs {
+			t.Errorf("Validate(%+v) = %v, want %d error(s)", s, errs, tt.errs)
+		}
+	}
+}
+
+func TestResolveTemplateMergesArchive(t *testing.T) {
+	cfg := &Config{
+		Templates: []Template{
+			{ID: "base", Name: "Base", BasePath: "/tmp", Directories: []Directory{{Name: "docs"}},
+				Archive: &ArchiveSettings{Format: "zip", Level: 3, Exclude: []string{"*.cache"}}},
+			{ID: "child", Name: "Child", Extends: "base",
+				Archive: &ArchiveSettings{Level: 9, Exclude: []string{"Proxies/"}}},
+		},
+	}
+	tmpl, err := cfg.ResolveTemplate("child")
+	if err != nil {
+		t.Fatalf("ResolveTemplate: %v", err)
+	}
+	if tmpl.Archive == nil || tmpl.Archive.Format != "zip" || tmpl.Archive.Level != 9 {
+		t.Errorf("Archive = %+v, want zip level 9", tmpl.Archive)
+	}
+	if len(tmpl.Archive.Exclude) != 2 {
+		t.Errorf("Exclude = %v, want parent and child patterns", tmpl.Archive.Exclude)
+	}
+}
+
+func TestValidateRetention(t *testing.T) {
+	tests := []struct {
+		rule RetentionRule
+		errs int
+	}{
+		{RetentionRule{Status: "delivered", InactiveDays: 90, Destination: "/mnt/cold", Delete: true}, 0},
+		{RetentionRule{InactiveDays: 30, Action: "flag", Tag: "dormant"}, 0},
+		{RetentionRule{InactiveDays: 0}, 1},
+		{RetentionRule{InactiveDays: 30, Action: "delete"}, 1},
+		{RetentionRule{InactiveDays: 30, Action: "flag", Destination: "/mnt/cold"}, 1},
+		{RetentionRule{InactiveDays: 30, Tag: "stale"}, 1},
+		{RetentionRule{InactiveDays: 30, Status: "shipped"}, 1},
+		{RetentionRule{InactiveDays: 30, Delete: true, DeleteFiltered: true}, 0},
+		{RetentionRule{InactiveDays: 30, DeleteFiltered: true}, 1},
+		{RetentionRule{InactiveDays: 30, Action: "flag", DeleteFiltered: true}, 1},
+	}
+	for _, tt := range tests {
+		cfg := &Config{
+			Templates: []Template{
+				{ID: "t", Name: "T", BasePath: "/tmp", Directories: []Directory{{Name: "src"}}, Retention: []RetentionRule{tt.rule}},
+			},
+		}
+		if errs := cfg.Validate(); len(errs) != tt.errs {
+			t.Errorf("Validate(%+v) = %v, want %d error(s)", tt.rule, errs, tt.errs)
+		}
+	}
+}
+
+func TestResolveTemplateRetention(t *testing.T) {
+	cfg := &Config{
+		Templates: []Template{
+			{ID: "base", Name: "Base", BasePath: "/tmp", Directories: []Directory{{Name: "docs"}},
+				Retention: []RetentionRule{{InactiveDays: 90}}},
+			{ID: "inherits", Name: "Inherits", Extends: "base"},
+			{ID: "overrides", Name: "Overrides", Extends: "base",
+				Retention: []RetentionRule{{InactiveDays: 30, Action: "flag"}}},
+		},
+	}
+	for id, want := range map[string]int{"inherits": 90, "overrides": 30} {
+		tmpl, err := cfg.ResolveTemplate(id)
+		if err != nil {
+			t.Fatalf("ResolveTemplate(%s): %v", id, err)
+		}
+		if len(tmpl.Retention) != 1 || tmpl.Retention[0].InactiveDays != want {
+			t.Errorf("%s: Retention = %+v, want one rule with %d days", id, tmpl.Retention, want)
+		}
+	}
+}
+
+func TestValidateMigrations(t *testing.T) {
+	tests := []struct {
+		name       string
+		version    int
+		migrations []Migration
+		errs       int
+	}{
+		{"valid", 3, []Migration{
+			{Version: 2, Steps: []MigrationStep{{Rename: "Color", To: "05_Color"}}},
+			{Version: 3, Steps: []MigrationStep{{Move: "Graphics", To: "Edits/Graphics"}, {DeleteIfEmpty: "Temp"}}},
+		}, 0},
+		{"beyond template version", 2, []Migration{
+			{Version: 3, Steps: []MigrationStep{{DeleteIfEmpty: "Temp"}}},
+		}, 1},
+		{"not increasing", 3, []Migration{
+			{Version: 3, Steps: []MigrationStep{{DeleteIfEmpty: "Temp"}}},
+			{Version: 2, Steps: []MigrationStep{{DeleteIfEmpty: "Old"}}},
+		}, 1},
+		{"two kinds", 2, []Migration{
+			{Version: 2, Steps: []MigrationStep{{Rename: "A", Move: "B", To: "C"}}},
+		}, 1},
+		{"missing to", 2, []Migration{
+			{Version: 2, Steps: []MigrationStep{{Merge: "Old_Audio"}}},
+		}, 1},
+		{"escapes project", 2, []Migration{
+			{Version: 2, Steps: []MigrationStep{{Move: "Audio", To: "../Audio"}}},
+		}, 1},
+		{"rename to path", 2, []Migration{
+			{Version: 2, Steps: []MigrationStep{{Rename: "Audio", To: "Edits/Audio"}}},
+		}, 1},
+	}
+	for _, tt := range tests {
+		cfg := &Config{
+			Templates: []Template{
+				{ID: "t", Name: "T", BasePath: "/tmp", Directories: []Directory{{Name: "src"}},
+					Version: tt.version, Migrations: tt.migrations},
+			},
+		}
+		if errs := cfg.Validate(); len(errs) != tt.errs {
+			t.Errorf("%s: Validate() = %v, want %d error(s)", tt.name, errs, tt.errs)
+		}
+	}
+}
+
+func TestValidateTidy(t *testing.T) {
+	tests := []struct {
+		tidy TidySettings
+		errs int
+	}{
+		{TidySettings{Unsorted: "_Inbox", Rules: []TidyRule{{Match: "*render*", To: "04_Export"}}}, 0},
+		{TidySettings{Unsorted: "../Inbox"}, 1},
+		{TidySettings{Rules: []TidyRule{{Match: "[render", To: "04_Export"}}}, 1},
+		{TidySettings{Rules: []TidyRule{{Match: "*render*"}}}, 1},
+	}
+	for _, tt := range tests {
+		tidy := tt.tidy
+		cfg := &Config{
+			Templates: []Template{
+				{ID: "t", Name: "T", BasePath: "/tmp", Directories: []Directory{{Name: "src"}}, Tidy: &tidy},
+			},
+		}
+		if errs := cfg.Validate(); len(errs) != tt.errs {
+			t.Errorf("Validate(%+v) = %v, want %d error(s)", tt.tidy, errs, tt.errs)
+		}
+	}
+	if got := (*TidySettings)(nil).UnsortedDir(); got != DefaultUnsortedDir {
+		t.Errorf("UnsortedDir() = %q, want %q", got, DefaultUnsortedDir)
+	}
+}
@@ -0,0 +1,134 @@
+package fscopy
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Method is how the data of a file gets to its copy.
+type Method string
+
+const (
+	// Copy writes a full copy of the data.
+	Copy Method = "copy"
+	// Reflink shares the data with a copy-on-write clone where the
+	// filesystem supports it (Btrfs and XFS on Linux).
+	Reflink Method = "reflink"
+	// Hard creates a hard link, which shares the file itself.
+	Hard Method = "hard"
+	// Symlink is reported for symbolic links, which are recreated.
+	Symlink Method = "symlink"
+)
+
+// ParseMethod returns the method named s.
+func ParseMethod(s string) (Method, error) {
+	switch m := Method(s); m {
+	case Copy, Reflink, Hard:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown link method %q (want hard, reflink or copy)", s)
+}
+
+// errReflinkUnsupported is returned by reflink where cloning is not
+// implemented.
+var errReflinkUnsupported = errors.New("reflinks are not supported on this platform")
+
+// chunkSize is how much data is copied between progress reports.
+const chunkSize = 32 << 20
+
+// File copies the regular file or symlink src to dst, which must not
+// exist, and returns the method actually used. Hard falls back to Reflink
+// when src cannot be linked, for example across filesystems, and Reflink
+// falls back to Copy when the filesystem cannot clone. Copies keep the
+// mode and modification time of src; symlinks are recreated with the same
+// target. progress, if not nil, is called with the number of bytes of src
+// done since the last call.
+func File(src, dst string, method Method, progress func(n int64)) (Method, error) {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return "", err
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(src)
+		if err != nil {
+			return "", err
+		}
+		return Symlink, os.Symlink(target, dst)
+	}
+	if !info.Mode().IsRegular() {
+		return "", fmt.Errorf("%s: not a regular file", src)
+	}
+
+	if method == Hard {
+		if err := os.Link(src, dst); err == nil {
+			report(progress, info.Size())
+			return Hard, nil
+		}
+		method = Reflink
+	}
+	used, err := copyData(src, dst, info, method, progress)
+	if err != nil {
+		return "", err
+	}
+	return used, SetModTime(dst, info)
+}
+
+// copyData writes the data of src to a new file dst with the permissions
+// of info, cloning it first if method is Reflink. A partial dst is removed.
+func copyData(src, dst string, info os.FileInfo, method Method, progress func(n int64)) (used Method, err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		out.Close()
+		if err != nil {
+			os.Remove(dst)
+		}
+	}()
+	// The umask may have narrowed the permissions
+	if err := out.Chmod(info.Mode().Perm()); err != nil {
+		return "", err
+	}
+
+	if method == Reflink {
+		if err := reflink(out, in); err == nil {
+			report(progress, info.Size())
+			return Reflink, out.Close()
+		}
+	}
+	// io.CopyN keeps the in-kernel copy between files while reporting
+	// progress for every chunk
+	for {
+		n, err := io.CopyN(out, in, chunkSize)
+		report(progress, n)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return Copy, out.Close()
+}
+
+// SetModTime sets the modification time of path to that of info and
+// leaves its access time alone.
+func SetModTime(path string, info os.FileInfo) error {
+	return os.Chtimes(path, time.Time{}, info.ModTime())
+}
+
+func report(progress func(n int64), n int64) {
+	if progress != nil && n > 0 {
+		progress(n)
+	}
+}
@@ -0,0 +1,125 @@
+package fscopy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeSource(t *testing.T, dir string) (string, time.Time) {
+	t.Helper()
+	src := filepath.Join(dir, "src.bin")
+	if err := os.WriteFile(src, []byte("footage"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(src, 0640); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(src, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	return src, mtime
+}
+
+func TestFileCopyAndReflink(t *testing.T) {
+	for _, method := range []Method{Copy, Reflink} {
+		t.Run(string(method), func(t *testing.T) {
+			dir := t.TempDir()
+			src, mtime := writeSource(t, dir)
+			dst := filepath.Join(dir, "dst.bin")
+
+			var done int64
+			used, err := File(src, dst, method, func(n int64) { done += n })
+			if err != nil {
+				t.Fatalf("File() error: %v", err)
+			}
+			// Reflink falls back to a copy on filesystems that cannot clone
+			if used != method && used != Copy {
+				t.Errorf("used = %q, want %q or copy", used, method)
+			}
+			data, _ := os.ReadFile(dst)
+			if string(data) != "footage" {
+				t.Errorf("content = %q", data)
+			}
+			if done != int64(len("footage")) {
+				t.Errorf("progress = %d bytes, want %d", done, len("footage"))
+			}
+			info, err := os.Stat(dst)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode().Perm() != 0640 {
+				t.Errorf("mode = %v, want 0640", info.Mode().Perm())
+			}
+			if !info.ModTime().Equal(mtime) {
+				t.Errorf("mtime = %v, want %v", info.ModTime(), mtime)
+			}
+		})
+	}
+}
+
+func TestFileHard(t *testing.T) {
+	dir := t.TempDir()
+	src, _ := writeSource(t, dir)
+	dst := filepath.Join(dir, "dst.bin")
+
+	used, err := File(src, dst, Hard, nil)
+	if err != nil {
+		t.Fatalf("File() error: %v", err)
+	}
+	if used != Hard {
+		t.Fatalf("used = %q, want hard", used)
+	}
+	a, _ := os.Stat(src)
+	b, _ := os.Stat(dst)
+	if !os.SameFile(a, b) {
+		t.Error("a hard link should share the file")
+	}
+}
+
+func TestFileSymlink(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "link")
+	if err := os.Symlink("../elsewhere", src); err != nil {
+		t.Skipf("symlinks not available: %v", err)
+	}
+	dst := filepath.Join(dir, "copy")
+
+	used, err := File(src, dst, Copy, nil)
+	if err != nil {
+		t.Fatalf("File() error: %v", err)
+	}
+	if used != Symlink {
+		t.Errorf("used = %q, want symlink", used)
+	}
+	if target, _ := os.Readlink(dst); target != "../elsewhere" {
+		t.Errorf("target = %q, want ../elsewhere", target)
+	}
+}
+
+func TestFileExistingTarget(t *testing.T) {
+	dir := t.TempDir()
+	src, _ := writeSource(t, dir)
+	dst := filepath.Join(dir, "dst.bin")
+	_ = os.WriteFile(dst, []byte("keep"), 0644)
+
+	if _, err := File(src, dst, Copy, nil); err == nil {
+		t.Fatal("expected an error for an existing target")
+	}
+	if data, _ := os.ReadFile(dst); string(data) != "keep" {
+		t.Errorf("existing target was changed to %q", data)
+	}
+}
+
+func TestParseMethod(t *testing.T) {
+	for _, s := range []string{"hard", "reflink", "copy"} {
+		if m, err := ParseMethod(s); err != nil || string(m) != s {
+			t.Errorf("ParseMethod(%q) = %q, %v", s, m, err)
+		}
+	}
+	if _, err := ParseMethod("symlink"); err == nil {
+		t.Error("ParseMethod(symlink) should fail")
+	}
+}
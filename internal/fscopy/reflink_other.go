@@ -0,0 +1,10 @@
+//go:build !linux
+
+package fscopy
+
+import "os"
+
+// reflink makes dst a copy-on-write clone of src.
+func reflink(dst, src *os.File) error {
+	return errReflinkUnsupported
+}
@@ -8,20 +8,105 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/fwartner/prjct/internal/atomicfile"
 	"github.com/fwartner/prjct/internal/config"
 )
 
 // Entry represents a single indexed project.
 type Entry struct {
-	Name         string    `json:"name"`
-	TemplateID   string    `json:"template_id"`
-	TemplateName string    `json:"template_name"`
-	Path         string    `json:"path"`
-	CreatedAt    time.Time `json:"created_at"`
-	Status       string    `json:"status,omitempty"`
-	Notes        []string  `json:"notes,omitempty"`
+	Name          string            `json:"name"`
+	TemplateID    string            `json:"template_id"`
+	TemplateName  string            `json:"template_name"`
+	Path          string            `json:"path"`
+	CreatedAt     time.Time         `json:"created_at"`
+	Status        string            `json:"status,omitempty"`
+	StatusHistory []StatusChange    `json:"status_history,omitempty"`
+	Metadata      map[string]string `json:"metadata,omitempty"`
+	Tags          []string          `json:"tags,omitempty"`
+	Notes         []string          `json:"notes,omitempty"`
+	// Variables and SkippedOptional record how the project was created so
+	// sync and diff can resolve the template the same way.
+	Variables       map[string]string `json:"variables,omitempty"`
+	SkippedOptional []string          `json:"skipped_optional,omitempty"`
+	// FileHashes maps the slash-separated relative path of each generated
+	// file to the SHA-256 of the content it was generated with.
+	FileHashes map[string]string `json:"file_hashes,omitempty"`
+	// TemplateVersion is the template version the project's layout
+	// follows; 0 means version 1.
+	TemplateVersion int `json:"template_version,omitempty"`
+	// ClonedFrom is the path of the project this one was cloned from.
+	ClonedFrom string `json:"cloned_from,omitempty"`
+}
+
+// StatusChange records a single lifecycle status transition.
+type StatusChange struct {
+	From string    `json:"from,omitempty"`
+	To   string    `json:"to"`
+	At   time.Time `json:"at"`
+}
+
+// SetStatus changes the entry's status and appends the transition to its
+// history. It is a no-op if the status is unchanged.
+func (e *Entry) SetStatus(status string, at time.Time) {
+	if e.Status == status {
+		return
+	}
+	e.StatusHistory = append(e.StatusHistory, StatusChange{From: e.Status, To: status, At: at})
+	e.Status = status
+}
+
+// NormalizeTag returns the canonical (trimmed, lowercase) form of a tag.
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// HasTag reports whether the entry carries tag (case-insensitive).
+func (e *Entry) HasTag(tag string) bool {
+	tag = NormalizeTag(tag)
+	for _, t := range e.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// AddTags adds the given tags, skipping duplicates, and keeps Tags sorted.
+// It returns the tags that were actually added.
+func (e *Entry) AddTags(tags ...string) []string {
+	var added []string
+	for _, t := range tags {
+		t = NormalizeTag(t)
+		if t == "" || e.HasTag(t) {
+			continue
+		}
+		e.Tags = append(e.Tags, t)
+		added = append(added, t)
+	}
+	sort.Strings(e.Tags)
+	return added
+}
+
+// RemoveTags removes the given tags and returns those that were present.
+func (e *Entry) RemoveTags(tags ...string) []string {
+	var removed []string
+	for _, t := range tags {
+		t = NormalizeTag(t)
+		for i, existing := range e.Tags {
+			if existing == t {
+				e.Tags = append(e.Tags[:i], e.Tags[i+1:]...)
+				removed = append(removed, t)
+				break
+			}
+		}
+	}
+	if len(e.Tags) == 0 {
+		e.Tags = nil
+	}
+	return removed
 }
 
 // Index holds all tracked projects.
@@ -70,15 +155,23 @@ func Save(path string, idx *Index) error {
 	}
 	data = append(data, '\n')
 
-	if err := os.WriteFile(path, data, 0644); err != nil {
+	if err := atomicfile.WriteFile(path, data, 0644); err != nil {
 		return fmt.Errorf("cannot write index: %w", err)
 	}
 	return nil
 }
 
+// writeMu serializes the read-modify-write cycles of Add, Remove and Update
+// so that commands running concurrently in one process do not lose
+// each other's changes.
+var writeMu sync.Mutex
+
 // Add appends an entry to the index. If an entry with the same Path
 // already exists, it is skipped (no duplicates).
 func Add(path string, entry Entry) error {
+	writeMu.Lock()
+	defer writeMu.Unlock()
+
 	idx, err := Load(path)
 	if err != nil {
 		return err
@@ -97,6 +190,9 @@ func Add(path string, entry Entry) error {
 // Remove deletes the entry matching projectPath from the index.
 // It is a no-op if the path is not found.
 func Remove(path string, projectPath string) error {
+	writeMu.Lock()
+	defer writeMu.Unlock()
+
 	idx, err := Load(path)
 	if err != nil {
 		return err
@@ -113,25 +209,103 @@ func Remove(path string, projectPath string) error {
 }
 
 // Search returns entries matching query as a case-insensitive substring
-// of Name, TemplateID, TemplateName, or Path. An empty query returns all entries.
+// of Name, TemplateID, TemplateName or Path. An entry whose path equals
+// query is listed first. An empty query returns all entries.
 func Search(idx *Index, query string) []Entry {
 	if query == "" {
 		return idx.Projects
 	}
 
 	q := strings.ToLower(query)
-	var results []Entry
+	var exact, results []Entry
+	for _, e := range idx.Projects {
+		if e.Path == query {
+			exact = append(exact, e)
+		} else if matchesQuery(e, q) {
+			results = append(results, e)
+		}
+	}
+	return append(exact, results...)
+}
+
+// SearchWithMeta is Search that also matches metadata values. Entries
+// matching on metadata only are listed last. It is meant for listing
+// projects; commands that act on the matches use Search, so a metadata
+// value never selects a project by accident.
+func SearchWithMeta(idx *Index, query string) []Entry {
+	results := Search(idx, query)
+	if query == "" {
+		return results
+	}
+	q := strings.ToLower(query)
 	for _, e := range idx.Projects {
-		if strings.Contains(strings.ToLower(e.Name), q) ||
-			strings.Contains(strings.ToLower(e.TemplateID), q) ||
-			strings.Contains(strings.ToLower(e.TemplateName), q) ||
-			strings.Contains(strings.ToLower(e.Path), q) {
+		if e.Path != query && !matchesQuery(e, q) && matchesMeta(e, q) {
+			results = append(results, e)
+		}
+	}
+	return results
+}
+
+// matchesQuery reports whether the name, template or path contains the
+// lowercased query.
+func matchesQuery(e Entry, q string) bool {
+	return strings.Contains(strings.ToLower(e.Name), q) ||
+		strings.Contains(strings.ToLower(e.TemplateID), q) ||
+		strings.Contains(strings.ToLower(e.TemplateName), q) ||
+		strings.Contains(strings.ToLower(e.Path), q)
+}
+
+// matchesMeta reports whether any metadata value contains the lowercased query.
+func matchesMeta(e Entry, q string) bool {
+	for _, v := range e.Metadata {
+		if strings.Contains(strings.ToLower(v), q) {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterByMeta returns only entries whose metadata key equals value
+// (case-insensitive).
+func FilterByMeta(entries []Entry, key, value string) []Entry {
+	var results []Entry
+	for _, e := range entries {
+		if strings.EqualFold(e.Metadata[key], value) {
+			results = append(results, e)
+		}
+	}
+	return results
+}
+
+// FilterByTags returns only entries carrying all of the given tags.
+func FilterByTags(entries []Entry, tags []string) []Entry {
+	var results []Entry
+	for _, e := range entries {
+		match := true
+		for _, t := range tags {
+			if !e.HasTag(t) {
+				match = false
+				break
+			}
+		}
+		if match {
 			results = append(results, e)
 		}
 	}
 	return results
 }
 
+// TagCounts returns the number of entries carrying each tag.
+func TagCounts(entries []Entry) map[string]int {
+	counts := make(map[string]int)
+	for _, e := range entries {
+		for _, t := range e.Tags {
+			counts[t]++
+		}
+	}
+	return counts
+}
+
 // FilterByTemplate returns only entries matching the given template ID.
 func FilterByTemplate(entries []Entry, templateID string) []Entry {
 	var results []Entry
@@ -143,6 +317,17 @@ func FilterByTemplate(entries []Entry, templateID string) []Entry {
 	return results
 }
 
+// FilterByStatus returns only entries with the given status.
+func FilterByStatus(entries []Entry, status string) []Entry {
+	var results []Entry
+	for _, e := range entries {
+		if e.Status == status {
+			results = append(results, e)
+		}
+	}
+	return results
+}
+
 // SortByCreatedDesc sorts entries by CreatedAt descending (newest first).
 func SortByCreatedDesc(entries []Entry) {
 	sort.Slice(entries, func(i, j int) bool {
@@ -172,7 +357,7 @@ func FuzzySearch(idx *Index, query string, maxDist int) []Entry {
 			strings.ToLower(e.TemplateName),
 		}
 		for _, f := range fields {
-			if levenshtein(q, f) <= maxDist || containsFuzzy(f, q, maxDist) {
+			if EditDistance(q, f) <= maxDist || containsFuzzy(f, q, maxDist) {
 				results = append(results, e)
 				break
 			}
@@ -181,8 +366,8 @@ func FuzzySearch(idx *Index, query string, maxDist int) []Entry {
 	return results
 }
 
-// levenshtein computes the edit distance between two strings.
-func levenshtein(a, b string) int {
+// EditDistance computes the Levenshtein edit distance between two strings.
+func EditDistance(a, b string) int {
 	la, lb := len(a), len(b)
 	if la == 0 {
 		return lb
@@ -226,7 +411,7 @@ func containsFuzzy(haystack, needle string, maxDist int) bool {
 			end = len(haystack)
 		}
 		sub := haystack[start:end]
-		if levenshtein(needle, sub) <= maxDist {
+		if EditDistance(needle, sub) <= maxDist {
 			return true
 		}
 	}
@@ -243,6 +428,9 @@ func minInt(a, b int) int {
 // Update modifies the entry with the given projectPath using the provided
 // function. If no entry matches, it is a no-op. The index is saved to disk.
 func Update(path string, projectPath string, fn func(*Entry)) error {
+	writeMu.Lock()
+	defer writeMu.Unlock()
+
 	idx, err := Load(path)
 	if err != nil {
 		return err
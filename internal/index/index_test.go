@@ -463,7 +463,7 @@ func TestEntryNotes(t *testing.T) {
 
 // --- Levenshtein tests ---
 
-func TestLevenshtein(t *testing.T) {
+func TestEditDistance(t *testing.T) {
 	tests := []struct {
 		a, b string
 		want int
@@ -477,9 +477,9 @@ func TestLevenshtein(t *testing.T) {
 		{"video", "vido", 1},
 	}
 	for _, tt := range tests {
-		got := levenshtein(tt.a, tt.b)
+		got := EditDistance(tt.a, tt.b)
 		if got != tt.want {
-			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+			t.Errorf("EditDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
 		}
 	}
 }
@@ -538,3 +538,117 @@ func TestFuzzySearchEmpty(t *testing.T) {
 		t.Errorf("FuzzySearch empty: got %d, want 2", len(results))
 	}
 }
+
+func TestEntrySetStatus(t *testing.T) {
+	e := Entry{Name: "p"}
+	t1 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	t2 := t1.Add(time.Hour)
+
+	e.SetStatus("active", t1)
+	e.SetStatus("active", t2) // unchanged — no history entry
+	e.SetStatus("review", t2)
+
+	if e.Status != "review" {
+		t.Errorf("Status = %q, want review", e.Status)
+	}
+	if len(e.StatusHistory) != 2 {
+		t.Fatalf("len(StatusHistory) = %d, want 2", len(e.StatusHistory))
+	}
+	if h := e.StatusHistory[1]; h.From != "active" || h.To != "review" || !h.At.Equal(t2) {
+		t.Errorf("StatusHistory[1] = %+v", h)
+	}
+}
+
+func TestFilterByStatus(t *testing.T) {
+	entries := []Entry{
+		{Name: "a", Status: "active"},
+		{Name: "b", Status: "review"},
+		{Name: "c", Status: "active"},
+	}
+	got := FilterByStatus(entries, "active")
+	if len(got) != 2 {
+		t.Errorf("FilterByStatus() returned %d, want 2", len(got))
+	}
+}
+
+func TestSearchMetadata(t *testing.T) {
+	idx := &Index{Projects: []Entry{
+		{Name: "Spot", TemplateID: "video", Path: "/a", Metadata: map[string]string{"client": "Acme Corp"}},
+		{Name: "acme-site", TemplateID: "dev", Path: "/b"},
+		{Name: "Other", TemplateID: "photo", Path: "/c"},
+	}}
+	if results := Search(idx, "acme"); len(results) != 1 || results[0].Name != "acme-site" {
+		t.Errorf("Search() = %v, want only the name match", results)
+	}
+	results := SearchWithMeta(idx, "acme")
+	if len(results) != 2 {
+		t.Fatalf("SearchWithMeta() returned %d, want 2", len(results))
+	}
+	if results[0].Name != "acme-site" {
+		t.Errorf("name match should come first, got %q", results[0].Name)
+	}
+}
+
+func TestFilterByMeta(t *testing.T) {
+	entries := []Entry{
+		{Name: "a", Metadata: map[string]string{"client": "Acme"}},
+		{Name: "b", Metadata: map[string]string{"client": "Globex"}},
+		{Name: "c"},
+	}
+	got := FilterByMeta(entries, "client", "acme")
+	if len(got) != 1 || got[0].Name != "a" {
+		t.Errorf("FilterByMeta() = %v", got)
+	}
+}
+
+func TestEntryTags(t *testing.T) {
+	var e Entry
+	added := e.AddTags("Rush", "pitch", "rush")
+	if len(added) != 2 {
+		t.Errorf("AddTags() added %v, want 2 tags", added)
+	}
+	if len(e.Tags) != 2 || e.Tags[0] != "pitch" || e.Tags[1] != "rush" {
+		t.Errorf("Tags = %v, want sorted [pitch rush]", e.Tags)
+	}
+	if !e.HasTag("RUSH") {
+		t.Error("HasTag should be case-insensitive")
+	}
+
+	removed := e.RemoveTags("rush", "missing")
+	if len(removed) != 1 || removed[0] != "rush" {
+		t.Errorf("RemoveTags() removed %v, want [rush]", removed)
+	}
+	e.RemoveTags("pitch")
+	if e.Tags != nil {
+		t.Errorf("Tags = %v, want nil after removing all", e.Tags)
+	}
+}
+
+func TestFilterByTagsAndCounts(t *testing.T) {
+	entries := []Entry{
+		{Name: "a", Tags: []string{"pitch", "rush"}},
+		{Name: "b", Tags: []string{"rush"}},
+		{Name: "c"},
+	}
+	if got := FilterByTags(entries, []string{"rush"}); len(got) != 2 {
+		t.Errorf("FilterByTags(rush) = %d entries, want 2", len(got))
+	}
+	if got := FilterByTags(entries, []string{"rush", "pitch"}); len(got) != 1 || got[0].Name != "a" {
+		t.Errorf("FilterByTags(rush, pitch) = %v, want [a]", got)
+	}
+	counts := TagCounts(entries)
+	if counts["rush"] != 2 || counts["pitch"] != 1 {
+		t.Errorf("TagCounts() = %v", counts)
+	}
+}
+
+func TestSearchExactPathFirst(t *testing.T) {
+	idx := &Index{Projects: []Entry{
+		{Name: "client-2", Path: "/p/client-2"},
+		{Name: "client", Path: "/p/client"},
+	}}
+	results := Search(idx, "/p/client")
+	if len(results) != 2 || results[0].Name != "client" {
+		t.Errorf("Search(/p/client) = %+v, want the exact path first", results)
+	}
+}
@@ -1,13 +1,18 @@
 package journal
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/fwartner/prjct/internal/atomicfile"
 	"github.com/fwartner/prjct/internal/config"
 )
 
@@ -23,20 +28,169 @@ const (
 	OpSync    OpType = "sync"
 	OpClean   OpType = "clean"
 	OpNote    OpType = "note"
+	OpStatus  OpType = "status"
+	OpMeta    OpType = "meta"
+	OpTag     OpType = "tag"
+	OpReindex OpType = "reindex"
+	OpRestore OpType = "restore"
+	OpMigrate OpType = "migrate"
+	OpTidy    OpType = "tidy"
 )
 
-// Record represents a single journaled operation.
+// Record represents a single journaled operation. Created and Removed list
+// the filesystem paths the operation added or deleted; Indexed lists
+// projects added to the index without touching the filesystem. Records
+// written by the same command invocation share a Group.
 type Record struct {
+	ID        int               `json:"id"`
+	Group     string            `json:"group,omitempty"`
 	Timestamp time.Time         `json:"timestamp"`
 	Operation OpType            `json:"operation"`
 	Details   map[string]string `json:"details"`
+	Created   []string          `json:"created,omitempty"`
+	Removed   []string          `json:"removed,omitempty"`
+	Indexed   []string          `json:"indexed,omitempty"`
+	Undone    *Undo             `json:"undone,omitempty"`
+}
+
+// NewGroupID returns a random identifier for grouping the records of one
+// command invocation.
+func NewGroupID() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%08x", time.Now().UnixNano()&0xffffffff)
+	}
+	return hex.EncodeToString(b)
+}
+
+// Undo marks a record as reverted. State holds whatever the undo had to
+// discard so that a later redo can restore it.
+type Undo struct {
+	At    time.Time         `json:"at"`
+	State map[string]string `json:"state,omitempty"`
 }
 
 // Journal holds a list of operation records.
 type Journal struct {
+	LastID  int      `json:"last_id,omitempty"`
 	Records []Record `json:"records"`
 }
 
+// Paths returns the project paths a record operates on, including the
+// projects it added to the index, which is all a reindex record carries.
+func (r *Record) Paths() []string {
+	var paths []string
+	for _, k := range []string{"path", "old_path", "new_path"} {
+		if p := r.Details[k]; p != "" {
+			paths = append(paths, p)
+		}
+	}
+	return append(paths, r.Indexed...)
+}
+
+// Templates returns the template IDs a record operates on.
+func (r *Record) Templates() []string {
+	var ids []string
+	if t := r.Details["template"]; t != "" {
+		ids = append(ids, t)
+	}
+	if t := r.Details["templates"]; t != "" {
+		ids = append(ids, strings.Split(t, ",")...)
+	}
+	return ids
+}
+
+// Overlaps reports whether two records touch a common path, or a common
+// template when one of them is an import. Paths overlap if they are equal
+// or one contains the other.
+func (r *Record) Overlaps(other *Record) bool {
+	for _, a := range r.Paths() {
+		for _, b := range other.Paths() {
+			if pathContains(a, b) || pathContains(b, a) {
+				return true
+			}
+		}
+	}
+	if r.Operation != OpImport && other.Operation != OpImport {
+		return false
+	}
+	for _, a := range r.Templates() {
+		for _, b := range other.Templates() {
+			if a == b {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func pathContains(parent, child string) bool {
+	if parent == child {
+		return true
+	}
+	rel, err := filepath.Rel(parent, child)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// Find returns the record with the given ID, or nil.
+func (j *Journal) Find(id int) *Record {
+	for i := range j.Records {
+		if j.Records[i].ID == id {
+			return &j.Records[i]
+		}
+	}
+	return nil
+}
+
+// LastActive returns the most recent record that has not been undone, or nil.
+func (j *Journal) LastActive() *Record {
+	for i := len(j.Records) - 1; i >= 0; i-- {
+		if j.Records[i].Undone == nil {
+			return &j.Records[i]
+		}
+	}
+	return nil
+}
+
+// LastUndone returns the most recently undone record, or nil.
+func (j *Journal) LastUndone() *Record {
+	var last *Record
+	for i := range j.Records {
+		u := j.Records[i].Undone
+		if u != nil && (last == nil || u.At.After(last.Undone.At)) {
+			last = &j.Records[i]
+		}
+	}
+	return last
+}
+
+// Dependents returns the active records made after rec that touch the same
+// paths or templates. They must be undone before rec can be.
+func (j *Journal) Dependents(rec *Record) []Record {
+	var deps []Record
+	for _, r := range j.Records {
+		if r.ID > rec.ID && r.Undone == nil && r.Overlaps(rec) {
+			deps = append(deps, r)
+		}
+	}
+	return deps
+}
+
+// Conflicts returns the active records made after rec was undone that touch
+// the same paths or templates. While they exist rec cannot be redone.
+func (j *Journal) Conflicts(rec *Record) []Record {
+	if rec.Undone == nil {
+		return nil
+	}
+	var conflicts []Record
+	for _, r := range j.Records {
+		if r.ID != rec.ID && r.Undone == nil && r.Timestamp.After(rec.Undone.At) && r.Overlaps(rec) {
+			conflicts = append(conflicts, r)
+		}
+	}
+	return conflicts
+}
+
 // JournalPath returns the path to the journal file alongside the config.
 func JournalPath() (string, error) {
 	cfgPath, err := config.DefaultPath()
@@ -60,9 +214,25 @@ func Load(path string) (*Journal, error) {
 	if err := json.Unmarshal(data, &j); err != nil {
 		return nil, fmt.Errorf("corrupt journal: %w", err)
 	}
+	j.assignIDs()
 	return &j, nil
 }
 
+// assignIDs numbers records written before IDs were introduced.
+func (j *Journal) assignIDs() {
+	for _, r := range j.Records {
+		if r.ID > j.LastID {
+			j.LastID = r.ID
+		}
+	}
+	for i := range j.Records {
+		if j.Records[i].ID == 0 {
+			j.LastID++
+			j.Records[i].ID = j.LastID
+		}
+	}
+}
+
 // Save writes the journal to disk.
 func Save(path string, j *Journal) error {
 	dir := filepath.Dir(path)
@@ -76,20 +246,40 @@ func Save(path string, j *Journal) error {
 	}
 	data = append(data, '\n')
 
-	return os.WriteFile(path, data, 0644)
+	return atomicfile.WriteFile(path, data, 0644)
 }
 
-// Append adds a record to the journal.
+// writeMu serializes changes to the journal file within the process.
+var writeMu sync.Mutex
+
+// Append adds a record to the journal, assigning it the next ID, and
+// applies the default retention.
 func Append(path string, rec Record) error {
+	return AppendWithRetention(path, rec, Retention{})
+}
+
+// AppendWithRetention adds a record to the journal and rotates records
+// beyond the retention limits into monthly archive files.
+func AppendWithRetention(path string, rec Record, r Retention) error {
+	writeMu.Lock()
+	defer writeMu.Unlock()
+
 	j, err := Load(path)
 	if err != nil {
 		return err
 	}
+	j.LastID++
+	rec.ID = j.LastID
 	j.Records = append(j.Records, rec)
 
-	// Keep only last 100 records
-	if len(j.Records) > 100 {
-		j.Records = j.Records[len(j.Records)-100:]
+	var expired []Record
+	j.Records, expired = r.split(j.Records, time.Now())
+	// Rotate before saving: if saving fails, the records are still in the
+	// active journal and rotating them again replaces them by ID.
+	if len(expired) > 0 {
+		if err := rotate(path, expired); err != nil {
+			return err
+		}
 	}
 
 	return Save(path, j)
@@ -108,8 +298,29 @@ func Last(path string) (*Record, error) {
 	return &rec, nil
 }
 
+// Mark applies fn to the record with the given ID and saves the journal.
+// Use it to set or clear a record's Undone marker.
+func Mark(path string, id int, fn func(*Record)) error {
+	writeMu.Lock()
+	defer writeMu.Unlock()
+
+	j, err := Load(path)
+	if err != nil {
+		return err
+	}
+	rec := j.Find(id)
+	if rec == nil {
+		return fmt.Errorf("no journal record with ID %d", id)
+	}
+	fn(rec)
+	return Save(path, j)
+}
+
 // RemoveLast removes the most recent record from the journal.
 func RemoveLast(path string) error {
+	writeMu.Lock()
+	defer writeMu.Unlock()
+
 	j, err := Load(path)
 	if err != nil {
 		return err
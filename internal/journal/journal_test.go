@@ -1,6 +1,7 @@
 package journal
 
 import (
+	"os"
 	"path/filepath"
 	"testing"
 	"time"
@@ -117,3 +118,97 @@ func TestMaxRecords(t *testing.T) {
 		t.Errorf("got %d records, want <=100", len(j.Records))
 	}
 }
+
+func TestAppendAssignsIDs(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "journal.json")
+
+	// A legacy journal without IDs
+	legacy := `{"records":[{"timestamp":"2026-01-01T00:00:00Z","operation":"create","details":{}}]}`
+	if err := os.WriteFile(path, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_ = Append(path, Record{Timestamp: time.Now(), Operation: OpNote, Details: map[string]string{}})
+
+	j, _ := Load(path)
+	if len(j.Records) != 2 || j.Records[0].ID != 1 || j.Records[1].ID != 2 {
+		t.Errorf("records = %+v, want IDs 1 and 2", j.Records)
+	}
+}
+
+func TestDependentsAndConflicts(t *testing.T) {
+	now := time.Now()
+	j := &Journal{Records: []Record{
+		{ID: 1, Timestamp: now, Operation: OpCreate, Details: map[string]string{"path": "/p/a", "template": "dev"}},
+		{ID: 2, Timestamp: now, Operation: OpNote, Details: map[string]string{"path": "/p/a"}},
+		{ID: 3, Timestamp: now, Operation: OpCreate, Details: map[string]string{"path": "/p/b", "template": "video"}},
+		{ID: 4, Timestamp: now, Operation: OpImport, Details: map[string]string{"templates": "photo,audio"}},
+		{ID: 5, Timestamp: now, Operation: OpCreate, Details: map[string]string{"path": "/p/c", "template": "photo"}},
+		{ID: 6, Timestamp: now, Operation: OpCreate, Details: map[string]string{"path": "/p/d", "template": "photo"}},
+	}}
+
+	if deps := j.Dependents(j.Find(1)); len(deps) != 1 || deps[0].ID != 2 {
+		t.Errorf("Dependents(1) = %+v, want [2]", deps)
+	}
+	if deps := j.Dependents(j.Find(3)); len(deps) != 0 {
+		t.Errorf("Dependents(3) = %+v, want none", deps)
+	}
+	if deps := j.Dependents(j.Find(4)); len(deps) != 2 {
+		t.Errorf("Dependents(4) = %+v, want creates from imported template", deps)
+	}
+	if deps := j.Dependents(j.Find(5)); len(deps) != 0 {
+		t.Errorf("creates sharing a template should be independent, got %+v", deps)
+	}
+
+	j.Find(2).Undone = &Undo{At: now}
+	if deps := j.Dependents(j.Find(1)); len(deps) != 0 {
+		t.Errorf("undone records should not block, got %+v", deps)
+	}
+	if got := j.LastActive(); got.ID != 6 {
+		t.Errorf("LastActive() = %d, want 6", got.ID)
+	}
+	if got := j.LastUndone(); got == nil || got.ID != 2 {
+		t.Errorf("LastUndone() = %+v, want 2", got)
+	}
+
+	j.Records = append(j.Records, Record{ID: 7, Timestamp: now.Add(time.Second), Operation: OpTag, Details: map[string]string{"path": "/p/a"}})
+	if c := j.Conflicts(j.Find(2)); len(c) != 1 || c[0].ID != 7 {
+		t.Errorf("Conflicts(2) = %+v, want [7]", c)
+	}
+}
+
+func TestDependentsOfReindex(t *testing.T) {
+	now := time.Now()
+	j := &Journal{Records: []Record{
+		{ID: 1, Timestamp: now, Operation: OpReindex, Indexed: []string{"/p/a", "/p/b"}},
+		{ID: 2, Timestamp: now, Operation: OpStatus, Details: map[string]string{"path": "/p/b"}},
+		{ID: 3, Timestamp: now, Operation: OpNote, Details: map[string]string{"path": "/p/c"}},
+	}}
+
+	if deps := j.Dependents(j.Find(1)); len(deps) != 1 || deps[0].ID != 2 {
+		t.Errorf("Dependents(reindex) = %+v, want [2]", deps)
+	}
+
+	j.Find(1).Undone = &Undo{At: now}
+	j.Records = append(j.Records, Record{ID: 4, Timestamp: now.Add(time.Second), Operation: OpTag, Details: map[string]string{"path": "/p/a"}})
+	if c := j.Conflicts(j.Find(1)); len(c) != 1 || c[0].ID != 4 {
+		t.Errorf("Conflicts(reindex) = %+v, want [4]", c)
+	}
+}
+
+func TestMark(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "journal.json")
+	_ = Append(path, Record{Timestamp: time.Now(), Operation: OpCreate, Details: map[string]string{}})
+
+	if err := Mark(path, 1, func(r *Record) { r.Undone = &Undo{At: time.Now()} }); err != nil {
+		t.Fatalf("Mark: %v", err)
+	}
+	j, _ := Load(path)
+	if j.Records[0].Undone == nil {
+		t.Error("record should be marked undone")
+	}
+	if err := Mark(path, 99, func(*Record) {}); err == nil {
+		t.Error("expected error for unknown ID")
+	}
+}
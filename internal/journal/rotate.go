@@ -0,0 +1,224 @@
+package journal
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fwartner/prjct/internal/atomicfile"
+)
+
+// DefaultMaxRecords is the number of records kept in the active journal
+// when no limit is configured.
+const DefaultMaxRecords = 100
+
+// rotatedMonthLayout is the month suffix of rotated journal files.
+const rotatedMonthLayout = "2006-01"
+
+// Retention limits the records kept in the active journal. A zero
+// MaxRecords means DefaultMaxRecords; a zero MaxAge keeps records
+// regardless of age.
+type Retention struct {
+	MaxRecords int
+	MaxAge     time.Duration
+}
+
+// split partitions records into those kept in the active journal and those
+// to rotate out. The newest record is always kept.
+func (r Retention) split(records []Record, now time.Time) (kept, expired []Record) {
+	max := r.MaxRecords
+	if max <= 0 {
+		max = DefaultMaxRecords
+	}
+	overflow := len(records) - max
+	for i, rec := range records {
+		tooOld := r.MaxAge > 0 && rec.Timestamp.Before(now.Add(-r.MaxAge))
+		if i < len(records)-1 && (i < overflow || tooOld) {
+			expired = append(expired, rec)
+			continue
+		}
+		kept = append(kept, rec)
+	}
+	return kept, expired
+}
+
+// RotatedPath returns the compressed archive file holding the records of
+// the given month, e.g. journal-2026-03.json.gz next to journal.json.
+func RotatedPath(path string, month time.Time) string {
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return filepath.Join(filepath.Dir(path), fmt.Sprintf("%s-%s.json.gz", base, month.Format(rotatedMonthLayout)))
+}
+
+// RotatedFiles returns the rotated archive files of the journal at path,
+// oldest month first.
+func RotatedFiles(path string) ([]string, error) {
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	files, err := filepath.Glob(filepath.Join(filepath.Dir(path), base+"-*.json.gz"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// rotate adds records to their monthly archive files. Records already
+// there are replaced by ID, so rotating the same records again, e.g. after
+// the active journal could not be saved, does not duplicate them.
+func rotate(path string, records []Record) error {
+	byMonth := make(map[string][]Record)
+	for _, r := range records {
+		p := RotatedPath(path, r.Timestamp)
+		byMonth[p] = append(byMonth[p], r)
+	}
+	for p, recs := range byMonth {
+		j, err := loadRotated(p)
+		if err != nil {
+			return err
+		}
+		j.Records = mergeRecords(j.Records, recs)
+		if err := saveRotated(p, j); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeRecords adds recs to records, replacing records with the same ID,
+// and returns them ordered by ID.
+func mergeRecords(records, recs []Record) []Record {
+	byID := make(map[int]int, len(records))
+	for i, r := range records {
+		byID[r.ID] = i
+	}
+	for _, r := range recs {
+		if i, ok := byID[r.ID]; ok {
+			records[i] = r
+			continue
+		}
+		byID[r.ID] = len(records)
+		records = append(records, r)
+	}
+	sort.SliceStable(records, func(a, b int) bool { return records[a].ID < records[b].ID })
+	return records
+}
+
+func loadRotated(path string) (*Journal, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return &Journal{}, nil
+		}
+		return nil, fmt.Errorf("cannot read rotated journal: %w", err)
+	}
+	defer f.Close()
+
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("corrupt rotated journal %s: %w", filepath.Base(path), err)
+	}
+	defer gr.Close()
+
+	var j Journal
+	if err := json.NewDecoder(gr).Decode(&j); err != nil {
+		return nil, fmt.Errorf("corrupt rotated journal %s: %w", filepath.Base(path), err)
+	}
+	return &j, nil
+}
+
+// saveRotated writes a rotated journal through a temporary file so an
+// interrupted write never truncates existing history.
+func saveRotated(path string, j *Journal) error {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(gw).Encode(j); err != nil {
+		return fmt.Errorf("cannot write rotated journal: %w", err)
+	}
+	if err := gw.Close(); err != nil {
+		return fmt.Errorf("cannot write rotated journal: %w", err)
+	}
+	if err := atomicfile.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("cannot write rotated journal: %w", err)
+	}
+	return nil
+}
+
+// Query selects records by time range and operation. Zero fields match
+// everything.
+type Query struct {
+	Since time.Time
+	Until time.Time
+	Op    OpType
+}
+
+// Match reports whether rec satisfies the query.
+func (q Query) Match(rec Record) bool {
+	if !q.Since.IsZero() && rec.Timestamp.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && rec.Timestamp.After(q.Until) {
+		return false
+	}
+	return q.Op == "" || rec.Operation == q.Op
+}
+
+// coversMonth reports whether any part of the month could match the query.
+// The month is widened by a day on each side to allow for time zones.
+func (q Query) coversMonth(month time.Time) bool {
+	end := month.AddDate(0, 1, 1)
+	month = month.AddDate(0, 0, -1)
+	if !q.Since.IsZero() && !end.After(q.Since) {
+		return false
+	}
+	return q.Until.IsZero() || !month.After(q.Until)
+}
+
+// Search returns the records matching q from the active journal and all
+// rotated files, oldest first.
+func Search(path string, q Query) ([]Record, error) {
+	files, err := RotatedFiles(path)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[int]bool)
+	var results []Record
+	add := func(records []Record) {
+		for _, r := range records {
+			if !seen[r.ID] && q.Match(r) {
+				seen[r.ID] = true
+				results = append(results, r)
+			}
+		}
+	}
+
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	for _, f := range files {
+		name := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(f), base+"-"), ".json.gz")
+		if month, err := time.ParseInLocation(rotatedMonthLayout, name, time.Local); err == nil && !q.coversMonth(month) {
+			continue
+		}
+		j, err := loadRotated(f)
+		if err != nil {
+			return nil, err
+		}
+		add(j.Records)
+	}
+
+	j, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	add(j.Records)
+
+	sort.SliceStable(results, func(i, k int) bool {
+		return results[i].ID < results[k].ID
+	})
+	return results, nil
+}
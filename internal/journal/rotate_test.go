@@ -0,0 +1,129 @@
+package journal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAppendRotatesByCount(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "journal.json")
+	start := time.Date(2026, 3, 30, 12, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 5; i++ {
+		rec := Record{Timestamp: start.AddDate(0, 0, i), Operation: OpCreate, Details: map[string]string{}}
+		if err := AppendWithRetention(path, rec, Retention{MaxRecords: 2}); err != nil {
+			t.Fatalf("AppendWithRetention: %v", err)
+		}
+	}
+
+	j, _ := Load(path)
+	if len(j.Records) != 2 || j.Records[0].ID != 4 {
+		t.Fatalf("active records = %+v, want IDs 4 and 5", j.Records)
+	}
+
+	files, _ := RotatedFiles(path)
+	if len(files) != 2 {
+		t.Fatalf("rotated files = %v, want March and April", files)
+	}
+	if filepath.Base(files[0]) != "journal-2026-03.json.gz" {
+		t.Errorf("first rotated file = %s", filepath.Base(files[0]))
+	}
+
+	all, err := Search(path, Query{})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(all) != 5 {
+		t.Errorf("Search() found %d records, want all 5", len(all))
+	}
+	for i, r := range all {
+		if r.ID != i+1 {
+			t.Errorf("record %d has ID %d, want ordered IDs", i, r.ID)
+		}
+	}
+}
+
+func TestAppendRotatesByAge(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "journal.json")
+
+	old := Record{Timestamp: time.Now().AddDate(0, 0, -40), Operation: OpNote, Details: map[string]string{}}
+	_ = AppendWithRetention(path, old, Retention{})
+	recent := Record{Timestamp: time.Now(), Operation: OpNote, Details: map[string]string{}}
+	_ = AppendWithRetention(path, recent, Retention{MaxAge: 30 * 24 * time.Hour})
+
+	j, _ := Load(path)
+	if len(j.Records) != 1 || j.Records[0].ID != 2 {
+		t.Errorf("active records = %+v, want only the recent one", j.Records)
+	}
+	if _, err := os.Stat(RotatedPath(path, old.Timestamp)); err != nil {
+		t.Errorf("old record should be rotated: %v", err)
+	}
+}
+
+func TestRotateIsIdempotent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.json")
+	march := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	recs := []Record{
+		{ID: 1, Timestamp: march, Operation: OpNote},
+		{ID: 2, Timestamp: march.Add(time.Hour), Operation: OpNote},
+	}
+
+	if err := rotate(path, recs); err != nil {
+		t.Fatalf("rotate: %v", err)
+	}
+	// As if saving the trimmed journal had failed and the records rotated
+	// again with the next append, one of them undone in between
+	recs[1].Undone = &Undo{At: march.Add(2 * time.Hour)}
+	if err := rotate(path, append(recs, Record{ID: 3, Timestamp: march.Add(3 * time.Hour), Operation: OpNote})); err != nil {
+		t.Fatalf("rotate again: %v", err)
+	}
+
+	j, err := loadRotated(RotatedPath(path, march))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(j.Records) != 3 {
+		t.Fatalf("rotated records = %+v, want IDs 1 to 3 once", j.Records)
+	}
+	for i, r := range j.Records {
+		if r.ID != i+1 {
+			t.Errorf("record %d has ID %d, want ordered IDs", i, r.ID)
+		}
+	}
+	if j.Records[1].Undone == nil {
+		t.Error("rotating again should keep the newer copy of a record")
+	}
+}
+
+func TestSearchQuery(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "journal.json")
+	jan := time.Date(2026, 1, 15, 10, 0, 0, 0, time.UTC)
+	feb := time.Date(2026, 2, 15, 10, 0, 0, 0, time.UTC)
+	mar := time.Date(2026, 3, 15, 10, 0, 0, 0, time.UTC)
+
+	for _, rec := range []Record{
+		{Timestamp: jan, Operation: OpCreate},
+		{Timestamp: feb, Operation: OpNote},
+		{Timestamp: mar, Operation: OpCreate},
+	} {
+		_ = AppendWithRetention(path, rec, Retention{MaxRecords: 1})
+	}
+
+	got, err := Search(path, Query{Op: OpCreate})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(got) != 2 {
+		t.Errorf("Search(op=create) = %d records, want 2", len(got))
+	}
+
+	got, _ = Search(path, Query{Since: feb.AddDate(0, 0, -1), Until: feb.AddDate(0, 0, 1)})
+	if len(got) != 1 || got[0].Operation != OpNote {
+		t.Errorf("Search(February) = %+v, want the note", got)
+	}
+}
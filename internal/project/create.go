@@ -1,9 +1,12 @@
 package project
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
 
 	"github.com/fwartner/prjct/internal/config"
@@ -30,6 +33,10 @@ type Result struct {
 	DirsCreated  int
 	FilesCreated int
 	TemplateName string
+	// Dirs and Files list every path that was created, or would be
+	// created in dry-run mode, in creation order.
+	Dirs  []string
+	Files []string
 }
 
 // ExecHook is the function used to run post-creation hooks. Replaceable for testing.
@@ -99,6 +106,8 @@ func Create(tmpl *config.Template, projectName string, opts CreateOptions) (*Res
 		DirsCreated:  dirCount,
 		FilesCreated: fileCount,
 		TemplateName: tmpl.Name,
+		Dirs:         created,
+		Files:        createdFiles,
 	}, nil
 }
 
@@ -108,14 +117,8 @@ func createTree(dirs []config.Directory, parentPath string, opts CreateOptions,
 	fileCount := 0
 
 	for _, d := range dirs {
-		dirName := tmplpkg.Resolve(d.Name, opts.Variables)
-
-		if d.Optional && opts.SkipOptional != nil && opts.SkipOptional[d.Name] {
-			continue
-		}
-
-		// Evaluate conditional directory
-		if d.When != "" && !config.EvalWhen(d.When, opts.Variables) {
+		dirName, ok := include(d, opts)
+		if !ok {
 			continue
 		}
 
@@ -131,8 +134,8 @@ func createTree(dirs []config.Directory, parentPath string, opts CreateOptions,
 				}
 				return dirCount, fileCount, fmt.Errorf("creating directory %s: %w", dirName, err)
 			}
-			*created = append(*created, fullPath)
 		}
+		*created = append(*created, fullPath)
 		dirCount++
 
 		// Create files
@@ -149,8 +152,8 @@ func createTree(dirs []config.Directory, parentPath string, opts CreateOptions,
 				if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
 					return dirCount, fileCount, fmt.Errorf("creating file %s: %w", fileName, err)
 				}
-				*createdFiles = append(*createdFiles, filePath)
 			}
+			*createdFiles = append(*createdFiles, filePath)
 			fileCount++
 		}
 
@@ -168,8 +171,63 @@ func createTree(dirs []config.Directory, parentPath string, opts CreateOptions,
 	return dirCount, fileCount, nil
 }
 
+// include reports whether d is created for opts and returns its resolved
+// name. Skipped optional directories and unmet When conditions exclude it.
+func include(d config.Directory, opts CreateOptions) (string, bool) {
+	if d.Optional && opts.SkipOptional != nil && opts.SkipOptional[d.Name] {
+		return "", false
+	}
+	if d.When != "" && !config.EvalWhen(d.When, opts.Variables) {
+		return "", false
+	}
+	return tmplpkg.Resolve(d.Name, opts.Variables), true
+}
+
+// File is a template file rendered for a project. Path is slash-separated
+// and relative to the project root.
+type File struct {
+	Path    string
+	Content string
+}
+
+// Layout returns the directories and files Create produces for dirs with
+// opts, as slash-separated paths relative to the project root in creation
+// order. Placeholders are resolved, and skipped optional directories and
+// directories whose When condition is not met are left out with their
+// children.
+func Layout(dirs []config.Directory, opts CreateOptions) (dirPaths []string, files []File) {
+	var walk func(dirs []config.Directory, prefix string)
+	walk = func(dirs []config.Directory, prefix string) {
+		for _, d := range dirs {
+			name, ok := include(d, opts)
+			if !ok {
+				continue
+			}
+			rel := path.Join(prefix, name)
+			dirPaths = append(dirPaths, rel)
+			for _, f := range d.Files {
+				files = append(files, File{
+					Path:    path.Join(rel, tmplpkg.Resolve(f.Name, opts.Variables)),
+					Content: tmplpkg.Resolve(f.Content, opts.Variables),
+				})
+			}
+			walk(d.Children, rel)
+		}
+	}
+	walk(dirs, "")
+	return dirPaths, files
+}
+
+// HashContent returns the hex SHA-256 of generated file content, used to
+// tell whether a file has been modified since it was generated.
+func HashContent(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
 // Flatten recursively converts a Directory tree into a flat list of
-// relative paths, parent-before-child ordering. Exported for use by diff.
+// relative paths, parent-before-child ordering. Names are not resolved and
+// conditions are ignored; use Layout for the paths of a specific project.
 func Flatten(dirs []config.Directory, prefix string) []string {
 	var paths []string
 	for _, d := range dirs {
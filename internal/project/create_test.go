@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/fwartner/prjct/internal/config"
@@ -372,6 +373,20 @@ func TestCreateDryRun(t *testing.T) {
 		t.Errorf("DirsCreated = %d, want 3", result.DirsCreated)
 	}
 
+	wantDirs := []string{
+		filepath.Join(base, "DryProject"),
+		filepath.Join(base, "DryProject", "src"),
+		filepath.Join(base, "DryProject", "docs"),
+	}
+	if len(result.Dirs) != len(wantDirs) {
+		t.Fatalf("Dirs = %v, want %v", result.Dirs, wantDirs)
+	}
+	for i, want := range wantDirs {
+		if result.Dirs[i] != want {
+			t.Errorf("Dirs[%d] = %q, want %q", i, result.Dirs[i], want)
+		}
+	}
+
 	// Verify nothing was created on disk
 	projectPath := filepath.Join(base, "DryProject")
 	if _, err := os.Stat(projectPath); !os.IsNotExist(err) {
@@ -599,3 +614,27 @@ func TestCreateHookFailure(t *testing.T) {
 		t.Error("project directory should exist despite hook failure")
 	}
 }
+
+func TestLayout(t *testing.T) {
+	dirs := []config.Directory{
+		{Name: "{client}", Files: []config.FileTemplate{{Name: "{name}.txt"}}, Children: []config.Directory{
+			{Name: "Raw"},
+			{Name: "Drone", When: "drone == yes"},
+		}},
+		{Name: "Extras", Optional: true, Children: []config.Directory{{Name: "Stills"}}},
+		{Name: "Edit"},
+	}
+	opts := CreateOptions{
+		Variables:    map[string]string{"client": "acme", "name": "spot", "drone": "no"},
+		SkipOptional: map[string]bool{"Extras": true},
+	}
+
+	gotDirs, gotFiles := Layout(dirs, opts)
+	wantDirs := []string{"acme", "acme/Raw", "Edit"}
+	if strings.Join(gotDirs, ",") != strings.Join(wantDirs, ",") {
+		t.Errorf("dirs = %v, want %v", gotDirs, wantDirs)
+	}
+	if len(gotFiles) != 1 || gotFiles[0].Path != "acme/spot.txt" {
+		t.Errorf("files = %v, want [acme/spot.txt]", gotFiles)
+	}
+}
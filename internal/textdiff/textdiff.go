@@ -0,0 +1,163 @@
+package textdiff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxCells bounds the size of the line comparison table. Larger inputs are
+// reported as a single hunk replacing all lines.
+const maxCells = 4_000_000
+
+// edit is one line of an edit script: ' ' keeps, '-' removes and '+' adds.
+type edit struct {
+	kind byte
+	line string
+}
+
+// Unified returns a unified diff turning a into b with context lines of
+// context around each change, or "" if they are equal. fromName and toName
+// label the two sides.
+func Unified(fromName, toName, a, b string, context int) string {
+	if a == b {
+		return ""
+	}
+	edits := diffLines(splitLines(a), splitLines(b))
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+	for start := 0; start < len(edits); {
+		// Find the next change and the end of the hunk around it
+		first := start
+		for first < len(edits) && edits[first].kind == ' ' {
+			first++
+		}
+		if first == len(edits) {
+			break
+		}
+		lo := max(first-context, start)
+		hi := first
+		for i := first; i < len(edits); i++ {
+			if edits[i].kind != ' ' {
+				hi = i
+			} else if i-hi > 2*context {
+				break
+			}
+		}
+		hi = min(hi+context+1, len(edits))
+		writeHunk(&sb, edits, lo, hi)
+		start = hi
+	}
+	return sb.String()
+}
+
+// writeHunk writes edits[lo:hi] with its @@ header.
+func writeHunk(sb *strings.Builder, edits []edit, lo, hi int) {
+	aStart, bStart := 1, 1
+	for _, e := range edits[:lo] {
+		if e.kind != '+' {
+			aStart++
+		}
+		if e.kind != '-' {
+			bStart++
+		}
+	}
+	aLen, bLen := 0, 0
+	for _, e := range edits[lo:hi] {
+		if e.kind != '+' {
+			aLen++
+		}
+		if e.kind != '-' {
+			bLen++
+		}
+	}
+	// An empty range names the line before it
+	if aLen == 0 {
+		aStart--
+	}
+	if bLen == 0 {
+		bStart--
+	}
+	fmt.Fprintf(sb, "@@ -%s +%s @@\n", hunkRange(aStart, aLen), hunkRange(bStart, bLen))
+	for _, e := range edits[lo:hi] {
+		sb.WriteByte(e.kind)
+		sb.WriteString(e.line)
+		if !strings.HasSuffix(e.line, "\n") {
+			sb.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+func hunkRange(start, n int) string {
+	if n == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, n)
+}
+
+// splitLines splits s into lines that keep their line endings.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines returns a shortest edit script from a to b based on their
+// longest common subsequence of lines.
+func diffLines(a, b []string) []edit {
+	n, m := len(a), len(b)
+	if n*m > maxCells {
+		edits := make([]edit, 0, n+m)
+		for _, l := range a {
+			edits = append(edits, edit{'-', l})
+		}
+		for _, l := range b {
+			edits = append(edits, edit{'+', l})
+		}
+		return edits
+	}
+
+	// lcs[i][j] is the length of the LCS of a[i:] and b[j:]
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	edits := make([]edit, 0, n+m)
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			edits = append(edits, edit{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			edits = append(edits, edit{'-', a[i]})
+			i++
+		default:
+			edits = append(edits, edit{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		edits = append(edits, edit{'-', a[i]})
+	}
+	for ; j < m; j++ {
+		edits = append(edits, edit{'+', b[j]})
+	}
+	return edits
+}
@@ -0,0 +1,42 @@
+package textdiff
+
+import "testing"
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{"equal", "a\nb\n", "a\nb\n", ""},
+		{
+			"change in the middle",
+			"1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			"1\n2\n3\n4\nfive\n6\n7\n8\n9\n",
+			"--- old\n+++ new\n@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+		},
+		{
+			"add to empty",
+			"",
+			"hello\n",
+			"--- old\n+++ new\n@@ -0,0 +1 @@\n+hello\n",
+		},
+		{
+			"missing final newline",
+			"a\nb",
+			"a\nc",
+			"--- old\n+++ new\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+c\n\\ No newline at end of file\n",
+		},
+		{
+			"two hunks",
+			"1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			"one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\ntwelve\n",
+			"--- old\n+++ new\n@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n@@ -9,4 +9,4 @@\n 9\n 10\n 11\n-12\n+twelve\n",
+		},
+	}
+	for _, tt := range tests {
+		if got := Unified("old", "new", tt.a, tt.b, 3); got != tt.want {
+			t.Errorf("%s:\ngot:\n%s\nwant:\n%s", tt.name, got, tt.want)
+		}
+	}
+}